@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/admission-webhook/pkg/webhook"
+	clientset "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned"
+	sainformers "github.com/lterrac/system-autoscaler/pkg/generated/informers/externalversions"
+	"github.com/lterrac/system-autoscaler/pkg/signals"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	coreinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+)
+
+var (
+	masterURL     string
+	kubeconfig    string
+	address       string
+	certFile      string
+	keyFile       string
+	controllers   string
+	sidecarCPU    string
+	sidecarMemory string
+)
+
+func main() {
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	// set up signals so we handle the first shutdown signal gracefully
+	stopCh := signals.SetupSignalHandler()
+
+	cfg, err := clientcmd.BuildConfigFromFlags(masterURL, kubeconfig)
+	if err != nil {
+		klog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building kubernetes clientset: %s", err.Error())
+	}
+
+	systemAutoscalerClient, err := clientset.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building example clientset: %s", err.Error())
+	}
+
+	sidecarResources := corev1.ResourceList{}
+	for name, value := range map[corev1.ResourceName]string{corev1.ResourceCPU: sidecarCPU, corev1.ResourceMemory: sidecarMemory} {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			klog.Fatalf("Error parsing the sidecar %s: %s", name, err.Error())
+		}
+		sidecarResources[name] = quantity
+	}
+
+	coreInformerFactory := coreinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
+	saInformerFactory := sainformers.NewSharedInformerFactory(systemAutoscalerClient, time.Second*30)
+
+	serviceInformer := coreInformerFactory.Core().V1().Services()
+	slaInformer := saInformerFactory.Systemautoscaler().V1beta1().ServiceLevelAgreements()
+
+	server := webhook.NewServer(
+		strings.Split(controllers, ","),
+		sidecarResources,
+		serviceInformer.Lister(),
+		slaInformer.Lister(),
+	)
+
+	coreInformerFactory.Start(stopCh)
+	saInformerFactory.Start(stopCh)
+
+	klog.Info("Waiting for informer caches to sync")
+	if ok := cache.WaitForCacheSync(stopCh, serviceInformer.Informer().HasSynced, slaInformer.Informer().HasSynced); !ok {
+		klog.Fatal("failed to wait for caches to sync")
+	}
+
+	srv := &http.Server{
+		Addr:    address,
+		Handler: server.Handler(),
+	}
+
+	klog.Info("Starting admission webhook on ", address)
+	if err := srv.ListenAndServeTLS(certFile, keyFile); err != nil {
+		klog.Fatalf("Error running admission webhook: %s", err.Error())
+	}
+}
+
+func init() {
+	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	flag.StringVar(&address, "address", ":8443", "The address the admission webhook listens on.")
+	flag.StringVar(&certFile, "tls-cert-file", "/etc/webhook/certs/tls.crt", "Path to the TLS certificate.")
+	flag.StringVar(&keyFile, "tls-private-key-file", "/etc/webhook/certs/tls.key", "Path to the TLS private key.")
+	flag.StringVar(&controllers, "controllers", "system:serviceaccount:kube-system:pod-autoscaler,system:serviceaccount:kube-system:podscale-controller", "Comma separated list of users allowed to change the PodScale fields managed by System Autoscaler.")
+	flag.StringVar(&sidecarCPU, "sidecar-cpu", "100m", "The cpu requests and limits of the containers of Guaranteed pods that set neither requests nor limits.")
+	flag.StringVar(&sidecarMemory, "sidecar-memory", "128Mi", "The memory requests and limits of the containers of Guaranteed pods that set neither requests nor limits.")
+}
@@ -0,0 +1,171 @@
+package mutation
+
+import (
+	"fmt"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// PatchOperation is a JSON patch operation applied to the admitted object
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MatchServiceLevelAgreement returns the ServiceLevelAgreement applied to the Pod, if any.
+// A Pod is matched by an agreement when one of the Services selected by the agreement selects the Pod.
+// As done by the PodScale controller, the first agreement matched is considered.
+func MatchServiceLevelAgreement(pod *corev1.Pod, slas []*v1beta1.ServiceLevelAgreement, services []*corev1.Service) *v1beta1.ServiceLevelAgreement {
+	podLabels := labels.Set(pod.Labels)
+
+	for _, sla := range slas {
+		if sla.Spec.Service == nil || sla.Spec.Service.Selector == nil {
+			continue
+		}
+
+		serviceSelector := labels.Set(sla.Spec.Service.Selector.MatchLabels).AsSelector()
+
+		for _, service := range services {
+			if len(service.Spec.Selector) == 0 || !serviceSelector.Matches(labels.Set(service.Labels)) {
+				continue
+			}
+
+			if labels.Set(service.Spec.Selector).AsSelector().Matches(podLabels) {
+				return sla
+			}
+		}
+	}
+
+	return nil
+}
+
+// GuaranteedResources returns the patch that makes the Pod Guaranteed QOS.
+// The container tracked by the agreement gets the same CPU and memory requests and limits,
+// using the limits if set, then the requests and finally the agreement default resources.
+// All the other containers, including the init containers, get the same requests and limits
+// as well, falling back to the sidecar resources when they set neither, since a single
+// container without limits would make the whole Pod Burstable.
+func GuaranteedResources(pod *corev1.Pod, sla *v1beta1.ServiceLevelAgreement, sidecarResources corev1.ResourceList) []PatchOperation {
+	patch := make([]PatchOperation, 0)
+
+	for i, container := range pod.Spec.InitContainers {
+		resources := guaranteedResources(container.Resources, sidecarResources)
+		patch = appendResources(patch, fmt.Sprintf("/spec/initContainers/%d/resources", i), container.Resources, resources)
+	}
+
+	for i, container := range pod.Spec.Containers {
+		defaults := sidecarResources
+		if container.Name == sla.Spec.Service.Container {
+			defaults = sla.Spec.DefaultResources
+		}
+
+		resources := guaranteedResources(container.Resources, defaults)
+		patch = appendResources(patch, fmt.Sprintf("/spec/containers/%d/resources", i), container.Resources, resources)
+	}
+
+	return patch
+}
+
+// appendResources appends the operation setting the resources of a container, if they changed
+func appendResources(patch []PatchOperation, path string, actual corev1.ResourceRequirements, resources corev1.ResourceRequirements) []PatchOperation {
+	if equality.Semantic.DeepEqual(resources, actual) {
+		return patch
+	}
+
+	return append(patch, PatchOperation{
+		Op:    "add",
+		Path:  path,
+		Value: resources,
+	})
+}
+
+// BurstableResources returns the patch that makes the Pod Burstable QOS.
+// The container tracked by the agreement gets the requests if set, then the limits and finally
+// the agreement default resources, while its limits are the requests scaled by the burst ratio
+// unless they are already greater than the requests. The other containers are not changed.
+func BurstableResources(pod *corev1.Pod, sla *v1beta1.ServiceLevelAgreement) []PatchOperation {
+	patch := make([]PatchOperation, 0)
+
+	for i, container := range pod.Spec.Containers {
+		if container.Name != sla.Spec.Service.Container {
+			continue
+		}
+
+		burstRatio := sla.Spec.BurstRatioOrDefault()
+		resources := burstableResources(container.Resources, sla.Spec.DefaultResources, float64(burstRatio.MilliValue())/1000)
+		patch = appendResources(patch, fmt.Sprintf("/spec/containers/%d/resources", i), container.Resources, resources)
+	}
+
+	return patch
+}
+
+// burstableResources sets CPU and memory limits greater than the requests
+func burstableResources(actual corev1.ResourceRequirements, defaults corev1.ResourceList, burstRatio float64) corev1.ResourceRequirements {
+	resources := *actual.DeepCopy()
+
+	if resources.Requests == nil {
+		resources.Requests = make(corev1.ResourceList)
+	}
+
+	if resources.Limits == nil {
+		resources.Limits = make(corev1.ResourceList)
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		request, ok := actual.Requests[name]
+		if !ok {
+			request, ok = actual.Limits[name]
+		}
+		if !ok {
+			request, ok = defaults[name]
+		}
+		if !ok {
+			continue
+		}
+
+		resources.Requests[name] = request.DeepCopy()
+
+		if limit, ok := actual.Limits[name]; ok && limit.Cmp(request) > 0 {
+			continue
+		}
+		resources.Limits[name] = *resource.NewMilliQuantity(int64(float64(request.MilliValue())*burstRatio), request.Format)
+	}
+
+	return resources
+}
+
+// guaranteedResources sets the same value for CPU and memory requests and limits
+func guaranteedResources(actual corev1.ResourceRequirements, defaults corev1.ResourceList) corev1.ResourceRequirements {
+	resources := *actual.DeepCopy()
+
+	if resources.Requests == nil {
+		resources.Requests = make(corev1.ResourceList)
+	}
+
+	if resources.Limits == nil {
+		resources.Limits = make(corev1.ResourceList)
+	}
+
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		value, ok := actual.Limits[name]
+		if !ok {
+			value, ok = actual.Requests[name]
+		}
+		if !ok {
+			value, ok = defaults[name]
+		}
+		if !ok {
+			continue
+		}
+
+		resources.Requests[name] = value.DeepCopy()
+		resources.Limits[name] = value.DeepCopy()
+	}
+
+	return resources
+}
@@ -0,0 +1,297 @@
+package mutation
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newSLA(name string, selector map[string]string) *v1beta1.ServiceLevelAgreement {
+	return &v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: v1beta1.ServiceLevelAgreementSpec{
+			DefaultResources: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("100Mi"),
+			},
+			Service: &v1beta1.Service{
+				Selector: &metav1.LabelSelector{
+					MatchLabels: selector,
+				},
+				Container: "app",
+			},
+		},
+	}
+}
+
+func TestMatchServiceLevelAgreement(t *testing.T) {
+	services := []*corev1.Service{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "frontend", Labels: map[string]string{"tier": "frontend"}},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "frontend"}},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "backend", Labels: map[string]string{"tier": "backend"}},
+			Spec:       corev1.ServiceSpec{Selector: map[string]string{"app": "backend"}},
+		},
+	}
+
+	slas := []*v1beta1.ServiceLevelAgreement{
+		newSLA("frontend-sla", map[string]string{"tier": "frontend"}),
+		newSLA("backend-sla", map[string]string{"tier": "backend"}),
+	}
+
+	testcases := []struct {
+		description string
+		labels      map[string]string
+		expected    string
+	}{
+		{
+			description: "should match the agreement of the service selecting the pod",
+			labels:      map[string]string{"app": "backend"},
+			expected:    "backend-sla",
+		},
+		{
+			description: "should not match pods outside the services",
+			labels:      map[string]string{"app": "database"},
+			expected:    "",
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Labels: tt.labels}}
+			sla := MatchServiceLevelAgreement(pod, slas, services)
+			if tt.expected == "" {
+				require.Nil(t, sla)
+				return
+			}
+			require.NotNil(t, sla)
+			require.Equal(t, tt.expected, sla.Name)
+		})
+	}
+}
+
+func TestGuaranteedResources(t *testing.T) {
+	sla := newSLA("sla", nil)
+	sidecarResources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("10m"),
+		corev1.ResourceMemory: resource.MustParse("16Mi"),
+	}
+
+	testcases := []struct {
+		description    string
+		containers     []corev1.Container
+		initContainers []corev1.Container
+		expected       []PatchOperation
+	}{
+		{
+			description: "should use the default resources when the container has none",
+			containers:  []corev1.Container{{Name: "app"}},
+			expected: []PatchOperation{
+				{
+					Op:   "add",
+					Path: "/spec/containers/0/resources",
+					Value: corev1.ResourceRequirements{
+						Requests: sla.Spec.DefaultResources,
+						Limits:   sla.Spec.DefaultResources,
+					},
+				},
+			},
+		},
+		{
+			description: "should set the requests equal to the limits",
+			containers: []corev1.Container{
+				{
+					Name: "sidecar",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("50m")},
+					},
+				},
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+						Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+					},
+				},
+			},
+			expected: []PatchOperation{
+				{
+					Op:   "add",
+					Path: "/spec/containers/0/resources",
+					Value: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("50m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("50m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+					},
+				},
+				{
+					Op:   "add",
+					Path: "/spec/containers/1/resources",
+					Value: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("100Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("500m"),
+							corev1.ResourceMemory: resource.MustParse("100Mi"),
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "should make guaranteed the sidecars and the init containers without resources",
+			initContainers: []corev1.Container{
+				{
+					Name: "init",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("20m")},
+					},
+				},
+			},
+			containers: []corev1.Container{
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: sla.Spec.DefaultResources,
+						Limits:   sla.Spec.DefaultResources,
+					},
+				},
+				{Name: "http-metrics"},
+			},
+			expected: []PatchOperation{
+				{
+					Op:   "add",
+					Path: "/spec/initContainers/0/resources",
+					Value: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("20m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("20m"),
+							corev1.ResourceMemory: resource.MustParse("16Mi"),
+						},
+					},
+				},
+				{
+					Op:   "add",
+					Path: "/spec/containers/1/resources",
+					Value: corev1.ResourceRequirements{
+						Requests: sidecarResources,
+						Limits:   sidecarResources,
+					},
+				},
+			},
+		},
+		{
+			description: "should not patch guaranteed pods",
+			containers: []corev1.Container{
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: sla.Spec.DefaultResources,
+						Limits:   sla.Spec.DefaultResources,
+					},
+				},
+			},
+			expected: []PatchOperation{},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: tt.containers, InitContainers: tt.initContainers}}
+			require.Equal(t, tt.expected, GuaranteedResources(pod, sla, sidecarResources))
+		})
+	}
+}
+
+func TestBurstableResources(t *testing.T) {
+	sla := newSLA("sla", nil)
+	sla.Spec.QOSClass = corev1.PodQOSBurstable
+
+	testcases := []struct {
+		description string
+		containers  []corev1.Container
+		expected    []PatchOperation
+	}{
+		{
+			description: "should scale the default resources by the burst ratio",
+			containers:  []corev1.Container{{Name: "sidecar"}, {Name: "app"}},
+			expected: []PatchOperation{
+				{
+					Op:   "add",
+					Path: "/spec/containers/1/resources",
+					Value: corev1.ResourceRequirements{
+						Requests: sla.Spec.DefaultResources,
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("200m"),
+							corev1.ResourceMemory: resource.MustParse("200Mi"),
+						},
+					},
+				},
+			},
+		},
+		{
+			description: "should keep the limits greater than the requests",
+			containers: []corev1.Container{
+				{
+					Name: "app",
+					Resources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("200m")},
+						Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("300m")},
+					},
+				},
+			},
+			expected: []PatchOperation{
+				{
+					Op:   "add",
+					Path: "/spec/containers/0/resources",
+					Value: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("200m"),
+							corev1.ResourceMemory: resource.MustParse("100Mi"),
+						},
+						Limits: corev1.ResourceList{
+							corev1.ResourceCPU:    resource.MustParse("300m"),
+							corev1.ResourceMemory: resource.MustParse("200Mi"),
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: tt.containers}}
+			actual := BurstableResources(pod, sla)
+			require.Equal(t, len(tt.expected), len(actual))
+			for i := range tt.expected {
+				require.Equal(t, tt.expected[i].Path, actual[i].Path)
+				expected := tt.expected[i].Value.(corev1.ResourceRequirements)
+				resources := actual[i].Value.(corev1.ResourceRequirements)
+				for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+					request, limit := resources.Requests[name], resources.Limits[name]
+					require.Equal(t, 0, request.Cmp(expected.Requests[name]))
+					require.Equal(t, 0, limit.Cmp(expected.Limits[name]))
+				}
+			}
+		})
+	}
+}
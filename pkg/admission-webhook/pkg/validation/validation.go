@@ -0,0 +1,291 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// RecommendLogics contains the logics supported by the recommender
+var RecommendLogics = sets.NewString(
+	string(v1beta1.FixedGainControl),
+	string(v1beta1.AdaptiveGainControl),
+	string(v1beta1.PIDControl),
+	string(v1beta1.QueueingModel),
+)
+
+// ForecastModels contains the models supported by the forecaster
+var ForecastModels = sets.NewString(
+	string(v1beta1.HoltWinters),
+	string(v1beta1.SeasonalNaive),
+)
+
+// IdlePolicies contains the policies supported for the idle pods
+var IdlePolicies = sets.NewString(
+	string(v1beta1.IdleHold),
+	string(v1beta1.IdleScaleDown),
+)
+
+// QOSClasses contains the QOS classes supported for the tracked pods
+var QOSClasses = sets.NewString(
+	string(v1.PodQOSGuaranteed),
+	string(v1.PodQOSBurstable),
+)
+
+// ValidateServiceLevelAgreement checks that the ServiceLevelAgreement spec is consistent.
+func ValidateServiceLevelAgreement(sla *v1beta1.ServiceLevelAgreement) field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	allErrs = append(allErrs, validateMetricRequirement(sla.Spec.Metric, specPath.Child("metric"))...)
+
+	if sla.Spec.RecommenderLogic != "" && !RecommendLogics.Has(string(sla.Spec.RecommenderLogic)) {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("recommenderLogic"), sla.Spec.RecommenderLogic, RecommendLogics.List()))
+	}
+
+	if sla.Spec.RecommenderLogic == v1beta1.QueueingModel && sla.Spec.Metric.ResponseTime.Sign() <= 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("metric", "responseTime"), "the queueingModel recommender logic requires a response time requirement"))
+	}
+
+	allErrs = append(allErrs, validateControllerParameters(sla.Spec.ControllerParameters, specPath.Child("controllerParameters"))...)
+	allErrs = append(allErrs, validateForecastParameters(sla.Spec.Forecast, specPath.Child("forecast"))...)
+
+	if sla.Spec.MinReplicas < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("minReplicas"), sla.Spec.MinReplicas, "must be greater than or equal to 0"))
+	}
+
+	if sla.Spec.MaxReplicas < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("maxReplicas"), sla.Spec.MaxReplicas, "must be greater than or equal to 0"))
+	}
+
+	if sla.Spec.MaxReplicas > 0 && sla.Spec.MinReplicas > sla.Spec.MaxReplicas {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("minReplicas"), sla.Spec.MinReplicas, "must be less than or equal to maxReplicas"))
+	}
+
+	allErrs = append(allErrs, validateResourceBounds(sla.Spec.MinResources, sla.Spec.MaxResources, specPath.Child("minResources"))...)
+
+	if sla.Spec.Idle != nil {
+		idlePath := specPath.Child("idle")
+		if sla.Spec.Idle.Policy != "" && !IdlePolicies.Has(string(sla.Spec.Idle.Policy)) {
+			allErrs = append(allErrs, field.NotSupported(idlePath.Child("policy"), sla.Spec.Idle.Policy, IdlePolicies.List()))
+		}
+		allErrs = append(allErrs, validateResourceBounds(sla.Spec.Idle.Resources, sla.Spec.MaxResources, idlePath.Child("resources"))...)
+		if sla.Spec.Idle.Policy == v1beta1.IdleScaleDown && len(sla.Spec.IdleResourcesOrDefault()) == 0 {
+			allErrs = append(allErrs, field.Required(idlePath.Child("resources"), "the scaleDown idle policy requires the idle resources or the minResources"))
+		}
+	}
+
+	if sla.Spec.QOSClass != "" && !QOSClasses.Has(string(sla.Spec.QOSClass)) {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("qosClass"), sla.Spec.QOSClass, QOSClasses.List()))
+	}
+
+	if sla.Spec.BurstRatio != nil && sla.Spec.BurstRatio.Cmp(resource.MustParse("1")) < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("burstRatio"), sla.Spec.BurstRatio.String(), "must be greater than or equal to 1"))
+	}
+
+	if sla.Spec.Service == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("service"), ""))
+	} else if sla.Spec.Service.Container == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("service", "container"), ""))
+	}
+
+	return allErrs
+}
+
+// validateMetricRequirement checks that at least one requirement is set and that all of them are positive.
+func validateMetricRequirement(requirement v1beta1.MetricRequirement, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	quantities := []struct {
+		name     string
+		quantity *resource.Quantity
+	}{
+		{"responseTime", &requirement.ResponseTime},
+		{"throughput", requirement.Throughput},
+		{"errorRate", requirement.ErrorRate},
+		{"cpuUtilization", requirement.CPUUtilization},
+	}
+
+	for _, q := range quantities {
+		if q.quantity != nil && q.quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(q.name), q.quantity.String(), "must be greater than 0"))
+		}
+	}
+
+	if len(metrics.Requirements(requirement)) == 0 {
+		allErrs = append(allErrs, field.Required(path, "at least one requirement greater than 0 must be set"))
+	}
+
+	return allErrs
+}
+
+// validateControllerParameters checks that the controller parameters, once merged with the default
+// values, keep the feedback controller stable.
+func validateControllerParameters(parameters *v1beta1.ControllerParameters, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if parameters == nil {
+		return allErrs
+	}
+
+	p := parameters.WithDefaults()
+	one := resource.MustParse("1")
+
+	positive := []struct {
+		name     string
+		quantity *resource.Quantity
+	}{
+		{"bc", p.BC},
+		{"dc", p.DC},
+		{"minCPU", p.MinCPU},
+		{"minBC", p.MinBC},
+		{"minDC", p.MinDC},
+	}
+
+	for _, q := range positive {
+		if q.quantity.Sign() <= 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(q.name), q.quantity.String(), "must be greater than 0"))
+		}
+	}
+
+	if p.MaxScaleOut.Cmp(one) < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxScaleOut"), p.MaxScaleOut.String(), "must be greater than or equal to 1"))
+	}
+
+	if p.MemoryMargin.Sign() < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("memoryMargin"), p.MemoryMargin.String(), "must be greater than or equal to 0"))
+	}
+
+	for _, q := range []struct {
+		name     string
+		quantity *resource.Quantity
+	}{
+		{"kp", p.KP},
+		{"ki", p.KI},
+		{"kd", p.KD},
+	} {
+		if q.quantity.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(q.name), q.quantity.String(), "must be greater than or equal to 0"))
+		}
+	}
+
+	if p.DerivativeFilter.Sign() < 0 || p.DerivativeFilter.Cmp(one) >= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("derivativeFilter"), p.DerivativeFilter.String(), "must be in the range [0, 1)"))
+	}
+
+	if p.OOMBumpRatio.Cmp(one) <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("oomBumpRatio"), p.OOMBumpRatio.String(), "must be greater than 1"))
+	}
+
+	bounds := []struct {
+		name  string
+		lower *resource.Quantity
+		upper *resource.Quantity
+	}{
+		{"minError", p.MinError, p.MaxError},
+		{"minBC", p.MinBC, p.MaxBC},
+		{"minDC", p.MinDC, p.MaxDC},
+	}
+
+	for _, b := range bounds {
+		if b.lower.Cmp(*b.upper) > 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(b.name), b.lower.String(), fmt.Sprintf("must be less than or equal to the upper bound %s", b.upper.String())))
+		}
+	}
+
+	return allErrs
+}
+
+// validateForecastParameters checks that the forecast parameters, once merged with the default
+// values, describe a season of at least two samples and valid smoothing factors.
+func validateForecastParameters(parameters *v1beta1.ForecastParameters, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if parameters == nil {
+		return allErrs
+	}
+
+	p := parameters.WithDefaults()
+
+	if !ForecastModels.Has(string(p.Model)) {
+		allErrs = append(allErrs, field.NotSupported(path.Child("model"), p.Model, ForecastModels.List()))
+	}
+
+	if p.Interval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("interval"), p.Interval.Duration.String(), "must be greater than 0"))
+	} else if p.Season.Duration < 2*p.Interval.Duration {
+		allErrs = append(allErrs, field.Invalid(path.Child("season"), p.Season.Duration.String(), "must be at least twice the interval"))
+	}
+
+	if p.Horizon.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(path.Child("horizon"), p.Horizon.Duration.String(), "must be greater than or equal to 0"))
+	}
+
+	one := resource.MustParse("1")
+	for _, q := range []struct {
+		name     string
+		quantity *resource.Quantity
+	}{
+		{"alpha", p.Alpha},
+		{"beta", p.Beta},
+		{"gamma", p.Gamma},
+	} {
+		if q.quantity.Sign() < 0 || q.quantity.Cmp(one) > 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(q.name), q.quantity.String(), "must be in the range [0, 1]"))
+		}
+	}
+
+	return allErrs
+}
+
+// validateResourceBounds checks that each lower bound is not greater than the corresponding upper bound.
+func validateResourceBounds(min v1.ResourceList, max v1.ResourceList, path *field.Path) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	for name, lower := range min {
+		upper, ok := max[name]
+		if ok && lower.Cmp(upper) > 0 {
+			allErrs = append(allErrs, field.Invalid(path.Key(string(name)), lower.String(), fmt.Sprintf("must be less than or equal to the upper bound %s", upper.String())))
+		}
+	}
+
+	return allErrs
+}
+
+// ValidatePodScaleUpdate rejects changes to the PodScale spec fields that can only be set by
+// System Autoscaler. The fields identifying the tracked container are immutable, while the desired
+// resources can be changed only by the controllers.
+func ValidatePodScaleUpdate(newPodScale *v1beta1.PodScale, oldPodScale *v1beta1.PodScale, isController bool) field.ErrorList {
+	allErrs := field.ErrorList{}
+	specPath := field.NewPath("spec")
+
+	immutable := []struct {
+		name     string
+		newValue string
+		oldValue string
+	}{
+		{"namespace", newPodScale.Spec.Namespace, oldPodScale.Spec.Namespace},
+		{"serviceLevelAgreement", newPodScale.Spec.SLA, oldPodScale.Spec.SLA},
+		{"pod", newPodScale.Spec.Pod, oldPodScale.Spec.Pod},
+		{"service", newPodScale.Spec.Service, oldPodScale.Spec.Service},
+		{"container", newPodScale.Spec.Container, oldPodScale.Spec.Container},
+	}
+
+	for _, f := range immutable {
+		if f.newValue != f.oldValue {
+			allErrs = append(allErrs, field.Forbidden(specPath.Child(f.name), "field is immutable"))
+		}
+	}
+
+	if !isController && !equality.Semantic.DeepEqual(newPodScale.Spec.DesiredResources, oldPodScale.Spec.DesiredResources) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("desired"), "field is managed by System Autoscaler"))
+	}
+
+	return allErrs
+}
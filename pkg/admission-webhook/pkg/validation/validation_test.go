@@ -0,0 +1,283 @@
+package validation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newSLA() *v1beta1.ServiceLevelAgreement {
+	return &v1beta1.ServiceLevelAgreement{
+		Spec: v1beta1.ServiceLevelAgreementSpec{
+			Metric: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			RecommenderLogic: v1beta1.FixedGainControl,
+			MinResources: v1.ResourceList{
+				v1.ResourceCPU: *resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			MaxResources: v1.ResourceList{
+				v1.ResourceCPU: *resource.NewMilliQuantity(1000, resource.BinarySI),
+			},
+			MinReplicas: 1,
+			MaxReplicas: 10,
+			Service: &v1beta1.Service{
+				Container: "app",
+			},
+		},
+	}
+}
+
+func TestValidateServiceLevelAgreement(t *testing.T) {
+	testcases := []struct {
+		description string
+		mutate      func(sla *v1beta1.ServiceLevelAgreement)
+		errors      []string
+	}{
+		{
+			description: "should accept a valid agreement",
+			mutate:      func(sla *v1beta1.ServiceLevelAgreement) {},
+			errors:      []string{},
+		},
+		{
+			description: "should reject an agreement without requirements",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.Metric.ResponseTime = resource.Quantity{}
+			},
+			errors: []string{"spec.metric"},
+		},
+		{
+			description: "should reject a negative requirement",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.Metric.ErrorRate = resource.NewMilliQuantity(-10, resource.BinarySI)
+			},
+			errors: []string{"spec.metric.errorRate"},
+		},
+		{
+			description: "should reject an unknown recommender logic",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.RecommenderLogic = "unknown"
+			},
+			errors: []string{"spec.recommenderLogic"},
+		},
+		{
+			description: "should accept the pid recommender logic",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.RecommenderLogic = v1beta1.PIDControl
+			},
+			errors: []string{},
+		},
+		{
+			description: "should reject the queueing model logic without response time requirement",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.RecommenderLogic = v1beta1.QueueingModel
+				sla.Spec.Metric.ResponseTime = resource.Quantity{}
+				sla.Spec.Metric.Throughput = resource.NewQuantity(10, resource.DecimalSI)
+			},
+			errors: []string{"spec.metric.responseTime"},
+		},
+		{
+			description: "should reject min replicas greater than max replicas",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.MinReplicas = 20
+			},
+			errors: []string{"spec.minReplicas"},
+		},
+		{
+			description: "should reject min resources greater than max resources",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.MinResources[v1.ResourceCPU] = *resource.NewMilliQuantity(2000, resource.BinarySI)
+			},
+			errors: []string{"spec.minResources[cpu]"},
+		},
+		{
+			description: "should accept valid controller parameters",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.ControllerParameters = &v1beta1.ControllerParameters{
+					BC:          resource.NewQuantity(20, resource.DecimalSI),
+					MaxScaleOut: resource.NewMilliQuantity(2000, resource.DecimalSI),
+				}
+			},
+			errors: []string{},
+		},
+		{
+			description: "should reject invalid controller parameters",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.ControllerParameters = &v1beta1.ControllerParameters{
+					DC:               resource.NewQuantity(0, resource.DecimalSI),
+					MaxScaleOut:      resource.NewMilliQuantity(500, resource.DecimalSI),
+					MinBC:            resource.NewQuantity(200, resource.DecimalSI),
+					MemoryMargin:     resource.NewMilliQuantity(-100, resource.DecimalSI),
+					OOMBumpRatio:     resource.NewQuantity(1, resource.DecimalSI),
+					KD:               resource.NewQuantity(-1, resource.DecimalSI),
+					DerivativeFilter: resource.NewQuantity(1, resource.DecimalSI),
+				}
+			},
+			errors: []string{
+				"spec.controllerParameters.dc",
+				"spec.controllerParameters.maxScaleOut",
+				"spec.controllerParameters.memoryMargin",
+				"spec.controllerParameters.kd",
+				"spec.controllerParameters.derivativeFilter",
+				"spec.controllerParameters.oomBumpRatio",
+				"spec.controllerParameters.minBC",
+			},
+		},
+		{
+			description: "should accept a burstable agreement",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.QOSClass = v1.PodQOSBurstable
+				sla.Spec.BurstRatio = resource.NewMilliQuantity(1500, resource.DecimalSI)
+			},
+			errors: []string{},
+		},
+		{
+			description: "should reject best effort agreements and burst ratios lower than 1",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.QOSClass = v1.PodQOSBestEffort
+				sla.Spec.BurstRatio = resource.NewMilliQuantity(500, resource.DecimalSI)
+			},
+			errors: []string{"spec.qosClass", "spec.burstRatio"},
+		},
+		{
+			description: "should accept valid forecast parameters",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.Forecast = &v1beta1.ForecastParameters{
+					Model:  v1beta1.SeasonalNaive,
+					Season: &metav1.Duration{Duration: time.Hour},
+				}
+			},
+			errors: []string{},
+		},
+		{
+			description: "should reject invalid forecast parameters",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.Forecast = &v1beta1.ForecastParameters{
+					Model:  "unknown",
+					Season: &metav1.Duration{Duration: time.Minute},
+					Alpha:  resource.NewQuantity(2, resource.DecimalSI),
+				}
+			},
+			errors: []string{"spec.forecast.model", "spec.forecast.season", "spec.forecast.alpha"},
+		},
+		{
+			description: "should reject an unknown idle policy",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.Idle = &v1beta1.IdleParameters{Policy: "unknown"}
+			},
+			errors: []string{"spec.idle.policy"},
+		},
+		{
+			description: "should reject idle resources above the upper bound",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.MaxResources = v1.ResourceList{v1.ResourceCPU: resource.MustParse("1")}
+				sla.Spec.Idle = &v1beta1.IdleParameters{
+					Policy:    v1beta1.IdleScaleDown,
+					Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("2")},
+				}
+			},
+			errors: []string{"spec.idle.resources[cpu]"},
+		},
+		{
+			description: "should reject the scaleDown idle policy without resources",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.MinResources = nil
+				sla.Spec.Idle = &v1beta1.IdleParameters{Policy: v1beta1.IdleScaleDown}
+			},
+			errors: []string{"spec.idle.resources"},
+		},
+		{
+			description: "should accept the scaleDown idle policy with the minimum resources",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.MinResources = v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")}
+				sla.Spec.Idle = &v1beta1.IdleParameters{Policy: v1beta1.IdleScaleDown}
+			},
+			errors: []string{},
+		},
+		{
+			description: "should reject an agreement without container",
+			mutate: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.Spec.Service.Container = ""
+			},
+			errors: []string{"spec.service.container"},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			sla := newSLA()
+			tt.mutate(sla)
+			fields := make([]string, 0)
+			for _, err := range ValidateServiceLevelAgreement(sla) {
+				fields = append(fields, err.Field)
+			}
+			require.Equal(t, tt.errors, fields)
+		})
+	}
+}
+
+func TestValidatePodScaleUpdate(t *testing.T) {
+	oldPodScale := &v1beta1.PodScale{
+		Spec: v1beta1.PodScaleSpec{
+			Namespace: "default",
+			SLA:       "sla",
+			Pod:       "pod",
+			Service:   "service",
+			Container: "app",
+			DesiredResources: v1.ResourceList{
+				v1.ResourceCPU: *resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+		},
+	}
+
+	testcases := []struct {
+		description  string
+		mutate       func(podScale *v1beta1.PodScale)
+		isController bool
+		errors       []string
+	}{
+		{
+			description: "should allow controllers to change the desired resources",
+			mutate: func(podScale *v1beta1.PodScale) {
+				podScale.Spec.DesiredResources[v1.ResourceCPU] = resource.MustParse("1")
+			},
+			isController: true,
+			errors:       []string{},
+		},
+		{
+			description: "should reject users changing the desired resources",
+			mutate: func(podScale *v1beta1.PodScale) {
+				podScale.Spec.DesiredResources[v1.ResourceCPU] = resource.MustParse("1")
+			},
+			errors: []string{"spec.desired"},
+		},
+		{
+			description:  "should reject changes to the tracked container",
+			mutate:       func(podScale *v1beta1.PodScale) { podScale.Spec.Container = "sidecar" },
+			isController: true,
+			errors:       []string{"spec.container"},
+		},
+		{
+			description: "should allow changes outside the spec",
+			mutate:      func(podScale *v1beta1.PodScale) { podScale.Labels = map[string]string{"app": "test"} },
+			errors:      []string{},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			newPodScale := oldPodScale.DeepCopy()
+			tt.mutate(newPodScale)
+			fields := make([]string, 0)
+			for _, err := range ValidatePodScaleUpdate(newPodScale, oldPodScale, tt.isController) {
+				fields = append(fields, err.Field)
+			}
+			require.Equal(t, tt.errors, fields)
+		})
+	}
+}
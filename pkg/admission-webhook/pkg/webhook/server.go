@@ -0,0 +1,220 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/lterrac/system-autoscaler/pkg/admission-webhook/pkg/mutation"
+	"github.com/lterrac/system-autoscaler/pkg/admission-webhook/pkg/validation"
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// ValidateServiceLevelAgreementPath is the path serving the ServiceLevelAgreement validation
+	ValidateServiceLevelAgreementPath = "/validate-servicelevelagreement"
+
+	// ValidatePodScalePath is the path serving the PodScale validation
+	ValidatePodScalePath = "/validate-podscale"
+
+	// MutatePodPath is the path serving the Pod mutation
+	MutatePodPath = "/mutate-pod"
+)
+
+// admitFunc handles an admission request and returns the corresponding response
+type admitFunc func(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse
+
+// Server is the admission webhook server for System Autoscaler resources
+type Server struct {
+	// controllers contains the users allowed to change the PodScale fields managed by System Autoscaler
+	controllers sets.String
+
+	// sidecarResources are assigned to the containers of the Guaranteed Pods that set neither requests nor limits
+	sidecarResources corev1.ResourceList
+
+	serviceLister corelisters.ServiceLister
+	slaLister     salisters.ServiceLevelAgreementLister
+}
+
+// NewServer returns a new admission webhook server. The controllers are the users,
+// usually service accounts, allowed to change the resources managed by System Autoscaler.
+// The sidecar resources are assigned to the untracked containers without resources of the Guaranteed Pods.
+// The listers are used to find the ServiceLevelAgreement matching the admitted Pods.
+func NewServer(controllers []string, sidecarResources corev1.ResourceList, serviceLister corelisters.ServiceLister, slaLister salisters.ServiceLevelAgreementLister) *Server {
+	return &Server{
+		controllers:      sets.NewString(controllers...),
+		sidecarResources: sidecarResources,
+		serviceLister:    serviceLister,
+		slaLister:        slaLister,
+	}
+}
+
+// Handler returns the handler serving all the webhooks
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(ValidateServiceLevelAgreementPath, serve(s.validateServiceLevelAgreement))
+	mux.Handle(ValidatePodScalePath, serve(s.validatePodScale))
+	mux.Handle(MutatePodPath, serve(s.mutatePod))
+	return mux
+}
+
+// serve decodes the AdmissionReview, calls the admit function and encodes the response
+func serve(admit admitFunc) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			klog.Errorf("failed to read the admission request: %s", err)
+			http.Error(res, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		review := &admissionv1.AdmissionReview{}
+		err = json.Unmarshal(body, review)
+		if err != nil || review.Request == nil {
+			klog.Errorf("failed to decode the admission review: %s", err)
+			http.Error(res, "malformed admission review", http.StatusBadRequest)
+			return
+		}
+
+		response := admit(review.Request)
+		response.UID = review.Request.UID
+		review.Response = response
+		review.Request = nil
+
+		body, err = json.Marshal(review)
+		if err != nil {
+			klog.Errorf("failed to encode the admission review: %s", err)
+			http.Error(res, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		res.Header().Set("Content-Type", "application/json")
+		_, _ = res.Write(body)
+	}
+}
+
+func (s *Server) validateServiceLevelAgreement(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	sla := &v1beta1.ServiceLevelAgreement{}
+	if err := json.Unmarshal(request.Object.Raw, sla); err != nil {
+		return errored(err)
+	}
+
+	return toResponse("ServiceLevelAgreement", sla.Name, validation.ValidateServiceLevelAgreement(sla))
+}
+
+func (s *Server) validatePodScale(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation != admissionv1.Update {
+		return allowed()
+	}
+
+	newPodScale := &v1beta1.PodScale{}
+	if err := json.Unmarshal(request.Object.Raw, newPodScale); err != nil {
+		return errored(err)
+	}
+
+	oldPodScale := &v1beta1.PodScale{}
+	if err := json.Unmarshal(request.OldObject.Raw, oldPodScale); err != nil {
+		return errored(err)
+	}
+
+	isController := s.controllers.Has(request.UserInfo.Username)
+
+	return toResponse("PodScale", newPodScale.Name, validation.ValidatePodScaleUpdate(newPodScale, oldPodScale, isController))
+}
+
+// mutatePod sets the QOS class required by the ServiceLevelAgreement to the tracked Pods
+func (s *Server) mutatePod(request *admissionv1.AdmissionRequest) *admissionv1.AdmissionResponse {
+	if request.Operation != admissionv1.Create {
+		return allowed()
+	}
+
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(request.Object.Raw, pod); err != nil {
+		return errored(err)
+	}
+
+	// the namespace is not set yet in the object when the Pod is created by a controller
+	slas, err := s.slaLister.ServiceLevelAgreements(request.Namespace).List(labels.Everything())
+	if err != nil {
+		return errored(err)
+	}
+
+	services, err := s.serviceLister.Services(request.Namespace).List(labels.Everything())
+	if err != nil {
+		return errored(err)
+	}
+
+	sla := mutation.MatchServiceLevelAgreement(pod, slas, services)
+	if sla == nil {
+		return allowed()
+	}
+
+	var patch []mutation.PatchOperation
+	if sla.Spec.IsBurstable() {
+		patch = mutation.BurstableResources(pod, sla)
+	} else {
+		patch = mutation.GuaranteedResources(pod, sla, s.sidecarResources)
+	}
+	if len(patch) == 0 {
+		return allowed()
+	}
+
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return errored(err)
+	}
+
+	klog.V(4).Infof("making pod %s/%s %s for service level agreement %s", request.Namespace, pod.GetGenerateName()+pod.GetName(), sla.Spec.QOSClass, sla.GetName())
+
+	patchType := admissionv1.PatchTypeJSONPatch
+	return &admissionv1.AdmissionResponse{
+		Allowed:   true,
+		Patch:     body,
+		PatchType: &patchType,
+	}
+}
+
+func allowed() *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: true,
+	}
+}
+
+func errored(err error) *admissionv1.AdmissionResponse {
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: err.Error(),
+			Reason:  metav1.StatusReasonBadRequest,
+			Code:    http.StatusBadRequest,
+		},
+	}
+}
+
+// toResponse converts the validation errors into an admission response
+func toResponse(kind string, name string, errs field.ErrorList) *admissionv1.AdmissionResponse {
+	if len(errs) == 0 {
+		return allowed()
+	}
+
+	return &admissionv1.AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: fmt.Sprintf("%s %q is invalid: %s", kind, name, errs.ToAggregate().Error()),
+			Reason:  metav1.StatusReasonInvalid,
+			Code:    http.StatusUnprocessableEntity,
+		},
+	}
+}
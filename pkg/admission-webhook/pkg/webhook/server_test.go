@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestValidateServiceLevelAgreement(t *testing.T) {
+	testcases := []struct {
+		description string
+		sla         *v1beta1.ServiceLevelAgreement
+		allowed     bool
+	}{
+		{
+			description: "should allow a valid agreement",
+			sla: &v1beta1.ServiceLevelAgreement{
+				Spec: v1beta1.ServiceLevelAgreementSpec{
+					Metric: v1beta1.MetricRequirement{
+						ResponseTime: *resource.NewMilliQuantity(100, resource.BinarySI),
+					},
+					Service: &v1beta1.Service{Container: "app"},
+				},
+			},
+			allowed: true,
+		},
+		{
+			description: "should deny an agreement with a zero response time",
+			sla: &v1beta1.ServiceLevelAgreement{
+				Spec: v1beta1.ServiceLevelAgreementSpec{
+					Service: &v1beta1.Service{Container: "app"},
+				},
+			},
+			allowed: false,
+		},
+	}
+
+	server := httptest.NewServer(NewServer(nil, nil, nil, nil).Handler())
+	defer server.Close()
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			raw, err := json.Marshal(tt.sla)
+			require.Nil(t, err)
+
+			body, err := json.Marshal(&admissionv1.AdmissionReview{
+				Request: &admissionv1.AdmissionRequest{
+					UID:       types.UID("uid"),
+					Operation: admissionv1.Create,
+					Object:    runtime.RawExtension{Raw: raw},
+				},
+			})
+			require.Nil(t, err)
+
+			res, err := http.Post(server.URL+ValidateServiceLevelAgreementPath, "application/json", bytes.NewReader(body))
+			require.Nil(t, err)
+			defer res.Body.Close()
+
+			review := &admissionv1.AdmissionReview{}
+			require.Nil(t, json.NewDecoder(res.Body).Decode(review))
+			require.Equal(t, types.UID("uid"), review.Response.UID)
+			require.Equal(t, tt.allowed, review.Response.Allowed)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package v1beta1
+
+import (
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DefaultControllerParameters returns the parameters used by the recommender
+// feedback controllers when the ServiceLevelAgreement does not tune them.
+func DefaultControllerParameters() ControllerParameters {
+	return ControllerParameters{
+		BC:               resource.NewQuantity(40, resource.DecimalSI),
+		DC:               resource.NewQuantity(80, resource.DecimalSI),
+		MaxScaleOut:      resource.NewMilliQuantity(1500, resource.DecimalSI),
+		MinCPU:           resource.NewMilliQuantity(5, resource.DecimalSI),
+		MinError:         resource.NewQuantity(-10, resource.DecimalSI),
+		MaxError:         resource.NewQuantity(10, resource.DecimalSI),
+		MinBC:            resource.NewQuantity(10, resource.DecimalSI),
+		MaxBC:            resource.NewQuantity(100, resource.DecimalSI),
+		MinDC:            resource.NewQuantity(15, resource.DecimalSI),
+		MaxDC:            resource.NewQuantity(150, resource.DecimalSI),
+		MemoryMargin:     resource.NewMilliQuantity(200, resource.DecimalSI),
+		OOMBumpRatio:     resource.NewMilliQuantity(1500, resource.DecimalSI),
+		KP:               resource.NewQuantity(80, resource.DecimalSI),
+		KI:               resource.NewQuantity(40, resource.DecimalSI),
+		KD:               resource.NewQuantity(20, resource.DecimalSI),
+		DerivativeFilter: resource.NewMilliQuantity(500, resource.DecimalSI),
+	}
+}
+
+// WithDefaults returns a copy of the parameters where the empty ones are
+// replaced by the default values. It can be called on a nil receiver.
+func (p *ControllerParameters) WithDefaults() ControllerParameters {
+	parameters := DefaultControllerParameters()
+	if p == nil {
+		return parameters
+	}
+
+	for _, field := range []struct {
+		value  *resource.Quantity
+		target **resource.Quantity
+	}{
+		{p.BC, &parameters.BC},
+		{p.DC, &parameters.DC},
+		{p.MaxScaleOut, &parameters.MaxScaleOut},
+		{p.MinCPU, &parameters.MinCPU},
+		{p.MinError, &parameters.MinError},
+		{p.MaxError, &parameters.MaxError},
+		{p.MinBC, &parameters.MinBC},
+		{p.MaxBC, &parameters.MaxBC},
+		{p.MinDC, &parameters.MinDC},
+		{p.MaxDC, &parameters.MaxDC},
+		{p.MemoryMargin, &parameters.MemoryMargin},
+		{p.OOMBumpRatio, &parameters.OOMBumpRatio},
+		{p.KP, &parameters.KP},
+		{p.KI, &parameters.KI},
+		{p.KD, &parameters.KD},
+		{p.DerivativeFilter, &parameters.DerivativeFilter},
+	} {
+		if field.value != nil {
+			value := field.value.DeepCopy()
+			*field.target = &value
+		}
+	}
+
+	return parameters
+}
+
+// DefaultBurstRatio is the ratio between the limits and the requests of
+// Burstable pods when the ServiceLevelAgreement does not set it.
+var DefaultBurstRatio = resource.MustParse("2")
+
+// IsBurstable returns true if the pods tracked by the agreement are Burstable
+func (s *ServiceLevelAgreementSpec) IsBurstable() bool {
+	return s.QOSClass == v1.PodQOSBurstable
+}
+
+// BurstRatioOrDefault returns the burst ratio of the agreement or the default one
+func (s *ServiceLevelAgreementSpec) BurstRatioOrDefault() resource.Quantity {
+	if s.BurstRatio == nil {
+		return DefaultBurstRatio.DeepCopy()
+	}
+	return s.BurstRatio.DeepCopy()
+}
+
+// DefaultForecastParameters returns the parameters used to forecast the load
+// when the ServiceLevelAgreement enables the forecasting without tuning it.
+func DefaultForecastParameters() ForecastParameters {
+	return ForecastParameters{
+		Model:    HoltWinters,
+		Interval: &metav1.Duration{Duration: time.Minute},
+		Season:   &metav1.Duration{Duration: 24 * time.Hour},
+		Horizon:  &metav1.Duration{Duration: 5 * time.Minute},
+		Alpha:    resource.NewMilliQuantity(500, resource.DecimalSI),
+		Beta:     resource.NewMilliQuantity(100, resource.DecimalSI),
+		Gamma:    resource.NewMilliQuantity(100, resource.DecimalSI),
+	}
+}
+
+// WithDefaults returns a copy of the parameters where the empty ones are
+// replaced by the default values. It can be called on a nil receiver.
+func (p *ForecastParameters) WithDefaults() ForecastParameters {
+	parameters := DefaultForecastParameters()
+	if p == nil {
+		return parameters
+	}
+
+	if p.Model != "" {
+		parameters.Model = p.Model
+	}
+
+	for _, field := range []struct {
+		value  *metav1.Duration
+		target **metav1.Duration
+	}{
+		{p.Interval, &parameters.Interval},
+		{p.Season, &parameters.Season},
+		{p.Horizon, &parameters.Horizon},
+	} {
+		if field.value != nil {
+			*field.target = field.value.DeepCopy()
+		}
+	}
+
+	for _, field := range []struct {
+		value  *resource.Quantity
+		target **resource.Quantity
+	}{
+		{p.Alpha, &parameters.Alpha},
+		{p.Beta, &parameters.Beta},
+		{p.Gamma, &parameters.Gamma},
+	} {
+		if field.value != nil {
+			value := field.value.DeepCopy()
+			*field.target = &value
+		}
+	}
+
+	return parameters
+}
+
+// IdlePolicyOrDefault returns the idle policy of the agreement or the default one
+func (s *ServiceLevelAgreementSpec) IdlePolicyOrDefault() IdlePolicy {
+	if s.Idle == nil || s.Idle.Policy == "" {
+		return IdleHold
+	}
+	return s.Idle.Policy
+}
+
+// IdleResourcesOrDefault returns the resources assigned to idle pods by the scaleDown policy,
+// which default to the minimum resources of the agreement
+func (s *ServiceLevelAgreementSpec) IdleResourcesOrDefault() v1.ResourceList {
+	if s.Idle == nil || s.Idle.Resources == nil {
+		return s.MinResources
+	}
+	return s.Idle.Resources
+}
+
+// RecordDecision appends the decision to the history, dropping the oldest ones beyond MaxDecisions
+func (s *PodScaleStatus) RecordDecision(decision Decision) {
+	s.Decisions = append(s.Decisions, decision)
+	if overflow := len(s.Decisions) - MaxDecisions; overflow > 0 {
+		s.Decisions = append([]Decision(nil), s.Decisions[overflow:]...)
+	}
+}
+
+// LastDecision returns the most recent decision, nil when the history is empty
+func (s *PodScaleStatus) LastDecision() *Decision {
+	if len(s.Decisions) == 0 {
+		return nil
+	}
+	return &s.Decisions[len(s.Decisions)-1]
+}
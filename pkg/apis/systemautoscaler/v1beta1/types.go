@@ -18,6 +18,13 @@ type ServiceLevelAgreementList struct {
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:resource:shortName=sla
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Violated",type=string,JSONPath=`.status.conditions[?(@.type=="Violated")].status`
+// +kubebuilder:printcolumn:name="PodScales",type=integer,JSONPath=`.status.trackedPodScales`
+// +kubebuilder:printcolumn:name="Response Time",type=string,JSONPath=`.status.lastResponseTime`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // ServiceLevelAgreement is a configuration for the autoscaling system.
 // It sets a requirement on the services that matches the selector.
@@ -27,6 +34,8 @@ type ServiceLevelAgreement struct {
 
 	// +kubebuilder:validation:Required
 	Spec ServiceLevelAgreementSpec `json:"spec"`
+	// +kubebuilder:validation:Optional
+	Status ServiceLevelAgreementStatus `json:"status,omitempty"`
 }
 
 // RecommendLogic defines logic used during the recommendation phase
@@ -35,6 +44,36 @@ type RecommendLogic string
 const (
 	FixedGainControl    RecommendLogic = "fixedGainControl"
 	AdaptiveGainControl RecommendLogic = "adaptiveGainControl"
+	PIDControl          RecommendLogic = "pidControl"
+	QueueingModel       RecommendLogic = "queueingModel"
+)
+
+// ForecastModel defines the model used to predict the load of a Service
+type ForecastModel string
+
+const (
+	HoltWinters   ForecastModel = "holtWinters"
+	SeasonalNaive ForecastModel = "seasonalNaive"
+)
+
+// IdlePolicy defines how the recommender handles the pods not receiving any request
+type IdlePolicy string
+
+const (
+	// IdleHold keeps the resources the pod had when it became idle
+	IdleHold IdlePolicy = "hold"
+	// IdleScaleDown assigns the idle resources to the pod
+	IdleScaleDown IdlePolicy = "scaleDown"
+)
+
+// ResponseTimePercentile defines which statistic of the response time distribution is tracked
+type ResponseTimePercentile string
+
+const (
+	Mean ResponseTimePercentile = "mean"
+	P90  ResponseTimePercentile = "p90"
+	P95  ResponseTimePercentile = "p95"
+	P99  ResponseTimePercentile = "p99"
 )
 
 // ServiceLevelAgreementSpec defines the agreement specifying the
@@ -48,8 +87,11 @@ type ServiceLevelAgreementSpec struct {
 	Metric MetricRequirement `json:"metric"`
 	// Specify the logic used during the recommendation phase
 	// +kubebuilder:validation:Optional
-	// +kubebuilder:default:="fixed_gain_control"
+	// +kubebuilder:default:="fixedGainControl"
 	RecommenderLogic RecommendLogic `json:"recommenderLogic"`
+	// Tune the feedback controller used during the recommendation phase.
+	// +kubebuilder:validation:Optional
+	ControllerParameters *ControllerParameters `json:"controllerParameters,omitempty"`
 	// Specify the default resources assigned to pods in case `requests` field is empty in `PodSpec`.
 	// +kubebuilder:validation:Required
 	DefaultResources v1.ResourceList `json:"defaultResources,omitempty" protobuf:"bytes,3,rep,name=defaultResources,casttype=ResourceList,castkey=ResourceName"`
@@ -59,6 +101,15 @@ type ServiceLevelAgreementSpec struct {
 	// The upper bound of resources to assign to containers.
 	// +kubebuilder:validation:Optional
 	MaxResources v1.ResourceList `json:"maxResources,omitempty" protobuf:"bytes,3,rep,name=maxResources,casttype=ResourceList,castkey=ResourceName"`
+	// The QOS class of the pods tracked by the agreement. Guaranteed pods have the same requests and limits,
+	// while Burstable pods get limits greater than the requests by the burst ratio.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=Guaranteed;Burstable
+	// +kubebuilder:default:="Guaranteed"
+	QOSClass v1.PodQOSClass `json:"qosClass,omitempty"`
+	// The ratio between the limits and the requests recommended to Burstable pods. Defaults to 2.
+	// +kubebuilder:validation:Optional
+	BurstRatio *resource.Quantity `json:"burstRatio,omitempty"`
 	// The lower bound of replicas for the application.
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default:=1
@@ -67,11 +118,171 @@ type ServiceLevelAgreementSpec struct {
 	// +kubebuilder:validation:Optional
 	// +kubebuilder:default:=100
 	MaxReplicas int32 `json:"maxReplicas,omitempty" protobuf:"bytes,3,rep,name=maxResources,casttype=ResourceList,castkey=ResourceName"`
+	// The priority of the agreement when the resources of a node are contended.
+	// The pods of the agreements with higher priority are satisfied first, while the
+	// others are squeezed down to their minimum resources.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:default:=0
+	Priority int32 `json:"priority,omitempty"`
+	// Enable the forecasting of the load of the Service, so that the resources and the replicas
+	// are recommended for the load predicted ahead instead of the current one.
+	// +kubebuilder:validation:Optional
+	Forecast *ForecastParameters `json:"forecast,omitempty"`
+	// Specify how the pods not receiving any request are handled. By default they hold their resources.
+	// +kubebuilder:validation:Optional
+	Idle *IdleParameters `json:"idle,omitempty"`
+	// Enable the shadow mode, in which the resources and the replicas are recommended and recorded
+	// in the PodScale status, but the Pods and the Deployments are never changed.
+	// +kubebuilder:validation:Optional
+	Shadow bool `json:"shadow,omitempty"`
 	// Identify the Service on which the agreement is defined
 	// +kubebuilder:validation:Required
 	Service *Service `json:"service"`
 }
 
+// ControllerParameters tunes the feedback controllers used by the recommender.
+// Parameters left empty take the recommender default values.
+type ControllerParameters struct {
+	// The gain of the integral term of the controller. Defaults to 40.
+	// +kubebuilder:validation:Optional
+	BC *resource.Quantity `json:"bc,omitempty"`
+	// The gain of the proportional term of the controller. Defaults to 80.
+	// +kubebuilder:validation:Optional
+	DC *resource.Quantity `json:"dc,omitempty"`
+	// The maximum ratio between the recommended CPU and the actual one. Defaults to 1.5.
+	// +kubebuilder:validation:Optional
+	MaxScaleOut *resource.Quantity `json:"maxScaleOut,omitempty"`
+	// The minimum CPU recommended to a container. Defaults to 5m.
+	// +kubebuilder:validation:Optional
+	MinCPU *resource.Quantity `json:"minCPU,omitempty"`
+	// The lower bound of the control error. Defaults to -10.
+	// +kubebuilder:validation:Optional
+	MinError *resource.Quantity `json:"minError,omitempty"`
+	// The upper bound of the control error. Defaults to 10.
+	// +kubebuilder:validation:Optional
+	MaxError *resource.Quantity `json:"maxError,omitempty"`
+	// The lower bound of the integral gain of the adaptive controller. Defaults to 10.
+	// +kubebuilder:validation:Optional
+	MinBC *resource.Quantity `json:"minBC,omitempty"`
+	// The upper bound of the integral gain of the adaptive controller. Defaults to 100.
+	// +kubebuilder:validation:Optional
+	MaxBC *resource.Quantity `json:"maxBC,omitempty"`
+	// The lower bound of the proportional gain of the adaptive controller. Defaults to 15.
+	// +kubebuilder:validation:Optional
+	MinDC *resource.Quantity `json:"minDC,omitempty"`
+	// The upper bound of the proportional gain of the adaptive controller. Defaults to 150.
+	// +kubebuilder:validation:Optional
+	MaxDC *resource.Quantity `json:"maxDC,omitempty"`
+	// The fraction of the observed working set added to the recommended memory. Defaults to 0.2.
+	// +kubebuilder:validation:Optional
+	MemoryMargin *resource.Quantity `json:"memoryMargin,omitempty"`
+	// The ratio applied to the memory of a container killed because out of memory. Defaults to 1.5.
+	// +kubebuilder:validation:Optional
+	OOMBumpRatio *resource.Quantity `json:"oomBumpRatio,omitempty"`
+	// The proportional gain of the PID controller. Defaults to 80.
+	// +kubebuilder:validation:Optional
+	KP *resource.Quantity `json:"kp,omitempty"`
+	// The integral gain of the PID controller. Defaults to 40.
+	// +kubebuilder:validation:Optional
+	KI *resource.Quantity `json:"ki,omitempty"`
+	// The derivative gain of the PID controller. Defaults to 20.
+	// +kubebuilder:validation:Optional
+	KD *resource.Quantity `json:"kd,omitempty"`
+	// The smoothing factor of the low pass filter applied to the derivative term of the
+	// PID controller, between 0 (no filter) and 1 excluded. Defaults to 0.5.
+	// +kubebuilder:validation:Optional
+	DerivativeFilter *resource.Quantity `json:"derivativeFilter,omitempty"`
+}
+
+// ForecastParameters tune the forecasting of the load of a Service.
+// The load history is sampled at every interval and the seasonal models
+// need a whole season of history before predicting the load.
+type ForecastParameters struct {
+	// The model used to predict the load.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=holtWinters;seasonalNaive
+	// +kubebuilder:default:="holtWinters"
+	Model ForecastModel `json:"model,omitempty"`
+	// The interval between two samples of the load history. Defaults to 1m.
+	// +kubebuilder:validation:Optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// The period of the seasonal pattern of the load. Defaults to 24h.
+	// +kubebuilder:validation:Optional
+	Season *metav1.Duration `json:"season,omitempty"`
+	// How far ahead the load is predicted. Defaults to 5m.
+	// +kubebuilder:validation:Optional
+	Horizon *metav1.Duration `json:"horizon,omitempty"`
+	// The smoothing factor of the level of the Holt-Winters model. Defaults to 0.5.
+	// +kubebuilder:validation:Optional
+	Alpha *resource.Quantity `json:"alpha,omitempty"`
+	// The smoothing factor of the trend of the Holt-Winters model. Defaults to 0.1.
+	// +kubebuilder:validation:Optional
+	Beta *resource.Quantity `json:"beta,omitempty"`
+	// The smoothing factor of the seasonal component of the Holt-Winters model. Defaults to 0.1.
+	// +kubebuilder:validation:Optional
+	Gamma *resource.Quantity `json:"gamma,omitempty"`
+}
+
+// IdleParameters define the allocation of the pods not receiving any request.
+// The pods go back to the resources they had before becoming idle as soon as
+// they receive requests again.
+type IdleParameters struct {
+	// The policy applied to idle pods.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=hold;scaleDown
+	// +kubebuilder:default:="hold"
+	Policy IdlePolicy `json:"policy,omitempty"`
+	// The resources assigned to idle pods by the scaleDown policy. Defaults to the minResources of the agreement.
+	// +kubebuilder:validation:Optional
+	Resources v1.ResourceList `json:"resources,omitempty"`
+}
+
+// Condition types reported in the ServiceLevelAgreement status
+const (
+	// SLAReady is true when the agreement matches at least one Service
+	// and its PodScales are in sync with the Service Pods
+	SLAReady = "Ready"
+	// SLAViolated is true when at least one metric requirement is not
+	// honored by one of the matched Services
+	SLAViolated = "Violated"
+	// SLADegraded is true when some Pods matched by the agreement cannot
+	// be tracked by System Autoscaler
+	SLADegraded = "Degraded"
+)
+
+// ServiceLevelAgreementStatus reports how the agreement is applied to the
+// matched Services and whether it is honored.
+type ServiceLevelAgreementStatus struct {
+	// The generation of the agreement observed by the controller.
+	// +kubebuilder:validation:Optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// The Services matched by the agreement selector.
+	// +kubebuilder:validation:Optional
+	MatchedServices []string `json:"matchedServices,omitempty"`
+	// The number of PodScales tracking the Pods of the matched Services.
+	// +kubebuilder:validation:Optional
+	TrackedPodScales int32 `json:"trackedPodScales"`
+	// The Pods of the matched Services that cannot be tracked.
+	// +kubebuilder:validation:Optional
+	SkippedPods []SkippedPod `json:"skippedPods,omitempty"`
+	// The highest response time observed among the matched Services, in seconds.
+	// +kubebuilder:validation:Optional
+	LastResponseTime *resource.Quantity `json:"lastResponseTime,omitempty"`
+	// The replicas recommended for the Deployment of the matched Services in shadow mode.
+	// +kubebuilder:validation:Optional
+	RecommendedReplicas *int32 `json:"recommendedReplicas,omitempty"`
+	// The compliance conditions of the agreement.
+	// +kubebuilder:validation:Optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// SkippedPod is a Pod matched by a ServiceLevelAgreement which is not tracked
+// by a PodScale, together with the reason why it has been skipped.
+type SkippedPod struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
 // Service is used to identify the application to scale by its service Lavels and the container offering the Application service
 type Service struct {
 	// Specify the selector to match Services and Service Level Agreement
@@ -85,16 +296,32 @@ type Service struct {
 // MetricRequirement specifies a requirement for a metric.
 // This means that System Autoscaler will try to honor the
 // agreement, making the service metric coherent with it.
-// Only one MetricRequirement per ServiceLevelAgreement resource
-// must be set to avoid ambiguity.
-// Currently supports only ResponseTime.
+// Multiple requirements can be set in the same MetricRequirement:
+// in that case System Autoscaler follows the most violated one.
+// Requirements left empty are ignored.
 //
 // i.e.: the metric type is the Response Time and the value
 // is 4 units of time. This means that the system will try
 // to keep the service response time below 4 on average.
 type MetricRequirement struct {
-	// +kubebuilder:validation:Required
+	// The upper bound of the service response time, in seconds.
+	// +kubebuilder:validation:Optional
 	ResponseTime resource.Quantity `json:"responseTime,omitempty"`
+	// Specify whether the response time requirement is set on the mean or on a percentile
+	// of the response time distribution.
+	// +kubebuilder:validation:Optional
+	// +kubebuilder:validation:Enum=mean;p90;p95;p99
+	// +kubebuilder:default:="mean"
+	ResponseTimePercentile ResponseTimePercentile `json:"responseTimePercentile,omitempty"`
+	// The lower bound of the service throughput, in requests per second.
+	// +kubebuilder:validation:Optional
+	Throughput *resource.Quantity `json:"throughput,omitempty"`
+	// The upper bound of the ratio of requests answered with a server error.
+	// +kubebuilder:validation:Optional
+	ErrorRate *resource.Quantity `json:"errorRate,omitempty"`
+	// The target ratio between the CPU used by the container and the CPU assigned to it.
+	// +kubebuilder:validation:Optional
+	CPUUtilization *resource.Quantity `json:"cpuUtilization,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -109,6 +336,7 @@ type PodScaleList struct {
 
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:subresource:status
 
 // PodScale defines the mapping between a `ServiceLevelAgreement` and a
 // `Pod` matching the selector. It also keeps track of the resource values
@@ -137,4 +365,74 @@ type PodScaleSpec struct {
 type PodScaleStatus struct {
 	CappedResources v1.ResourceList `json:"capped,omitempty" protobuf:"bytes,3,rep,name=actual,casttype=ResourceList,castkey=ResourceName"`
 	ActualResources v1.ResourceList `json:"actual,omitempty" protobuf:"bytes,3,rep,name=actual,casttype=ResourceList,castkey=ResourceName"`
+	// The limits recommended to Burstable pods. Guaranteed pods have limits equal to the capped resources.
+	CappedLimits v1.ResourceList `json:"cappedLimits,omitempty"`
+	// The limits assigned to Burstable pods. Guaranteed pods have limits equal to the actual resources.
+	ActualLimits v1.ResourceList `json:"actualLimits,omitempty"`
+	// The state of the recommender feedback controller, used to restore it after a restart
+	ControllerState *ControllerState `json:"controllerState,omitempty"`
+	// The time the resources of the pod were last changed
+	LastResizeTime *metav1.Time `json:"lastResizeTime,omitempty"`
+	// The status of the last in-place resize of the pod, empty when it has been completed or
+	// the cluster does not support the resize subresource
+	ResizeStatus PodResizeStatus `json:"resizeStatus,omitempty"`
+	// The most recent resource decisions taken for the pod, the oldest first
+	Decisions []Decision `json:"decisions,omitempty"`
+}
+
+// MaxDecisions is the number of decisions kept in the PodScale status
+const MaxDecisions = 10
+
+// Decision is a resource decision taken by the Recommender and the Contention Manager for a pod
+type Decision struct {
+	// The time of the recommendation
+	Timestamp metav1.Time `json:"timestamp"`
+	// The response time of the pod observed by the Recommender, in seconds
+	ResponseTime *resource.Quantity `json:"responseTime,omitempty"`
+	// The control error computed by the Recommender
+	Error *resource.Quantity `json:"error,omitempty"`
+	// The resources recommended for the pod
+	Desired v1.ResourceList `json:"desired,omitempty"`
+	// The recommended resources bounded by the ServiceLevelAgreement
+	Capped v1.ResourceList `json:"capped,omitempty"`
+	// The resources assigned by the Contention Manager
+	Actual v1.ResourceList `json:"actual,omitempty"`
+	// Why the assigned resources differ from the recommended ones, empty when they do not
+	CapReason string `json:"capReason,omitempty"`
+}
+
+// PodResizeStatus is the status of an in-place resize of a pod reported by the kubelet
+type PodResizeStatus string
+
+const (
+	// ResizeProposed means that the resize has been accepted by the API server but not yet by the kubelet
+	ResizeProposed PodResizeStatus = "Proposed"
+	// ResizeInProgress means that the kubelet is actuating the resize
+	ResizeInProgress PodResizeStatus = "InProgress"
+	// ResizeDeferred means that the resize is feasible but the node cannot currently fit it
+	ResizeDeferred PodResizeStatus = "Deferred"
+	// ResizeInfeasible means that the node will never fit the resize
+	ResizeInfeasible PodResizeStatus = "Infeasible"
+)
+
+// ControllerState is the checkpoint of the feedback controller tracking a container.
+type ControllerState struct {
+	// The CPU computed by the integral term of the controller at the last iteration.
+	XCPrec *resource.Quantity `json:"xcprec,omitempty"`
+	// The CPU assigned to the container at the last iteration.
+	Cores *resource.Quantity `json:"cores,omitempty"`
+	// The control error of the last iteration.
+	PrevError *resource.Quantity `json:"prevError,omitempty"`
+	// The integral gain of the adaptive controller.
+	BC *resource.Quantity `json:"bc,omitempty"`
+	// The proportional gain of the adaptive controller.
+	DC *resource.Quantity `json:"dc,omitempty"`
+	// The filtered derivative of the control error of the PID controller.
+	Derivative *resource.Quantity `json:"derivative,omitempty"`
+	// The resources assigned to the container before it became idle.
+	BusyResources v1.ResourceList `json:"busyResources,omitempty"`
+	// The time the container was last killed because out of memory.
+	LastOOM *metav1.Time `json:"lastOOM,omitempty"`
+	// The memory recommended after the last out of memory kill, used as lower bound.
+	OOMMemory *resource.Quantity `json:"oomMemory,omitempty"`
 }
@@ -10,10 +10,300 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerParameters) DeepCopyInto(out *ControllerParameters) {
+	*out = *in
+	if in.BC != nil {
+		in, out := &in.BC, &out.BC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DC != nil {
+		in, out := &in.DC, &out.DC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxScaleOut != nil {
+		in, out := &in.MaxScaleOut, &out.MaxScaleOut
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinCPU != nil {
+		in, out := &in.MinCPU, &out.MinCPU
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinError != nil {
+		in, out := &in.MinError, &out.MinError
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxError != nil {
+		in, out := &in.MaxError, &out.MaxError
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinBC != nil {
+		in, out := &in.MinBC, &out.MinBC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxBC != nil {
+		in, out := &in.MaxBC, &out.MaxBC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MinDC != nil {
+		in, out := &in.MinDC, &out.MinDC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MaxDC != nil {
+		in, out := &in.MaxDC, &out.MaxDC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.MemoryMargin != nil {
+		in, out := &in.MemoryMargin, &out.MemoryMargin
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.OOMBumpRatio != nil {
+		in, out := &in.OOMBumpRatio, &out.OOMBumpRatio
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.KP != nil {
+		in, out := &in.KP, &out.KP
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.KI != nil {
+		in, out := &in.KI, &out.KI
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.KD != nil {
+		in, out := &in.KD, &out.KD
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DerivativeFilter != nil {
+		in, out := &in.DerivativeFilter, &out.DerivativeFilter
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerParameters.
+func (in *ControllerParameters) DeepCopy() *ControllerParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ControllerState) DeepCopyInto(out *ControllerState) {
+	*out = *in
+	if in.XCPrec != nil {
+		in, out := &in.XCPrec, &out.XCPrec
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Cores != nil {
+		in, out := &in.Cores, &out.Cores
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.PrevError != nil {
+		in, out := &in.PrevError, &out.PrevError
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.BC != nil {
+		in, out := &in.BC, &out.BC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.DC != nil {
+		in, out := &in.DC, &out.DC
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Derivative != nil {
+		in, out := &in.Derivative, &out.Derivative
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.BusyResources != nil {
+		in, out := &in.BusyResources, &out.BusyResources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.LastOOM != nil {
+		in, out := &in.LastOOM, &out.LastOOM
+		*out = (*in).DeepCopy()
+	}
+	if in.OOMMemory != nil {
+		in, out := &in.OOMMemory, &out.OOMMemory
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ControllerState.
+func (in *ControllerState) DeepCopy() *ControllerState {
+	if in == nil {
+		return nil
+	}
+	out := new(ControllerState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Decision) DeepCopyInto(out *Decision) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	if in.ResponseTime != nil {
+		in, out := &in.ResponseTime, &out.ResponseTime
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Error != nil {
+		in, out := &in.Error, &out.Error
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Desired != nil {
+		in, out := &in.Desired, &out.Desired
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Capped != nil {
+		in, out := &in.Capped, &out.Capped
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Actual != nil {
+		in, out := &in.Actual, &out.Actual
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Decision.
+func (in *Decision) DeepCopy() *Decision {
+	if in == nil {
+		return nil
+	}
+	out := new(Decision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ForecastParameters) DeepCopyInto(out *ForecastParameters) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Season != nil {
+		in, out := &in.Season, &out.Season
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Horizon != nil {
+		in, out := &in.Horizon, &out.Horizon
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Alpha != nil {
+		in, out := &in.Alpha, &out.Alpha
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Beta != nil {
+		in, out := &in.Beta, &out.Beta
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Gamma != nil {
+		in, out := &in.Gamma, &out.Gamma
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ForecastParameters.
+func (in *ForecastParameters) DeepCopy() *ForecastParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(ForecastParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IdleParameters) DeepCopyInto(out *IdleParameters) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IdleParameters.
+func (in *IdleParameters) DeepCopy() *IdleParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(IdleParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetricRequirement) DeepCopyInto(out *MetricRequirement) {
 	*out = *in
 	out.ResponseTime = in.ResponseTime.DeepCopy()
+	if in.Throughput != nil {
+		in, out := &in.Throughput, &out.Throughput
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.ErrorRate != nil {
+		in, out := &in.ErrorRate, &out.ErrorRate
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.CPUUtilization != nil {
+		in, out := &in.CPUUtilization, &out.CPUUtilization
+		x := (*in).DeepCopy()
+		*out = &x
+	}
 	return
 }
 
@@ -128,6 +418,36 @@ func (in *PodScaleStatus) DeepCopyInto(out *PodScaleStatus) {
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.CappedLimits != nil {
+		in, out := &in.CappedLimits, &out.CappedLimits
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.ActualLimits != nil {
+		in, out := &in.ActualLimits, &out.ActualLimits
+		*out = make(v1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.ControllerState != nil {
+		in, out := &in.ControllerState, &out.ControllerState
+		*out = new(ControllerState)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastResizeTime != nil {
+		in, out := &in.LastResizeTime, &out.LastResizeTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Decisions != nil {
+		in, out := &in.Decisions, &out.Decisions
+		*out = make([]Decision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -168,6 +488,7 @@ func (in *ServiceLevelAgreement) DeepCopyInto(out *ServiceLevelAgreement) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -226,6 +547,11 @@ func (in *ServiceLevelAgreementList) DeepCopyObject() runtime.Object {
 func (in *ServiceLevelAgreementSpec) DeepCopyInto(out *ServiceLevelAgreementSpec) {
 	*out = *in
 	in.Metric.DeepCopyInto(&out.Metric)
+	if in.ControllerParameters != nil {
+		in, out := &in.ControllerParameters, &out.ControllerParameters
+		*out = new(ControllerParameters)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.DefaultResources != nil {
 		in, out := &in.DefaultResources, &out.DefaultResources
 		*out = make(v1.ResourceList, len(*in))
@@ -247,6 +573,21 @@ func (in *ServiceLevelAgreementSpec) DeepCopyInto(out *ServiceLevelAgreementSpec
 			(*out)[key] = val.DeepCopy()
 		}
 	}
+	if in.BurstRatio != nil {
+		in, out := &in.BurstRatio, &out.BurstRatio
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.Forecast != nil {
+		in, out := &in.Forecast, &out.Forecast
+		*out = new(ForecastParameters)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Idle != nil {
+		in, out := &in.Idle, &out.Idle
+		*out = new(IdleParameters)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Service != nil {
 		in, out := &in.Service, &out.Service
 		*out = new(Service)
@@ -264,3 +605,62 @@ func (in *ServiceLevelAgreementSpec) DeepCopy() *ServiceLevelAgreementSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServiceLevelAgreementStatus) DeepCopyInto(out *ServiceLevelAgreementStatus) {
+	*out = *in
+	if in.MatchedServices != nil {
+		in, out := &in.MatchedServices, &out.MatchedServices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SkippedPods != nil {
+		in, out := &in.SkippedPods, &out.SkippedPods
+		*out = make([]SkippedPod, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastResponseTime != nil {
+		in, out := &in.LastResponseTime, &out.LastResponseTime
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.RecommendedReplicas != nil {
+		in, out := &in.RecommendedReplicas, &out.RecommendedReplicas
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServiceLevelAgreementStatus.
+func (in *ServiceLevelAgreementStatus) DeepCopy() *ServiceLevelAgreementStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ServiceLevelAgreementStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SkippedPod) DeepCopyInto(out *SkippedPod) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SkippedPod.
+func (in *SkippedPod) DeepCopy() *SkippedPod {
+	if in == nil {
+		return nil
+	}
+	out := new(SkippedPod)
+	in.DeepCopyInto(out)
+	return out
+}
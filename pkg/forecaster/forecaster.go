@@ -0,0 +1,212 @@
+package forecaster
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
+	"github.com/modern-go/concurrent"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// Sample is the load of a Service at a given time
+type Sample struct {
+	// Throughput is the rate of the requests served by the Service, in requests per second
+	Throughput float64
+	// ResponseTime is the mean response time of the Service, in seconds
+	ResponseTime float64
+}
+
+// history is the load history of a Service along with the parameters it has been created with
+type history struct {
+	sync.Mutex
+	parameters   v1beta1.ForecastParameters
+	throughput   Model
+	responseTime Model
+	last         time.Time
+	current      Sample
+}
+
+// newHistory creates an empty history tuned with the forecast parameters
+func newHistory(parameters v1beta1.ForecastParameters) *history {
+	return &history{
+		parameters:   parameters,
+		throughput:   newModel(parameters),
+		responseTime: newModel(parameters),
+	}
+}
+
+// newModel returns the model requested by the forecast parameters
+func newModel(parameters v1beta1.ForecastParameters) Model {
+	season := seasonLength(parameters)
+	switch parameters.Model {
+	case v1beta1.SeasonalNaive:
+		return newSeasonalNaive(season)
+	default:
+		return newHoltWinters(toFloat(parameters.Alpha), toFloat(parameters.Beta), toFloat(parameters.Gamma), season)
+	}
+}
+
+// seasonLength returns the number of samples in a season, at least one
+func seasonLength(parameters v1beta1.ForecastParameters) int {
+	return int(math.Max(1, float64(parameters.Season.Duration/parameters.Interval.Duration)))
+}
+
+// horizon returns the number of samples between the last one and the forecasted one, at least one
+func horizon(parameters v1beta1.ForecastParameters) int {
+	return int(math.Max(1, math.Ceil(float64(parameters.Horizon.Duration)/float64(parameters.Interval.Duration))))
+}
+
+func toFloat(q *resource.Quantity) float64 {
+	return float64(q.MilliValue()) / 1000
+}
+
+// Forecaster keeps the load history of the Services and predicts their future load.
+// It is safe for concurrent use.
+type Forecaster struct {
+	// Key: namespace/name of the Service, Value: load history
+	histories concurrent.Map
+}
+
+// NewForecaster returns a forecaster without any history
+func NewForecaster() *Forecaster {
+	return &Forecaster{
+		histories: *concurrent.NewMap(),
+	}
+}
+
+// Observe records the load of the Service. The load is sampled at most once per interval,
+// so it can be called at every recommendation. The history is reset when the parameters change.
+func (f *Forecaster) Observe(key string, parameters *v1beta1.ForecastParameters, now time.Time, sample Sample) {
+	p := parameters.WithDefaults()
+
+	h, ok := f.load(key)
+	if !ok || !equality.Semantic.DeepEqual(h.parameters, p) {
+		klog.V(4).Info("Resetting the load history of service ", key)
+		h = newHistory(p)
+		f.histories.Store(key, h)
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.current = sample
+	if !h.last.IsZero() && now.Sub(h.last) < p.Interval.Duration {
+		return
+	}
+	h.last = now
+	h.throughput.Observe(sample.Throughput)
+	h.responseTime.Observe(sample.ResponseTime)
+}
+
+// Forecast returns the load of the Service predicted at the horizon.
+// It returns false while the history is not long enough.
+func (f *Forecaster) Forecast(key string) (Sample, bool) {
+	h, ok := f.load(key)
+	if !ok {
+		return Sample{}, false
+	}
+
+	h.Lock()
+	defer h.Unlock()
+	return h.forecast()
+}
+
+// forecast returns the load predicted at the horizon, the caller must hold the lock
+func (h *history) forecast() (Sample, bool) {
+	steps := horizon(h.parameters)
+	throughput, ok := h.throughput.Forecast(steps)
+	if !ok {
+		return Sample{}, false
+	}
+	responseTime, _ := h.responseTime.Forecast(steps)
+
+	return Sample{
+		Throughput:   math.Max(0, throughput),
+		ResponseTime: math.Max(0, responseTime),
+	}, true
+}
+
+// Growth returns the ratio between the throughput of the Service predicted at the horizon
+// and the last observed one. The ratio is 1 when the prediction is not available or the
+// Service does not serve any request.
+func (f *Forecaster) Growth(key string) float64 {
+	h, ok := f.load(key)
+	if !ok {
+		return 1
+	}
+
+	h.Lock()
+	defer h.Unlock()
+
+	predicted, ok := h.forecast()
+	if !ok || h.current.Throughput <= 0 {
+		return 1
+	}
+
+	return predicted.Throughput / h.current.Throughput
+}
+
+// Retain evicts the histories of the Services for which alive returns false
+func (f *Forecaster) Retain(alive func(key string) bool) {
+	f.histories.Range(func(k, v interface{}) bool {
+		key, ok := k.(string)
+		if !ok || !alive(key) {
+			klog.V(4).Info("Evicting the load history of service ", k)
+			f.histories.Delete(k)
+		}
+		return true
+	})
+}
+
+func (f *Forecaster) load(key string) (*history, bool) {
+	v, ok := f.histories.Load(key)
+	if !ok {
+		return nil, false
+	}
+	h, ok := v.(*history)
+	return h, ok
+}
+
+// ObserveService retrieves the load of the Service through the metric client, records it
+// and returns the growth of its throughput predicted at the horizon.
+func (f *Forecaster) ObserveService(service *corev1.Service, parameters *v1beta1.ForecastParameters, metricClient metricsgetter.MetricGetter) (float64, error) {
+	throughput, err := metricClient.ServiceMetrics(service, metrics.Throughput)
+	if err != nil {
+		return 1, err
+	}
+
+	responseTime, err := metricClient.ServiceMetrics(service, metrics.ResponseTime)
+	if err != nil {
+		return 1, err
+	}
+
+	key := service.Namespace + "/" + service.Name
+	f.Observe(key, parameters, time.Now(), Sample{
+		Throughput:   toFloat(&throughput.Value),
+		ResponseTime: toFloat(&responseTime.Value),
+	})
+
+	return f.Growth(key), nil
+}
+
+// Sweep evicts the histories of the Services that no longer exist
+func (f *Forecaster) Sweep(services corelisters.ServiceLister) {
+	f.Retain(func(key string) bool {
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			return false
+		}
+		_, err = services.Services(namespace).Get(name)
+		return !errors.IsNotFound(err)
+	})
+}
@@ -0,0 +1,70 @@
+package forecaster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestForecaster(t *testing.T) {
+	parameters := &v1beta1.ForecastParameters{
+		Model:    v1beta1.SeasonalNaive,
+		Interval: &metav1.Duration{Duration: time.Minute},
+		Season:   &metav1.Duration{Duration: 4 * time.Minute},
+		Horizon:  &metav1.Duration{Duration: 90 * time.Second},
+	}
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	pattern := []float64{10, 20, 40, 20}
+
+	f := NewForecaster()
+	require.Equal(t, float64(1), f.Growth("default/service"))
+
+	for i, throughput := range pattern {
+		now := start.Add(time.Duration(i) * time.Minute)
+		f.Observe("default/service", parameters, now, Sample{Throughput: throughput, ResponseTime: 0.1})
+		// samples within the interval only update the current load
+		f.Observe("default/service", parameters, now.Add(time.Second), Sample{Throughput: throughput, ResponseTime: 0.1})
+	}
+
+	// the horizon is rounded up to two samples ahead
+	forecast, ok := f.Forecast("default/service")
+	require.True(t, ok)
+	require.Equal(t, Sample{Throughput: 20, ResponseTime: 0.1}, forecast)
+	require.Equal(t, float64(1), f.Growth("default/service"))
+
+	f.Observe("default/service", parameters, start.Add(4*time.Minute), Sample{Throughput: 10})
+	require.Equal(t, float64(4), f.Growth("default/service"))
+
+	// changing the parameters resets the history
+	changed := parameters.DeepCopy()
+	changed.Model = v1beta1.HoltWinters
+	f.Observe("default/service", changed, start.Add(5*time.Minute), Sample{Throughput: 10})
+	_, ok = f.Forecast("default/service")
+	require.False(t, ok)
+
+	f.Retain(func(key string) bool { return key != "default/service" })
+	_, ok = f.load("default/service")
+	require.False(t, ok)
+}
+
+func TestSweep(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.Nil(t, indexer.Add(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "alive", Namespace: "default"}}))
+
+	f := NewForecaster()
+	f.Observe("default/alive", nil, time.Now(), Sample{})
+	f.Observe("default/deleted", nil, time.Now(), Sample{})
+
+	f.Sweep(corelisters.NewServiceLister(indexer))
+
+	_, ok := f.load("default/alive")
+	require.True(t, ok)
+	_, ok = f.load("default/deleted")
+	require.False(t, ok)
+}
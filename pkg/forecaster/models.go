@@ -0,0 +1,95 @@
+package forecaster
+
+// Model predicts the next values of a time series sampled at a fixed interval.
+type Model interface {
+	// Observe adds the next sample to the time series
+	Observe(value float64)
+	// Forecast returns the value predicted the given number of samples ahead of the
+	// last observed one. It returns false while the history is not long enough.
+	Forecast(steps int) (float64, bool)
+}
+
+// seasonalNaive predicts that the time series repeats the value it had one season before.
+type seasonalNaive struct {
+	// values is a ring buffer with the samples of the last season
+	values []float64
+	count  int
+}
+
+// newSeasonalNaive returns a seasonal naive model with the given season length in samples
+func newSeasonalNaive(season int) *seasonalNaive {
+	return &seasonalNaive{values: make([]float64, season)}
+}
+
+func (m *seasonalNaive) Observe(value float64) {
+	m.values[m.count%len(m.values)] = value
+	m.count++
+}
+
+func (m *seasonalNaive) Forecast(steps int) (float64, bool) {
+	season := len(m.values)
+	if m.count < season {
+		return 0, false
+	}
+	// the sample one season before the forecasted one, going back whole seasons
+	// when the forecast is more than a season ahead
+	index := m.count - 1 + steps - season*((steps+season-1)/season)
+	return m.values[index%season], true
+}
+
+// holtWinters is the additive Holt-Winters model, which smooths the level, the trend and
+// the seasonal component of the time series. The level is initialized with the mean of the
+// first season, the trend with zero and the seasonal component with the deviations from it.
+type holtWinters struct {
+	alpha float64
+	beta  float64
+	gamma float64
+
+	level    float64
+	trend    float64
+	seasonal []float64
+	count    int
+}
+
+// newHoltWinters returns a Holt-Winters model with the given smoothing factors and season length in samples
+func newHoltWinters(alpha, beta, gamma float64, season int) *holtWinters {
+	return &holtWinters{
+		alpha:    alpha,
+		beta:     beta,
+		gamma:    gamma,
+		seasonal: make([]float64, season),
+	}
+}
+
+func (m *holtWinters) Observe(value float64) {
+	season := len(m.seasonal)
+	index := m.count % season
+	m.count++
+
+	// collect the first season to initialize the components
+	if m.count <= season {
+		m.seasonal[index] = value
+		if m.count == season {
+			for _, v := range m.seasonal {
+				m.level += v / float64(season)
+			}
+			for i := range m.seasonal {
+				m.seasonal[i] -= m.level
+			}
+		}
+		return
+	}
+
+	level := m.alpha*(value-m.seasonal[index]) + (1-m.alpha)*(m.level+m.trend)
+	m.trend = m.beta*(level-m.level) + (1-m.beta)*m.trend
+	m.seasonal[index] = m.gamma*(value-level) + (1-m.gamma)*m.seasonal[index]
+	m.level = level
+}
+
+func (m *holtWinters) Forecast(steps int) (float64, bool) {
+	season := len(m.seasonal)
+	if m.count < season {
+		return 0, false
+	}
+	return m.level + float64(steps)*m.trend + m.seasonal[(m.count-1+steps)%season], true
+}
@@ -0,0 +1,71 @@
+package forecaster
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModels(t *testing.T) {
+	season := 4
+	pattern := []float64{10, 20, 30, 20}
+
+	testcases := []struct {
+		description string
+		model       Model
+		// trend is added to the pattern at every sample
+		trend    float64
+		seasons  int
+		steps    int
+		expected float64
+		delta    float64
+	}{
+		{
+			description: "seasonal naive should repeat the last season",
+			model:       newSeasonalNaive(season),
+			seasons:     2,
+			steps:       2,
+			expected:    20,
+		},
+		{
+			description: "seasonal naive should go back whole seasons",
+			model:       newSeasonalNaive(season),
+			seasons:     2,
+			steps:       6,
+			expected:    20,
+		},
+		{
+			description: "holt-winters should repeat a stationary season",
+			model:       newHoltWinters(0.5, 0.1, 0.1, season),
+			seasons:     1,
+			steps:       3,
+			expected:    30,
+		},
+		{
+			description: "holt-winters should follow the trend",
+			model:       newHoltWinters(0.5, 0.5, 0.1, season),
+			trend:       1,
+			seasons:     50,
+			steps:       2,
+			// the sample 201 follows the second value of the pattern
+			expected: 20 + 201,
+			delta:    1,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			_, ok := tt.model.Forecast(tt.steps)
+			require.False(t, ok)
+
+			for i := 0; i < tt.seasons*season; i++ {
+				tt.model.Observe(pattern[i%season] + tt.trend*float64(i))
+			}
+
+			forecast, ok := tt.model.Forecast(tt.steps)
+			require.True(t, ok)
+			require.InDelta(t, tt.expected, forecast, math.Max(tt.delta, 1e-9))
+		})
+	}
+}
@@ -86,6 +86,18 @@ func (c *FakeServiceLevelAgreements) Update(ctx context.Context, serviceLevelAgr
 	return obj.(*v1beta1.ServiceLevelAgreement), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeServiceLevelAgreements) UpdateStatus(ctx context.Context, serviceLevelAgreement *v1beta1.ServiceLevelAgreement, opts v1.UpdateOptions) (*v1beta1.ServiceLevelAgreement, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(servicelevelagreementsResource, "status", c.ns, serviceLevelAgreement), &v1beta1.ServiceLevelAgreement{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1beta1.ServiceLevelAgreement), err
+}
+
 // Delete takes name of the serviceLevelAgreement and deletes it. Returns an error if one occurs.
 func (c *FakeServiceLevelAgreements) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	_, err := c.Fake.
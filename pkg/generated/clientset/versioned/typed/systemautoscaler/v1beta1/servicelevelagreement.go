@@ -24,6 +24,7 @@ type ServiceLevelAgreementsGetter interface {
 type ServiceLevelAgreementInterface interface {
 	Create(ctx context.Context, serviceLevelAgreement *v1beta1.ServiceLevelAgreement, opts v1.CreateOptions) (*v1beta1.ServiceLevelAgreement, error)
 	Update(ctx context.Context, serviceLevelAgreement *v1beta1.ServiceLevelAgreement, opts v1.UpdateOptions) (*v1beta1.ServiceLevelAgreement, error)
+	UpdateStatus(ctx context.Context, serviceLevelAgreement *v1beta1.ServiceLevelAgreement, opts v1.UpdateOptions) (*v1beta1.ServiceLevelAgreement, error)
 	Delete(ctx context.Context, name string, opts v1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, opts v1.DeleteOptions, listOpts v1.ListOptions) error
 	Get(ctx context.Context, name string, opts v1.GetOptions) (*v1beta1.ServiceLevelAgreement, error)
@@ -119,6 +120,22 @@ func (c *serviceLevelAgreements) Update(ctx context.Context, serviceLevelAgreeme
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *serviceLevelAgreements) UpdateStatus(ctx context.Context, serviceLevelAgreement *v1beta1.ServiceLevelAgreement, opts v1.UpdateOptions) (result *v1beta1.ServiceLevelAgreement, err error) {
+	result = &v1beta1.ServiceLevelAgreement{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("servicelevelagreements").
+		Name(serviceLevelAgreement.Name).
+		SubResource("status").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Body(serviceLevelAgreement).
+		Do(ctx).
+		Into(result)
+	return
+}
+
 // Delete takes name of the serviceLevelAgreement and deletes it. Returns an error if one occurs.
 func (c *serviceLevelAgreements) Delete(ctx context.Context, name string, opts v1.DeleteOptions) error {
 	return c.client.Delete().
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"k8s.io/klog/v2"
 	"log"
@@ -17,6 +18,7 @@ import (
 
 var target = &url.URL{}
 var window = &rolling.TimePolicy{}
+var errorWindow = &rolling.TimePolicy{}
 
 // Environment
 var address string
@@ -28,8 +30,12 @@ func main() {
 	mux := http.NewServeMux()
 
 	mux.Handle("/metric/response_time", http.HandlerFunc(ResponseTime))
+	for percentile := range metrics.ResponseTimePercentiles {
+		mux.Handle("/metric/"+percentile.String(), ResponseTimePercentile(percentile))
+	}
 	mux.Handle("/metric/request_count", http.HandlerFunc(RequestCount))
 	mux.Handle("/metric/throughput", http.HandlerFunc(Throughput))
+	mux.Handle("/metric/error_rate", http.HandlerFunc(ErrorRate))
 	mux.Handle("/metrics/", http.HandlerFunc(AllMetrics))
 	mux.Handle("/", http.HandlerFunc(ForwardRequest))
 
@@ -61,6 +67,7 @@ func main() {
 	}
 
 	window = rolling.NewTimePolicy(rolling.NewWindow(int(windowSize.Nanoseconds()/windowGranularity.Nanoseconds())), time.Millisecond)
+	errorWindow = rolling.NewTimePolicy(rolling.NewWindow(int(windowSize.Nanoseconds()/windowGranularity.Nanoseconds())), time.Millisecond)
 	log.Println("Time window initialized with size:", windowSizeString, " and granularity:", windowGranularityString)
 
 	// output error and quit if ListenAndServe fails
@@ -68,13 +75,31 @@ func main() {
 
 }
 
+// statusRecorder keeps track of the status code written to the client
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status code before sending it
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
 // ForwardRequest send all the request the the pod except for the ones having metrics/ in the path
 func ForwardRequest(res http.ResponseWriter, req *http.Request) {
+	recorder := &statusRecorder{ResponseWriter: res, status: http.StatusOK}
 	requestTime := time.Now()
-	httputil.NewSingleHostReverseProxy(target).ServeHTTP(res, req)
+	httputil.NewSingleHostReverseProxy(target).ServeHTTP(recorder, req)
 	responseTime := time.Now()
 	delta := responseTime.Sub(requestTime)
 	window.Append(float64(delta.Milliseconds()))
+	if recorder.status >= http.StatusInternalServerError {
+		errorWindow.Append(1)
+	} else {
+		errorWindow.Append(0)
+	}
 }
 
 // ResponseTime return the pod average response time
@@ -86,6 +111,22 @@ func ResponseTime(res http.ResponseWriter, req *http.Request) {
 	_, _ = fmt.Fprintf(res, `{"%s": %f}`, metrics.ResponseTime.String(), responseTime)
 }
 
+// ResponseTimePercentile returns an handler exposing the given percentile of the pod response time
+func ResponseTimePercentile(percentile metrics.MetricType) http.HandlerFunc {
+	return func(res http.ResponseWriter, req *http.Request) {
+		_, _ = fmt.Fprintf(res, `{"%s": %f}`, percentile.String(), responseTimePercentile(percentile))
+	}
+}
+
+// responseTimePercentile computes the given percentile of the response times in the window
+func responseTimePercentile(percentile metrics.MetricType) float64 {
+	responseTime := window.Reduce(rolling.Percentile(metrics.ResponseTimePercentiles[percentile]))
+	if math.IsNaN(responseTime) {
+		responseTime = 0
+	}
+	return responseTime
+}
+
 // RequestCount return the current number of request sent to the pod
 func RequestCount(res http.ResponseWriter, req *http.Request) {
 	requestCount := window.Reduce(rolling.Count)
@@ -101,6 +142,15 @@ func Throughput(res http.ResponseWriter, req *http.Request) {
 	_, _ = fmt.Fprintf(res, `{"%s": %f}`, metrics.Throughput.String(), throughput)
 }
 
+// ErrorRate returns the ratio of requests answered with a server error
+func ErrorRate(res http.ResponseWriter, req *http.Request) {
+	errorRate := errorWindow.Reduce(rolling.Avg)
+	if math.IsNaN(errorRate) {
+		errorRate = 0
+	}
+	_, _ = fmt.Fprintf(res, `{"%s": %f}`, metrics.ErrorRate.String(), errorRate)
+}
+
 // AllMetrics returns all the metrics available for the pod
 func AllMetrics(res http.ResponseWriter, req *http.Request) {
 
@@ -115,8 +165,30 @@ func AllMetrics(res http.ResponseWriter, req *http.Request) {
 	}
 
 	throughput := window.Reduce(rolling.Count) / windowSize.Seconds()
-	// TODO: maybe we should wrap this into an helper function of metrics struct
 
-	klog.Infof(`{"%s": %f,"%s": %f,"%s": %f}`, metrics.ResponseTime.String(), responseTime, metrics.RequestCount.String(), requestCount, metrics.Throughput.String(), throughput)
-	_, _ = fmt.Fprintf(res, `{"%s": %f,"%s": %f,"%s": %f}`, metrics.ResponseTime.String(), responseTime, metrics.RequestCount.String(), requestCount, metrics.Throughput.String(), throughput)
+	errorRate := errorWindow.Reduce(rolling.Avg)
+	if math.IsNaN(errorRate) {
+		errorRate = 0
+	}
+
+	values := map[string]float64{
+		metrics.ResponseTime.String(): responseTime,
+		metrics.RequestCount.String(): requestCount,
+		metrics.Throughput.String():   throughput,
+		metrics.ErrorRate.String():    errorRate,
+	}
+
+	for percentile := range metrics.ResponseTimePercentiles {
+		values[percentile.String()] = responseTimePercentile(percentile)
+	}
+
+	body, err := json.Marshal(values)
+	if err != nil {
+		klog.Errorf("failed to encode metrics: %s", err)
+		res.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	klog.Info(string(body))
+	_, _ = res.Write(body)
 }
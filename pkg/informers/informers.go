@@ -3,10 +3,15 @@ package informers
 import (
 	sainformers "github.com/lterrac/system-autoscaler/pkg/generated/informers/externalversions/systemautoscaler/v1beta1"
 	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	corev1 "k8s.io/api/core/v1"
 	coreinformers "k8s.io/client-go/informers/core/v1"
 	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
 )
 
+// NodeNameIndex is the name of the index of the pods by the node they are scheduled on
+const NodeNameIndex = "spec.nodeName"
+
 type Informers struct {
 	Pod                   coreinformers.PodInformer
 	Node                  coreinformers.NodeInformer
@@ -18,6 +23,7 @@ type Informers struct {
 func (i *Informers) GetListers() Listers {
 	return Listers{
 		i.Pod.Lister(),
+		NewNodePodLister(i.Pod.Informer().GetIndexer()),
 		i.Node.Lister(),
 		i.Service.Lister(),
 		i.PodScale.Lister(),
@@ -25,10 +31,62 @@ func (i *Informers) GetListers() Listers {
 	}
 }
 
+// IndexPodsByNode adds the node name index to the pod informer, if it is missing.
+// It must be called before starting the informer.
+func (i *Informers) IndexPodsByNode() error {
+	informer := i.Pod.Informer()
+	if _, ok := informer.GetIndexer().GetIndexers()[NodeNameIndex]; ok {
+		return nil
+	}
+
+	return informer.AddIndexers(cache.Indexers{
+		NodeNameIndex: nodeNameIndexFunc,
+	})
+}
+
+func nodeNameIndexFunc(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || pod.Spec.NodeName == "" {
+		return []string{}, nil
+	}
+	return []string{pod.Spec.NodeName}, nil
+}
+
 type Listers struct {
 	corelisters.PodLister
+	NodePodLister
 	corelisters.NodeLister
 	corelisters.ServiceLister
 	salisters.PodScaleLister
 	salisters.ServiceLevelAgreementLister
 }
+
+// NodePodLister lists the pods scheduled on a node from the informer cache
+type NodePodLister interface {
+	// PodsOnNode returns the pods scheduled on the node
+	PodsOnNode(node string) ([]*corev1.Pod, error)
+}
+
+type nodePodLister struct {
+	indexer cache.Indexer
+}
+
+// NewNodePodLister returns a NodePodLister backed by the node name index of the pod informer
+func NewNodePodLister(indexer cache.Indexer) NodePodLister {
+	return &nodePodLister{indexer: indexer}
+}
+
+func (l *nodePodLister) PodsOnNode(node string) ([]*corev1.Pod, error) {
+	objs, err := l.indexer.ByIndex(NodeNameIndex, node)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		if pod, ok := obj.(*corev1.Pod); ok {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
@@ -0,0 +1,67 @@
+package informers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coreinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPodsOnNode(t *testing.T) {
+	factory := coreinformers.NewSharedInformerFactory(fake.NewSimpleClientset(), 0)
+	informers := Informers{Pod: factory.Core().V1().Pods()}
+
+	require.Nil(t, informers.IndexPodsByNode())
+	// adding the index twice must not fail
+	require.Nil(t, informers.IndexPodsByNode())
+
+	indexer := informers.Pod.Informer().GetIndexer()
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "node-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "bar", Namespace: "other"}, Spec: corev1.PodSpec{NodeName: "node-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "baz", Namespace: "default"}, Spec: corev1.PodSpec{NodeName: "node-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending", Namespace: "default"}},
+	}
+	for _, pod := range pods {
+		require.Nil(t, indexer.Add(pod))
+	}
+
+	testcases := []struct {
+		description string
+		node        string
+		expected    []string
+	}{
+		{
+			description: "should list the pods of all the namespaces on the node",
+			node:        "node-1",
+			expected:    []string{"bar", "foo"},
+		},
+		{
+			description: "should not list pods on other nodes",
+			node:        "node-2",
+			expected:    []string{"baz"},
+		},
+		{
+			description: "should not list unscheduled pods",
+			node:        "",
+			expected:    []string{},
+		},
+	}
+
+	lister := NewNodePodLister(indexer)
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			actual, err := lister.PodsOnNode(tt.node)
+			require.Nil(t, err)
+
+			names := make([]string, 0)
+			for _, pod := range actual {
+				names = append(names, pod.Name)
+			}
+			require.ElementsMatch(t, tt.expected, names)
+		})
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/component-base/logs"
 	"k8s.io/klog/v2"
+	resourcemetricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 
 	"github.com/kubernetes-sigs/custom-metrics-apiserver/pkg/apiserver"
 	basecmd "github.com/kubernetes-sigs/custom-metrics-apiserver/pkg/cmd"
@@ -37,7 +38,7 @@ type ResponseTimeMetricsAdapter struct {
 	informers informers2.Informers
 }
 
-func (a *ResponseTimeMetricsAdapter) makeProviderOrDie(informers informers2.Informers, stopCh <-chan struct{}) provider.CustomMetricsProvider {
+func (a *ResponseTimeMetricsAdapter) makeProviderOrDie(resourceMetricsClient resourcemetricsclient.Interface, informers informers2.Informers, stopCh <-chan struct{}) provider.CustomMetricsProvider {
 	client, err := a.DynamicClient()
 	if err != nil {
 		klog.Fatalf("unable to construct dynamic client: %v", err)
@@ -48,7 +49,7 @@ func (a *ResponseTimeMetricsAdapter) makeProviderOrDie(informers informers2.Info
 		klog.Fatalf("unable to construct discovery REST mapper: %v", err)
 	}
 
-	return rtprovider.NewResponseTimeMetricsProvider(client, mapper, informers, stopCh)
+	return rtprovider.NewResponseTimeMetricsProvider(client, mapper, resourceMetricsClient, informers, stopCh)
 }
 
 func main() {
@@ -80,6 +81,11 @@ func main() {
 		klog.Fatalf("Error building example clientset: %s", err.Error())
 	}
 
+	resourceMetricsClient, err := resourcemetricsclient.NewForConfig(cfg)
+	if err != nil {
+		klog.Fatalf("Error building resource metrics clientset: %s", err.Error())
+	}
+
 	saInformerFactory := sainformers.NewSharedInformerFactory(saClient, time.Second*30)
 	coreInformerFactory := coreinformers.NewSharedInformerFactory(kubernetesClient, time.Second*30)
 
@@ -112,7 +118,7 @@ func main() {
 		klog.Fatalf("failed to wait for caches to sync")
 	}
 
-	responseTimeMetricsProvider := cmd.makeProviderOrDie(informers, stopCh)
+	responseTimeMetricsProvider := cmd.makeProviderOrDie(resourceMetricsClient, informers, stopCh)
 	cmd.WithCustomMetrics(responseTimeMetricsProvider)
 
 	if err := cmd.Run(stopCh); err != nil {
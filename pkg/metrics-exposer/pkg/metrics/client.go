@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/klog/v2"
 )
@@ -21,16 +22,49 @@ type Client struct {
 type MetricType string
 
 const (
-	ResponseTime MetricType = "response_time"
-	RequestCount MetricType = "request_count"
-	Throughput   MetricType = "throughput"
-	All          MetricType = ""
+	ResponseTime    MetricType = "response_time"
+	ResponseTimeP90 MetricType = "response_time_p90"
+	ResponseTimeP95 MetricType = "response_time_p95"
+	ResponseTimeP99 MetricType = "response_time_p99"
+	RequestCount    MetricType = "request_count"
+	Throughput      MetricType = "throughput"
+	ErrorRate       MetricType = "error_rate"
+	CPUUtilization  MetricType = "cpu_utilization"
+	All             MetricType = ""
 )
 
+// ResponseTimePercentiles maps each tracked percentile to the metric exposing it.
+var ResponseTimePercentiles = map[MetricType]float64{
+	ResponseTimeP90: 90,
+	ResponseTimeP95: 95,
+	ResponseTimeP99: 99,
+}
+
 func (m MetricType) String() string {
 	return string(m)
 }
 
+// IsResponseTime returns true if the metric is the mean or a percentile of the response time.
+func (m MetricType) IsResponseTime() bool {
+	_, percentile := ResponseTimePercentiles[m]
+	return m == ResponseTime || percentile
+}
+
+// ResponseTimeMetric returns the metric tracking the given statistic of the response time.
+// The mean response time is returned when the percentile is empty or unknown.
+func ResponseTimeMetric(percentile v1beta1.ResponseTimePercentile) MetricType {
+	switch percentile {
+	case v1beta1.P90:
+		return ResponseTimeP90
+	case v1beta1.P95:
+		return ResponseTimeP95
+	case v1beta1.P99:
+		return ResponseTimeP99
+	default:
+		return ResponseTime
+	}
+}
+
 // NewClient returns a new MetricClient representing a metric client.
 func NewClient() *Client {
 	httpClient := http.Client{
@@ -57,6 +91,11 @@ func (c Client) ResponseTime(pod *v1.Pod) (map[string]interface{}, error) {
 	return c.getMetric(pod, ResponseTime)
 }
 
+// ResponseTimePercentile returns the given percentile of the pod response time.
+func (c Client) ResponseTimePercentile(pod *v1.Pod, percentile MetricType) (map[string]interface{}, error) {
+	return c.getMetric(pod, percentile)
+}
+
 // RequestCount returns the average pod request within the current time window.
 func (c Client) RequestCount(pod *v1.Pod) (map[string]interface{}, error) {
 	return c.getMetric(pod, RequestCount)
@@ -67,6 +106,11 @@ func (c Client) Throughput(pod *v1.Pod) (map[string]interface{}, error) {
 	return c.getMetric(pod, Throughput)
 }
 
+// ErrorRate returns the ratio of pod requests answered with a server error.
+func (c Client) ErrorRate(pod *v1.Pod) (map[string]interface{}, error) {
+	return c.getMetric(pod, ErrorRate)
+}
+
 // AllMetrics returns all the metrics available for the pod.
 func (c Client) AllMetrics(pod *v1.Pod) (map[string]interface{}, error) {
 	return c.getMetric(pod, All)
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Requirement is the target set on a single metric by a ServiceLevelAgreement.
+type Requirement struct {
+	Metric MetricType
+	Target resource.Quantity
+	// LowerBound is true when the metric must be kept above the target
+	// instead of below it.
+	LowerBound bool
+}
+
+// Requirements returns all the requirements set in a MetricRequirement.
+// Requirements with an empty or zero target are ignored.
+func Requirements(m v1beta1.MetricRequirement) []Requirement {
+	requirements := make([]Requirement, 0)
+
+	if !m.ResponseTime.IsZero() {
+		requirements = append(requirements, Requirement{Metric: ResponseTimeMetric(m.ResponseTimePercentile), Target: m.ResponseTime})
+	}
+
+	if m.Throughput != nil && !m.Throughput.IsZero() {
+		requirements = append(requirements, Requirement{Metric: Throughput, Target: *m.Throughput, LowerBound: true})
+	}
+
+	if m.ErrorRate != nil && !m.ErrorRate.IsZero() {
+		requirements = append(requirements, Requirement{Metric: ErrorRate, Target: *m.ErrorRate})
+	}
+
+	if m.CPUUtilization != nil && !m.CPUUtilization.IsZero() {
+		requirements = append(requirements, Requirement{Metric: CPUUtilization, Target: *m.CPUUtilization})
+	}
+
+	return requirements
+}
+
+// Observed tells whether the actual value can be compared with the target. Lower bounds like
+// the throughput depend on the load offered to the service, so they are not violated by a
+// service that receives no traffic.
+func (r Requirement) Observed(actual resource.Quantity) bool {
+	return !r.LowerBound || !actual.IsZero()
+}
+
+// Proportional tells whether the metric changes proportionally to the resources and the replicas
+// of the service. The error rate does not, so it is not scaled down while it is below the target.
+func (r Requirement) Proportional() bool {
+	return r.Metric != ErrorRate
+}
+
+// Ratio returns how many times the actual value should be scaled to honor the requirement:
+// actual/target for upper bounds and target/actual for lower bounds.
+// Values greater than 1 mean that the requirement is violated. Requirements
+// that are not observed are honored by definition, as well as the requirements
+// on metrics that are not proportional when their value does not exceed the target.
+func (r Requirement) Ratio(actual resource.Quantity) float64 {
+	if !r.Observed(actual) {
+		return 1
+	}
+
+	target := float64(r.Target.MilliValue())
+	value := float64(actual.MilliValue())
+
+	if !r.LowerBound {
+		if !r.Proportional() && value <= target {
+			return 1
+		}
+		return value / target
+	}
+
+	return target / value
+}
+
+// Violation returns the relative distance between the actual value and the target.
+// Positive values mean that the requirement is violated.
+func (r Requirement) Violation(actual resource.Quantity) float64 {
+	return r.Ratio(actual) - 1
+}
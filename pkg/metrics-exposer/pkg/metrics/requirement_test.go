@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestRequirements(t *testing.T) {
+	testcases := []struct {
+		description string
+		requirement v1beta1.MetricRequirement
+		expected    []MetricType
+	}{
+		{
+			description: "should return only the response time",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			expected: []MetricType{ResponseTime},
+		},
+		{
+			description: "should return the response time percentile",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime:           *resource.NewMilliQuantity(100, resource.BinarySI),
+				ResponseTimePercentile: v1beta1.P95,
+			},
+			expected: []MetricType{ResponseTimeP95},
+		},
+		{
+			description: "should ignore zero targets",
+			requirement: v1beta1.MetricRequirement{
+				Throughput: resource.NewQuantity(0, resource.BinarySI),
+				ErrorRate:  resource.NewMilliQuantity(10, resource.BinarySI),
+			},
+			expected: []MetricType{ErrorRate},
+		},
+		{
+			description: "should return all the requirements",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime:   *resource.NewMilliQuantity(100, resource.BinarySI),
+				Throughput:     resource.NewQuantity(10, resource.BinarySI),
+				ErrorRate:      resource.NewMilliQuantity(10, resource.BinarySI),
+				CPUUtilization: resource.NewMilliQuantity(800, resource.BinarySI),
+			},
+			expected: []MetricType{ResponseTime, Throughput, ErrorRate, CPUUtilization},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			actual := make([]MetricType, 0)
+			for _, r := range Requirements(tt.requirement) {
+				actual = append(actual, r.Metric)
+			}
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestRatio(t *testing.T) {
+	testcases := []struct {
+		description string
+		requirement Requirement
+		actual      resource.Quantity
+		expected    float64
+	}{
+		{
+			description: "upper bound violated",
+			requirement: Requirement{Metric: ResponseTime, Target: *resource.NewMilliQuantity(100, resource.BinarySI)},
+			actual:      *resource.NewMilliQuantity(200, resource.BinarySI),
+			expected:    2,
+		},
+		{
+			description: "upper bound honored",
+			requirement: Requirement{Metric: ResponseTime, Target: *resource.NewMilliQuantity(100, resource.BinarySI)},
+			actual:      *resource.NewMilliQuantity(50, resource.BinarySI),
+			expected:    0.5,
+		},
+		{
+			description: "lower bound violated",
+			requirement: Requirement{Metric: Throughput, Target: *resource.NewQuantity(10, resource.BinarySI), LowerBound: true},
+			actual:      *resource.NewQuantity(5, resource.BinarySI),
+			expected:    2,
+		},
+		{
+			description: "lower bound honored",
+			requirement: Requirement{Metric: Throughput, Target: *resource.NewQuantity(10, resource.BinarySI), LowerBound: true},
+			actual:      *resource.NewQuantity(20, resource.BinarySI),
+			expected:    0.5,
+		},
+		{
+			description: "lower bound with no traffic is not violated",
+			requirement: Requirement{Metric: Throughput, Target: *resource.NewQuantity(10, resource.BinarySI), LowerBound: true},
+			actual:      *resource.NewQuantity(0, resource.BinarySI),
+			expected:    1,
+		},
+		{
+			description: "error rate violated",
+			requirement: Requirement{Metric: ErrorRate, Target: *resource.NewMilliQuantity(100, resource.BinarySI)},
+			actual:      *resource.NewMilliQuantity(300, resource.BinarySI),
+			expected:    3,
+		},
+		{
+			description: "error rate below the target is honored",
+			requirement: Requirement{Metric: ErrorRate, Target: *resource.NewMilliQuantity(100, resource.BinarySI)},
+			actual:      *resource.NewMilliQuantity(50, resource.BinarySI),
+			expected:    1,
+		},
+		{
+			description: "error rate without errors is honored",
+			requirement: Requirement{Metric: ErrorRate, Target: *resource.NewMilliQuantity(100, resource.BinarySI)},
+			actual:      *resource.NewQuantity(0, resource.BinarySI),
+			expected:    1,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.requirement.Ratio(tt.actual))
+			require.Equal(t, tt.expected-1, tt.requirement.Violation(tt.actual))
+		})
+	}
+}
+
+func TestObserved(t *testing.T) {
+	throughput := Requirement{Metric: Throughput, Target: *resource.NewQuantity(10, resource.BinarySI), LowerBound: true}
+	errorRate := Requirement{Metric: ErrorRate, Target: *resource.NewMilliQuantity(100, resource.BinarySI)}
+
+	require.False(t, throughput.Observed(*resource.NewQuantity(0, resource.BinarySI)))
+	require.True(t, throughput.Observed(*resource.NewQuantity(5, resource.BinarySI)))
+	require.True(t, errorRate.Observed(*resource.NewQuantity(0, resource.BinarySI)))
+}
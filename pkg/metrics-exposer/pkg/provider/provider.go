@@ -15,6 +15,7 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/metrics/pkg/apis/custom_metrics"
+	resourcemetricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 
 	"github.com/kubernetes-sigs/custom-metrics-apiserver/pkg/provider"
 	"github.com/kubernetes-sigs/custom-metrics-apiserver/pkg/provider/helpers"
@@ -35,22 +36,24 @@ type metricValue struct {
 
 // responseTimeMetricsProvider is a sample implementation of provider.MetricsProvider which stores a map of fake metrics
 type responseTimeMetricsProvider struct {
-	client       dynamic.Interface
-	mapper       apimeta.RESTMapper
-	metricClient *metrics.Client
-	informers    informers.Informers
-	cacheLock    sync.RWMutex
-	cache        map[CustomMetricResource]metricValue
+	client                dynamic.Interface
+	mapper                apimeta.RESTMapper
+	metricClient          *metrics.Client
+	resourceMetricsClient resourcemetricsclient.Interface
+	informers             informers.Informers
+	cacheLock             sync.RWMutex
+	cache                 map[CustomMetricResource]metricValue
 }
 
 // NewResponseTimeMetricsProvider returns an instance of responseTimeMetricsProvider
-func NewResponseTimeMetricsProvider(client dynamic.Interface, mapper apimeta.RESTMapper, informers informers.Informers, stopCh <-chan struct{}) provider.CustomMetricsProvider {
+func NewResponseTimeMetricsProvider(client dynamic.Interface, mapper apimeta.RESTMapper, resourceMetricsClient resourcemetricsclient.Interface, informers informers.Informers, stopCh <-chan struct{}) provider.CustomMetricsProvider {
 	p := &responseTimeMetricsProvider{
-		client:       client,
-		mapper:       mapper,
-		metricClient: metrics.NewClient(),
-		informers:    informers,
-		cache:        make(map[CustomMetricResource]metricValue),
+		client:                client,
+		mapper:                mapper,
+		metricClient:          metrics.NewClient(),
+		resourceMetricsClient: resourceMetricsClient,
+		informers:             informers,
+		cache:                 make(map[CustomMetricResource]metricValue),
 	}
 
 	go wait.Until(p.updateMetrics, time.Second, stopCh)
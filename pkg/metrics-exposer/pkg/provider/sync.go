@@ -1,23 +1,36 @@
 package provider
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/kubernetes-sigs/custom-metrics-apiserver/pkg/provider"
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
 	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/klog/v2"
+	resourcemetrics "k8s.io/metrics/pkg/apis/metrics/v1beta1"
 )
 
 // Metrics is the wrapper for Kubernetes resource metrics
 type Metrics struct {
+	// ResponseTime is expressed in milliseconds
 	ResponseTime *resource.Quantity
-	RequestCount *resource.Quantity
-	Throughput   *resource.Quantity
+	// ResponseTimePercentiles contains only the percentiles exposed by the pod
+	ResponseTimePercentiles map[metrics.MetricType]*resource.Quantity
+	RequestCount            *resource.Quantity
+	// Throughput is expressed in requests per second. The throughput of a Service
+	// is the sum of the throughput of its Pods.
+	Throughput *resource.Quantity
+	// ErrorRate is the fraction of the requests answered with a server error
+	ErrorRate *resource.Quantity
+	// CPUUtilization is nil when the resource metrics are not available
+	CPUUtilization *resource.Quantity
 }
 
 // updateMetrics updates the map of metrics
@@ -33,6 +46,11 @@ func (p *responseTimeMetricsProvider) updateMetrics() {
 		return
 	}
 
+	usages, err := p.containerUsages()
+	if err != nil {
+		klog.Errorf("failed to retrieve the resource metrics, cpu utilization will not be updated: %s", err)
+	}
+
 	serviceMetricsMap := make(map[string]map[string][]*Metrics)
 
 	for _, podScale := range podScales {
@@ -62,6 +80,13 @@ func (p *responseTimeMetricsProvider) updateMetrics() {
 			continue
 		}
 
+		err = p.updatePodPercentiles(podName, namespace, podMetrics.ResponseTimePercentiles)
+
+		if err != nil {
+			klog.Errorf("error while updating response time percentiles for pod with name %s and namespace %s", podName, namespace)
+			continue
+		}
+
 		err = p.updatePodMetric(podName, namespace, metrics.RequestCount, *podMetrics.RequestCount)
 
 		if err != nil {
@@ -76,6 +101,24 @@ func (p *responseTimeMetricsProvider) updateMetrics() {
 			continue
 		}
 
+		err = p.updatePodMetric(podName, namespace, metrics.ErrorRate, *podMetrics.ErrorRate)
+
+		if err != nil {
+			klog.Errorf("error while updating error rate for pod with name %s and namespace %s", podName, namespace)
+			continue
+		}
+
+		podMetrics.CPUUtilization = cpuUtilization(usages, podScale)
+
+		if podMetrics.CPUUtilization != nil {
+			err = p.updatePodMetric(podName, namespace, metrics.CPUUtilization, *podMetrics.CPUUtilization)
+
+			if err != nil {
+				klog.Errorf("error while updating cpu utilization for pod with name %s and namespace %s", podName, namespace)
+				continue
+			}
+		}
+
 		if _, ok := serviceMetricsMap[namespace]; !ok {
 			serviceMetricsMap[namespace] = make(map[string][]*Metrics)
 		}
@@ -93,42 +136,20 @@ func (p *responseTimeMetricsProvider) updateMetrics() {
 
 	for namespace, nestedMap := range serviceMetricsMap {
 		for name, serviceMetrics := range nestedMap {
-			// Compute average
-			responseTimeSum := 0
-			requestCountSum := 0
-			throughputSum := 0
-
-			for _, metric := range serviceMetrics {
-				requests := metric.RequestCount.Value()
-				responseTimeSum += int(metric.ResponseTime.MilliValue()) * int(requests)
-				throughputSum += int(metric.Throughput.MilliValue()) * int(requests)
-				requestCountSum += int(requests)
-			}
+			metricsValue := aggregateMetrics(serviceMetrics)
 
-			var metricsValue *Metrics
-			if requestCountSum == 0 {
-				metricsValue = &Metrics{
-					ResponseTime: resource.NewQuantity(0, resource.BinarySI),
-					RequestCount: resource.NewQuantity(0, resource.BinarySI),
-					Throughput:   resource.NewQuantity(0, resource.BinarySI),
-				}
-			} else {
-				averageResponseTime := resource.NewMilliQuantity(int64(responseTimeSum/requestCountSum), resource.BinarySI)
-				averageRequestCount := resource.NewQuantity(int64(requestCountSum), resource.BinarySI)
-				averageThroughput := resource.NewMilliQuantity(int64(throughputSum/requestCountSum), resource.BinarySI)
-
-				metricsValue = &Metrics{
-					ResponseTime: averageResponseTime,
-					RequestCount: averageRequestCount,
-					Throughput:   averageThroughput,
-				}
-			}
 			err = p.updateServiceMetric(name, namespace, metrics.ResponseTime, *metricsValue.ResponseTime)
 			if err != nil {
 				klog.Errorf("error while updating response time for service with name %s and namespace %s", name, namespace)
 				continue
 			}
 
+			err = p.updateServicePercentiles(name, namespace, metricsValue.ResponseTimePercentiles)
+			if err != nil {
+				klog.Errorf("error while updating response time percentiles for service with name %s and namespace %s", name, namespace)
+				continue
+			}
+
 			err = p.updateServiceMetric(name, namespace, metrics.RequestCount, *metricsValue.RequestCount)
 			if err != nil {
 				klog.Errorf("error while updating request count for service with name %s and namespace %s", name, namespace)
@@ -141,6 +162,20 @@ func (p *responseTimeMetricsProvider) updateMetrics() {
 				continue
 			}
 
+			err = p.updateServiceMetric(name, namespace, metrics.ErrorRate, *metricsValue.ErrorRate)
+			if err != nil {
+				klog.Errorf("error while updating error rate for service with name %s and namespace %s", name, namespace)
+				continue
+			}
+
+			if metricsValue.CPUUtilization != nil {
+				err = p.updateServiceMetric(name, namespace, metrics.CPUUtilization, *metricsValue.CPUUtilization)
+				if err != nil {
+					klog.Errorf("error while updating cpu utilization for service with name %s and namespace %s", name, namespace)
+					continue
+				}
+			}
+
 		}
 	}
 }
@@ -152,13 +187,115 @@ func (p *responseTimeMetricsProvider) PodMetrics(pod *v1.Pod) (*Metrics, error)
 		return nil, fmt.Errorf("failed to retrieve all metrics for pod with name %s and namespace %s, error: %v", pod.Name, pod.Namespace, err)
 	}
 
+	// sidecars not exposing the error rate are considered error free
+	errorRate, _ := value[metrics.ErrorRate.String()].(float64)
+
+	percentiles := make(map[metrics.MetricType]*resource.Quantity)
+	for percentile := range metrics.ResponseTimePercentiles {
+		if responseTime, ok := value[percentile.String()].(float64); ok {
+			percentiles[percentile] = resource.NewMilliQuantity(int64(responseTime), resource.BinarySI)
+		}
+	}
+
 	return &Metrics{
-		ResponseTime: resource.NewMilliQuantity(int64(value[metrics.ResponseTime.String()].(float64)), resource.BinarySI),
-		RequestCount: resource.NewQuantity(int64(value[metrics.RequestCount.String()].(float64)), resource.BinarySI),
-		Throughput:   resource.NewMilliQuantity(int64(value[metrics.Throughput.String()].(float64)), resource.BinarySI),
+		ResponseTime:            resource.NewMilliQuantity(int64(value[metrics.ResponseTime.String()].(float64)), resource.BinarySI),
+		ResponseTimePercentiles: percentiles,
+		RequestCount:            resource.NewQuantity(int64(value[metrics.RequestCount.String()].(float64)), resource.BinarySI),
+		Throughput:              resource.NewMilliQuantity(int64(value[metrics.Throughput.String()].(float64)*1000), resource.BinarySI),
+		ErrorRate:               resource.NewMilliQuantity(int64(errorRate*1000), resource.BinarySI),
 	}, nil
 }
 
+// aggregateMetrics computes the Service metrics starting from the ones of its Pods.
+// Response time and error rate are averaged by the number of requests served by each Pod,
+// throughput and request count are summed up and cpu utilization is averaged among the Pods
+// exposing it. Percentiles cannot be averaged, so the Service percentile is conservatively
+// approximated with the highest one among the Pods that served requests.
+func aggregateMetrics(podMetrics []*Metrics) *Metrics {
+	var responseTimeSum, errorRateSum, requestCountSum, throughputSum int64
+	var cpuUtilizationSum, cpuUtilizationCount int64
+	percentiles := make(map[metrics.MetricType]*resource.Quantity)
+
+	for _, metric := range podMetrics {
+		requests := metric.RequestCount.Value()
+
+		if requests > 0 {
+			for percentile, value := range metric.ResponseTimePercentiles {
+				if current, ok := percentiles[percentile]; !ok || value.Cmp(*current) > 0 {
+					percentiles[percentile] = value
+				}
+			}
+		}
+
+		responseTimeSum += metric.ResponseTime.MilliValue() * requests
+		errorRateSum += metric.ErrorRate.MilliValue() * requests
+		throughputSum += metric.Throughput.MilliValue()
+		requestCountSum += requests
+
+		if metric.CPUUtilization != nil {
+			cpuUtilizationSum += metric.CPUUtilization.MilliValue()
+			cpuUtilizationCount++
+		}
+	}
+
+	metricsValue := &Metrics{
+		ResponseTime:            resource.NewQuantity(0, resource.BinarySI),
+		ResponseTimePercentiles: percentiles,
+		RequestCount:            resource.NewQuantity(requestCountSum, resource.BinarySI),
+		Throughput:              resource.NewMilliQuantity(throughputSum, resource.BinarySI),
+		ErrorRate:               resource.NewQuantity(0, resource.BinarySI),
+	}
+
+	if requestCountSum != 0 {
+		metricsValue.ResponseTime = resource.NewMilliQuantity(responseTimeSum/requestCountSum, resource.BinarySI)
+		metricsValue.ErrorRate = resource.NewMilliQuantity(errorRateSum/requestCountSum, resource.BinarySI)
+	}
+
+	if cpuUtilizationCount != 0 {
+		metricsValue.CPUUtilization = resource.NewMilliQuantity(cpuUtilizationSum/cpuUtilizationCount, resource.BinarySI)
+	}
+
+	return metricsValue
+}
+
+// containerUsages retrieves the resource usage of all the Pods from the resource metrics API.
+func (p *responseTimeMetricsProvider) containerUsages() (map[types.NamespacedName]*resourcemetrics.PodMetrics, error) {
+	list, err := p.resourceMetricsClient.MetricsV1beta1().PodMetricses(v1.NamespaceAll).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make(map[types.NamespacedName]*resourcemetrics.PodMetrics)
+	for i := range list.Items {
+		item := &list.Items[i]
+		usages[types.NamespacedName{Name: item.Name, Namespace: item.Namespace}] = item
+	}
+
+	return usages, nil
+}
+
+// cpuUtilization returns the ratio between the cpu used by the container tracked by the
+// PodScale and the cpu actually assigned to it. It returns nil if the usage is unknown.
+func cpuUtilization(usages map[types.NamespacedName]*resourcemetrics.PodMetrics, podScale *v1beta1.PodScale) *resource.Quantity {
+	usage, ok := usages[types.NamespacedName{Name: podScale.Spec.Pod, Namespace: podScale.Spec.Namespace}]
+	if !ok {
+		return nil
+	}
+
+	assigned := podScale.Status.ActualResources.Cpu().MilliValue()
+	if assigned <= 0 {
+		return nil
+	}
+
+	for _, container := range usage.Containers {
+		if container.Name == podScale.Spec.Container {
+			return resource.NewMilliQuantity(container.Usage.Cpu().MilliValue()*1000/assigned, resource.BinarySI)
+		}
+	}
+
+	return nil
+}
+
 // setMetrics saves the metrics in the provider cache
 func (p *responseTimeMetricsProvider) setMetrics(metricInfo CustomMetricResource, value metricValue) {
 	p.cacheLock.RLock()
@@ -202,6 +339,26 @@ func (p *responseTimeMetricsProvider) updatePodMetric(pod, namespace string, met
 	return nil
 }
 
+// updatePodPercentiles saves all the response time percentiles of a pod
+func (p *responseTimeMetricsProvider) updatePodPercentiles(pod, namespace string, percentiles map[metrics.MetricType]*resource.Quantity) error {
+	for percentile, value := range percentiles {
+		if err := p.updatePodMetric(pod, namespace, percentile, *value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateServicePercentiles saves all the response time percentiles of a service
+func (p *responseTimeMetricsProvider) updateServicePercentiles(service, namespace string, percentiles map[metrics.MetricType]*resource.Quantity) error {
+	for percentile, value := range percentiles {
+		if err := p.updateServiceMetric(service, namespace, percentile, *value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (p *responseTimeMetricsProvider) updateServiceMetric(service, namespace string, metricType metrics.MetricType, quantity resource.Quantity) error {
 	groupResource := schema.ParseGroupResource("service")
 
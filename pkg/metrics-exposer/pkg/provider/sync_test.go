@@ -0,0 +1,35 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestAggregateMetrics(t *testing.T) {
+	podMetrics := []*Metrics{
+		{
+			ResponseTime: resource.NewMilliQuantity(100, resource.BinarySI),
+			RequestCount: resource.NewQuantity(30, resource.BinarySI),
+			Throughput:   resource.NewMilliQuantity(3000, resource.BinarySI),
+			ErrorRate:    resource.NewMilliQuantity(0, resource.BinarySI),
+		},
+		{
+			ResponseTime: resource.NewMilliQuantity(200, resource.BinarySI),
+			RequestCount: resource.NewQuantity(10, resource.BinarySI),
+			Throughput:   resource.NewMilliQuantity(1000, resource.BinarySI),
+			ErrorRate:    resource.NewMilliQuantity(400, resource.BinarySI),
+		},
+	}
+
+	service := aggregateMetrics(podMetrics)
+
+	// the Service serves the requests of all its Pods
+	require.Equal(t, int64(4000), service.Throughput.MilliValue())
+	require.Equal(t, int64(40), service.RequestCount.Value())
+	// the other metrics are averaged by the requests served by each Pod
+	require.Equal(t, int64(125), service.ResponseTime.MilliValue())
+	require.Equal(t, int64(100), service.ErrorRate.MilliValue())
+	require.Nil(t, service.CPUUtilization)
+}
@@ -30,8 +30,16 @@ import (
 )
 
 var (
-	masterURL  string
-	kubeconfig string
+	masterURL         string
+	kubeconfig        string
+	contentionSolver  string
+	headroom          string
+	minCPUChange      string
+	minMemoryChange   string
+	minChange         string
+	minResizeInterval time.Duration
+	resizeTimeout     time.Duration
+	shadow            bool
 )
 
 func main() {
@@ -77,6 +85,21 @@ func main() {
 		ServiceLevelAgreement: saInformerFactory.Systemautoscaler().V1beta1().ServiceLevelAgreements(),
 	}
 
+	solver, err := cm.NewSolver(cm.SolverPolicy(contentionSolver))
+	if err != nil {
+		klog.Fatalf("Error building contention solver: %s", err.Error())
+	}
+
+	nodeHeadroom, err := cm.ParseHeadroom(headroom)
+	if err != nil {
+		klog.Fatalf("Error parsing headroom: %s", err.Error())
+	}
+
+	resizePolicy, err := resupd.NewResizePolicy(minCPUChange, minMemoryChange, minChange, minResizeInterval, resizeTimeout)
+	if err != nil {
+		klog.Fatalf("Error parsing resize policy: %s", err.Error())
+	}
+
 	//TODO: should be renamed
 	//TODO: we should try without buffer
 	recommenderOut := make(chan types.NodeScales, 10000)
@@ -88,6 +111,7 @@ func main() {
 		client,
 		metricsGetter,
 		informers,
+		shadow,
 		recommenderOut,
 	)
 
@@ -95,6 +119,8 @@ func main() {
 		kubernetesClient,
 		client,
 		informers,
+		solver,
+		nodeHeadroom,
 		recommenderOut,
 		contentionManagerOut,
 	)
@@ -103,6 +129,8 @@ func main() {
 		kubernetesClient,
 		client,
 		informers,
+		resizePolicy,
+		shadow,
 		contentionManagerOut,
 	)
 
@@ -134,4 +162,12 @@ func main() {
 func init() {
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	flag.StringVar(&contentionSolver, "contention-solver", string(cm.Proportional), "The policy used to solve resource contentions on the nodes: proportional, max-min-fairness, priority-weighted or min-violation. It can be overridden on single nodes through the "+cm.SolverLabel+" label.")
+	flag.StringVar(&headroom, "headroom", "0", "The percentage of the node allocatable resources that is never assigned to the pods. It can be overridden on single nodes through the "+cm.HeadroomLabel+" label.")
+	flag.StringVar(&minCPUChange, "min-cpu-change", "0", "The smallest change of the cpu of a pod that is actuated.")
+	flag.StringVar(&minMemoryChange, "min-memory-change", "0", "The smallest change of the memory of a pod that is actuated.")
+	flag.StringVar(&minChange, "min-change", "0", "The smallest change of the resources of a pod that is actuated, as a percentage of the current resources.")
+	flag.DurationVar(&minResizeInterval, "min-resize-interval", 0, "The minimum time between two resizes of the same pod.")
+	flag.DurationVar(&resizeTimeout, "resize-timeout", 5*time.Minute, "The time after which a resize not actuated by the kubelet is rolled back. Zero disables the timeout.")
+	flag.BoolVar(&shadow, "shadow", false, "Record the recommendations in the pod scales without resizing the pods. It can be enabled on single agreements through the shadow field.")
 }
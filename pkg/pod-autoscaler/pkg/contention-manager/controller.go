@@ -35,10 +35,19 @@ type Controller struct {
 	listers informers.Listers
 
 	podScalesSynced cache.InformerSynced
+	podsSynced      cache.InformerSynced
 	nodesSynced     cache.InformerSynced
+	slasSynced      cache.InformerSynced
 
 	recorder record.EventRecorder
 
+	// solver is the default solver used on the nodes without the solver label
+	solver Solver
+
+	// headroom is the default percentage of allocatable resources left unassigned
+	// on the nodes without the headroom label
+	headroom float64
+
 	in  chan types.NodeScales
 	out chan types.NodeScales
 }
@@ -48,6 +57,8 @@ func NewController(
 	kubeClient kubernetes.Interface,
 	podScalesClient clientset.Interface,
 	informers informers.Informers,
+	solver Solver,
+	headroom float64,
 	in chan types.NodeScales,
 	out chan types.NodeScales) *Controller {
 
@@ -60,6 +71,9 @@ func NewController(
 	eventBroadcaster.StartStructuredLogging(0)
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: AgentName})
 
+	// the pods of each node are retrieved through the node name index of the pod informer
+	utilruntime.Must(informers.IndexPodsByNode())
+
 	controller := &Controller{
 		kubeClientset:      kubeClient,
 		podScalesClientset: podScalesClient,
@@ -67,10 +81,15 @@ func NewController(
 		listers: informers.GetListers(),
 
 		podScalesSynced: informers.PodScale.Informer().HasSynced,
+		podsSynced:      informers.Pod.Informer().HasSynced,
 		nodesSynced:     informers.Node.Informer().HasSynced,
+		slasSynced:      informers.ServiceLevelAgreement.Informer().HasSynced,
 
 		recorder: recorder,
 
+		solver:   solver,
+		headroom: headroom,
+
 		in:  in,
 		out: out,
 	}
@@ -91,7 +110,9 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	klog.Info("Waiting for informer caches to sync")
 	if ok := cache.WaitForCacheSync(stopCh,
 		c.podScalesSynced,
-		c.nodesSynced); !ok {
+		c.podsSynced,
+		c.nodesSynced,
+		c.slasSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
@@ -1,47 +1,57 @@
 package contentionmanager
 
 import (
-	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
 	"github.com/lterrac/system-autoscaler/pkg/podscale-controller/pkg/types"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/fields"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/klog/v2"
 )
 
-// TODO wrap it into a structure/interface to enable multiple solver policies
-// solverFn solves resource contentions on the node.
-type solverFn func(desired, totalDesired, totalAvailable int64) int64
-
-// proportional is the default policy to handle resource contentions.
-// It divides the node resources based on the amount of resources requested
-// with respect to the total amount and it adjust them according to
-// the actual node capacity.
-func proportional(desired, totalDesired, totalAvailable int64) int64 {
-	quota := float64(desired) / float64(totalDesired)
-	return int64(quota * float64(totalAvailable))
-}
+// MinMemory is the memory always assigned to a tracked pod during a contention when its
+// service level agreement has no minimum resources, since a zero memory is not a valid resize
+var MinMemory = resource.MustParse("16Mi")
 
 // ContentionManager embeds the contention resolution logic on a given Node.
 type ContentionManager struct {
-	solverFn
+	Solver         Solver
 	CPUCapacity    *resource.Quantity
 	MemoryCapacity *resource.Quantity
 	PodScales      []*v1beta1.PodScale
+	// Weights contains the weight of the pods, keyed by namespace and name. Pods without weight count as 1.
+	Weights map[string]float64
+	// Agreements contains the service level agreements of the podscales, keyed by namespace and name.
+	// They set the priority and the minimum resources of the podscales.
+	Agreements map[string]*v1beta1.ServiceLevelAgreement
 }
 
-// NewContentionManager returns a new ContentionManager instance
-func NewContentionManager(n *corev1.Node, ns types.NodeScales, p []corev1.Pod, solver solverFn) *ContentionManager {
+// NewContentionManager returns a new ContentionManager instance. The resources available to the
+// tracked pods are the node allocatable ones, reduced by the headroom percentage, minus the ones
+// requested by the untracked pods and the overhead of all the pods.
+func NewContentionManager(n *corev1.Node, ns types.NodeScales, p []corev1.Pod, solver Solver, headroom float64) *ContentionManager {
 	// exclude from the computation the resources allocated to pod not tracked by System Autoscaler
 	var err error
 	untrackedCPU := &resource.Quantity{}
 	untrackedMemory := &resource.Quantity{}
+	weights := make(map[string]float64)
 
 	for _, pod := range p {
+		// the overhead of the pod runtime is not part of the container resources
+		untrackedCPU.Add(*pod.Spec.Overhead.Cpu())
+		untrackedMemory.Add(*pod.Spec.Overhead.Memory())
+
+		// the pod priority is used as weight by the priority weighted solver
+		if pod.Spec.Priority != nil && *pod.Spec.Priority > 1 {
+			weights[pod.Namespace+"/"+pod.Name] = priorityWeight(*pod.Spec.Priority)
+		}
+
 		if !ns.Contains(pod.Name, pod.Namespace) {
 			for _, c := range pod.Spec.Containers {
 				untrackedCPU.Add(*c.Resources.Requests.Cpu())
@@ -49,11 +59,11 @@ func NewContentionManager(n *corev1.Node, ns types.NodeScales, p []corev1.Pod, s
 			}
 		}
 
-		// This must never happen. In place resource update could not work properly if requests and
-		// limits do not coincide.
-		// TODO remove this when webhook server is implemented
+		// This must never happen since the admission webhook sets the QOS class of tracked pods, but pods
+		// created while the webhook is not available are still skipped. In place resource update could
+		// not work properly if the QOS class of the pod is not the one of its agreement.
 		// TODO Discuss about QOS behaviour for external pods
-		if ns.Contains(pod.Name, pod.Namespace) && pod.Status.QOSClass != corev1.PodQOSGuaranteed {
+		if ns.Contains(pod.Name, pod.Namespace) && !supportsQOS(&pod, ns) {
 			_, err = ns.Remove(pod.Name, pod.Namespace)
 			if err != nil {
 				utilruntime.HandleError(fmt.Errorf("error while creating the contention manager: %#v", err))
@@ -66,11 +76,11 @@ func NewContentionManager(n *corev1.Node, ns types.NodeScales, p []corev1.Pod, s
 		}
 	}
 
-	allocatableCPU := n.Status.Capacity.Cpu()
+	allocatableCPU := withHeadroom(n.Status.Allocatable.Cpu(), headroom)
 	untrackedCPU.Neg()
 	allocatableCPU.Add(*untrackedCPU)
 
-	allocatableMemory := n.Status.Capacity.Memory()
+	allocatableMemory := withHeadroom(n.Status.Allocatable.Memory(), headroom)
 	untrackedMemory.Neg()
 	allocatableMemory.Add(*untrackedMemory)
 
@@ -80,11 +90,71 @@ func NewContentionManager(n *corev1.Node, ns types.NodeScales, p []corev1.Pod, s
 	}
 
 	return &ContentionManager{
-		solverFn:       solver,
+		Solver:         solver,
 		CPUCapacity:    allocatableCPU,
 		MemoryCapacity: allocatableMemory,
 		PodScales:      ns.PodScales,
+		Weights:        weights,
+	}
+}
+
+// priorityWeight returns the weight of a pod with the given priority. Priorities range up to
+// two billions, so they are compressed on a logarithmic scale: a pod with priority 1000 weighs
+// 4 times a pod without priority, while the system critical ones weigh about 10 times.
+func priorityWeight(priority int32) float64 {
+	if priority <= 1 {
+		return 1
+	}
+	return 1 + math.Log10(float64(priority))
+}
+
+// withoutOvercommit caps the capacity of the node to the resources currently requested by the
+// tracked containers, so that the resources of a pod can only grow if the ones of another pod shrink.
+func (m *ContentionManager) withoutOvercommit(p []corev1.Pod) {
+	requestedCPU := &resource.Quantity{}
+	requestedMemory := &resource.Quantity{}
+
+	for _, podscale := range m.PodScales {
+		for _, pod := range p {
+			if pod.Namespace != podscale.Spec.Namespace || pod.Name != podscale.Spec.Pod {
+				continue
+			}
+			for _, c := range pod.Spec.Containers {
+				if c.Name == podscale.Spec.Container {
+					requestedCPU.Add(*c.Resources.Requests.Cpu())
+					requestedMemory.Add(*c.Resources.Requests.Memory())
+				}
+			}
+		}
+	}
+
+	if requestedCPU.Cmp(*m.CPUCapacity) < 0 {
+		m.CPUCapacity = requestedCPU
+	}
+	if requestedMemory.Cmp(*m.MemoryCapacity) < 0 {
+		m.MemoryCapacity = requestedMemory
+	}
+}
+
+// supportsQOS returns true if the resources of the tracked pod can be updated in place. Guaranteed pods
+// are always supported, while Burstable pods only if the recommender computed separate limits for them.
+func supportsQOS(pod *corev1.Pod, ns types.NodeScales) bool {
+	switch pod.Status.QOSClass {
+	case corev1.PodQOSGuaranteed:
+		return true
+	case corev1.PodQOSBurstable:
+		for _, podscale := range ns.PodScales {
+			if podscale.Spec.Namespace == pod.Namespace && podscale.Spec.Pod == pod.Name {
+				return len(podscale.Status.CappedLimits) > 0
+			}
+		}
 	}
+	return false
+}
+
+// withHeadroom returns the quantity reduced by the headroom percentage
+func withHeadroom(q *resource.Quantity, headroom float64) *resource.Quantity {
+	return resource.NewMilliQuantity(int64(float64(q.MilliValue())*(100-headroom)/100), q.Format)
 }
 
 // Solve resolves the contentions between the podscales
@@ -97,50 +167,120 @@ func (m *ContentionManager) Solve() []*v1beta1.PodScale {
 		desiredMemory.Add(*podscale.Status.CappedResources.Memory())
 	}
 
-	var actualCPU *resource.Quantity
-	var actualMemory *resource.Quantity
-
-	for _, cs := range m.PodScales {
-		if desiredCPU.Cmp(*m.CPUCapacity) == 1 {
-			actualCPU = resource.NewMilliQuantity(
-				m.solverFn(
-					cs.Status.CappedResources.Cpu().MilliValue(),
-					desiredCPU.MilliValue(),
-					m.CPUCapacity.MilliValue(),
-				),
-				resource.BinarySI,
-			)
-		} else {
-			actualCPU = resource.NewMilliQuantity(
-				cs.Status.CappedResources.Cpu().MilliValue(), resource.BinarySI,
-			)
-		}
-
-		if desiredMemory.Cmp(*m.MemoryCapacity) == 1 {
-			actualMemory = resource.NewMilliQuantity(
-				m.solverFn(
-					cs.Status.CappedResources.Memory().MilliValue(),
-					desiredMemory.MilliValue(),
-					m.MemoryCapacity.MilliValue(),
-				),
-				resource.BinarySI,
-			)
-		} else {
-			actualMemory = resource.NewMilliQuantity(
-				cs.Status.CappedResources.Memory().MilliValue(),
-				resource.BinarySI,
-			)
-		}
+	actualCPU := m.solve(corev1.ResourceCPU, desiredCPU, m.CPUCapacity)
+	actualMemory := m.solve(corev1.ResourceMemory, desiredMemory, m.MemoryCapacity)
 
+	for i, cs := range m.PodScales {
 		cs.Status.ActualResources = corev1.ResourceList{
-			corev1.ResourceCPU:    *actualCPU,
-			corev1.ResourceMemory: *actualMemory,
+			corev1.ResourceCPU:    *resource.NewMilliQuantity(actualCPU[i], resource.BinarySI),
+			corev1.ResourceMemory: *resource.NewMilliQuantity(actualMemory[i], resource.BinarySI),
+		}
+		cs.Status.ActualLimits = actualLimits(cs)
+
+		if decision := cs.Status.LastDecision(); decision != nil {
+			decision.Actual = cs.Status.ActualResources.DeepCopy()
+			decision.CapReason = contentionReason(decision.CapReason, cs.Status.CappedResources, cs.Status.ActualResources)
 		}
 	}
 
 	return m.PodScales
 }
 
+// contentionReason adds to the reason of a decision the resources reduced by the contention on the node
+func contentionReason(reason string, capped, actual corev1.ResourceList) string {
+	reasons := make([]string, 0)
+	if reason != "" {
+		reasons = append(reasons, reason)
+	}
+	for _, name := range []corev1.ResourceName{corev1.ResourceCPU, corev1.ResourceMemory} {
+		c, ok := capped[name]
+		a, found := actual[name]
+		if ok && found && a.Cmp(c) < 0 {
+			reasons = append(reasons, fmt.Sprintf("%s reduced by node contention", name))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// actualLimits returns the limits of Burstable pods. Contentions are solved on the requests only,
+// so the recommended limits are kept, but they are raised if lower than the actual requests.
+func actualLimits(podscale *v1beta1.PodScale) corev1.ResourceList {
+	if len(podscale.Status.CappedLimits) == 0 {
+		return nil
+	}
+
+	limits := make(corev1.ResourceList)
+	for name, limit := range podscale.Status.CappedLimits {
+		if request, ok := podscale.Status.ActualResources[name]; ok && limit.Cmp(request) < 0 {
+			limit = request
+		}
+		limits[name] = limit.DeepCopy()
+	}
+	return limits
+}
+
+// solve returns the amount of the resource assigned to each podscale. The solver
+// is used only if the total desired amount exceeds the node capacity.
+func (m *ContentionManager) solve(name corev1.ResourceName, desired *resource.Quantity, capacity *resource.Quantity) []int64 {
+	requests := make([]Request, len(m.PodScales))
+	for i, podscale := range m.PodScales {
+		capped := podscale.Status.CappedResources[name]
+		requests[i] = Request{
+			Desired: capped.MilliValue(),
+			Weight:  m.weight(podscale),
+			Error:   controlError(podscale),
+		}
+		sla, ok := m.Agreements[podscale.Spec.Namespace+"/"+podscale.Spec.SLA]
+		if ok {
+			requests[i].Priority = sla.Spec.Priority
+			if min, ok := sla.Spec.MinResources[name]; ok {
+				requests[i].Min = min.MilliValue()
+			}
+		}
+		requests[i].Floor = floor(name, sla)
+	}
+
+	if desired.Cmp(*capacity) != 1 {
+		actual := make([]int64, len(requests))
+		for i, r := range requests {
+			actual[i] = r.Desired
+		}
+		return actual
+	}
+
+	return solveByPriority(m.Solver, requests, capacity.MilliValue())
+}
+
+// floor returns the amount of the resource always assigned to the pods of the agreement,
+// which is the minimum CPU of the feedback controllers or MinMemory
+func floor(name corev1.ResourceName, sla *v1beta1.ServiceLevelAgreement) int64 {
+	if name == corev1.ResourceMemory {
+		return MinMemory.MilliValue()
+	}
+	var parameters *v1beta1.ControllerParameters
+	if sla != nil {
+		parameters = sla.Spec.ControllerParameters
+	}
+	return parameters.WithDefaults().MinCPU.MilliValue()
+}
+
+// weight returns the weight of the pod tracked by the podscale
+func (m *ContentionManager) weight(podscale *v1beta1.PodScale) float64 {
+	if w, ok := m.Weights[podscale.Spec.Namespace+"/"+podscale.Spec.Pod]; ok {
+		return w
+	}
+	return 1
+}
+
+// controlError returns the last control error computed by the recommender for the podscale
+func controlError(podscale *v1beta1.PodScale) float64 {
+	state := podscale.Status.ControllerState
+	if state == nil || state.PrevError == nil {
+		return 0
+	}
+	return float64(state.PrevError.MilliValue()) / 1000
+}
+
 // processNextNode adjust the resources of all the pods scheduled on a node
 // according to the actual capacity. Resources not tracked by System Autoscaler
 // are not considered.
@@ -154,19 +294,28 @@ func (c *Controller) processNextNode(podscalesInfos <-chan types.NodeScales) boo
 			return true
 		}
 
-		//TODO: maybe there is a label attached to the Pod. If so, it would be better to use it
-		pods, err := c.kubeClientset.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-			FieldSelector: fields.SelectorFromSet(map[string]string{
-				"spec.nodeName": node.Name,
-			}).String(),
-		})
+		nodePods, err := c.listers.PodsOnNode(node.Name)
 
 		if err != nil {
 			utilruntime.HandleError(fmt.Errorf("error while getting node pods: %#v", err))
 			return true
 		}
 
-		cm := NewContentionManager(node, podscalesInfo, pods.Items, proportional)
+		pods := make([]corev1.Pod, len(nodePods))
+		for i, pod := range nodePods {
+			pods[i] = *pod
+		}
+
+		cm := NewContentionManager(node, podscalesInfo, pods, c.solverFor(node), c.headroomFor(node))
+		if cm == nil {
+			return true
+		}
+		cm.Agreements = c.agreements(podscalesInfo)
+
+		if resizeInfeasible(node, time.Now()) {
+			klog.Info("Node ", node.Name, " recently failed a resize, it is not overcommitted")
+			cm.withoutOvercommit(pods)
+		}
 
 		nodeScale := cm.Solve()
 
@@ -177,3 +326,74 @@ func (c *Controller) processNextNode(podscalesInfos <-chan types.NodeScales) boo
 
 	return true
 }
+
+// agreements returns the service level agreements of the podscales on the node
+func (c *Controller) agreements(nodeScales types.NodeScales) map[string]*v1beta1.ServiceLevelAgreement {
+	agreements := make(map[string]*v1beta1.ServiceLevelAgreement)
+	for _, podscale := range nodeScales.PodScales {
+		sla, err := c.listers.ServiceLevelAgreements(podscale.Spec.Namespace).Get(podscale.Spec.SLA)
+		if err != nil {
+			utilruntime.HandleError(fmt.Errorf("error while getting the service level agreement of podscale %s/%s: %#v", podscale.Namespace, podscale.Name, err))
+			continue
+		}
+		agreements[podscale.Spec.Namespace+"/"+podscale.Spec.SLA] = sla
+	}
+	return agreements
+}
+
+// resizeInfeasible tells whether the node failed to actuate a resize within the cooldown
+func resizeInfeasible(node *corev1.Node, now time.Time) bool {
+	value, ok := node.Annotations[ResizeInfeasibleAnnotation]
+	if !ok {
+		return false
+	}
+
+	mark, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid resize infeasible annotation on node %s: %s", node.Name, err))
+		return false
+	}
+	return now.Sub(mark) < ResizeInfeasibleCooldown
+}
+
+// solverFor returns the solver of the node, which can be overridden through the solver label
+func (c *Controller) solverFor(node *corev1.Node) Solver {
+	policy, ok := node.Labels[SolverLabel]
+	if !ok {
+		return c.solver
+	}
+
+	solver, err := NewSolver(SolverPolicy(policy))
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid solver label on node %s, using the default solver: %s", node.Name, err))
+		return c.solver
+	}
+	return solver
+}
+
+// headroomFor returns the headroom percentage of the node, which can be overridden through the headroom label
+func (c *Controller) headroomFor(node *corev1.Node) float64 {
+	value, ok := node.Labels[HeadroomLabel]
+	if !ok {
+		return c.headroom
+	}
+
+	headroom, err := ParseHeadroom(value)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid headroom label on node %s, using the default headroom: %s", node.Name, err))
+		return c.headroom
+	}
+	return headroom
+}
+
+// ParseHeadroom parses a headroom percentage, which must be in the range [0, 100)
+func ParseHeadroom(value string) (float64, error) {
+	headroom, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, err
+	}
+	if headroom < 0 || headroom >= 100 {
+		return 0, fmt.Errorf("the headroom percentage %s is not in the range [0, 100)", value)
+	}
+	return headroom, nil
+}
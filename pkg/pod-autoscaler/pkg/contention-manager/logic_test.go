@@ -1,7 +1,9 @@
 package contentionmanager
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
 	"github.com/lterrac/system-autoscaler/pkg/podscale-controller/pkg/types"
@@ -65,7 +67,8 @@ func TestNewContentionManager(t *testing.T) {
 		nodeScale   types.NodeScales
 		node        *corev1.Node
 		pods        []corev1.Pod
-		solver      solverFn
+		solver      Solver
+		headroom    float64
 		asserts     func(*testing.T, *ContentionManager, types.NodeScales, *corev1.Node, []corev1.Pod)
 	}{
 		{
@@ -79,16 +82,16 @@ func TestNewContentionManager(t *testing.T) {
 					Name: nodeName,
 				},
 				Status: corev1.NodeStatus{
-					Capacity: corev1.ResourceList{
+					Allocatable: corev1.ResourceList{
 						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
 						corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
 					},
 				},
 			},
-			solver: proportional,
+			solver: proportionalSolver{},
 			asserts: func(t *testing.T, cm *ContentionManager, ns types.NodeScales, n *corev1.Node, p []corev1.Pod) {
-				require.Equal(t, n.Status.Capacity.Cpu().MilliValue(), cm.CPUCapacity.MilliValue())
-				require.Equal(t, n.Status.Capacity.Memory().MilliValue(), cm.MemoryCapacity.MilliValue())
+				require.Equal(t, n.Status.Allocatable.Cpu().MilliValue(), cm.CPUCapacity.MilliValue())
+				require.Equal(t, n.Status.Allocatable.Memory().MilliValue(), cm.MemoryCapacity.MilliValue())
 			},
 		},
 		{
@@ -130,7 +133,7 @@ func TestNewContentionManager(t *testing.T) {
 					Name: nodeName,
 				},
 				Status: corev1.NodeStatus{
-					Capacity: corev1.ResourceList{
+					Allocatable: corev1.ResourceList{
 						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
 						corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
 					},
@@ -180,7 +183,7 @@ func TestNewContentionManager(t *testing.T) {
 					Name: nodeName,
 				},
 				Status: corev1.NodeStatus{
-					Capacity: corev1.ResourceList{
+					Allocatable: corev1.ResourceList{
 						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
 						corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
 					},
@@ -260,7 +263,7 @@ func TestNewContentionManager(t *testing.T) {
 					Name: nodeName,
 				},
 				Status: corev1.NodeStatus{
-					Capacity: corev1.ResourceList{
+					Allocatable: corev1.ResourceList{
 						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
 						corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
 					},
@@ -271,12 +274,86 @@ func TestNewContentionManager(t *testing.T) {
 				require.Equal(t, resource.NewScaledQuantity(50, resource.Mega).MilliValue(), cm.MemoryCapacity.MilliValue())
 			},
 		},
+		{
+			description: "should use the allocatable resources instead of the capacity",
+			nodeScale: types.NodeScales{
+				Node: nodeName,
+			},
+			pods: []corev1.Pod{},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: nodeName,
+				},
+				Status: corev1.NodeStatus{
+					Capacity: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
+					},
+					Allocatable: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(80, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(80, resource.Mega),
+					},
+				},
+			},
+			asserts: func(t *testing.T, cm *ContentionManager, ns types.NodeScales, n *corev1.Node, p []corev1.Pod) {
+				require.Equal(t, resource.NewScaledQuantity(80, resource.Milli).MilliValue(), cm.CPUCapacity.MilliValue())
+				require.Equal(t, resource.NewScaledQuantity(80, resource.Mega).MilliValue(), cm.MemoryCapacity.MilliValue())
+			},
+		},
+		{
+			description: "should leave the headroom and the pod overhead unassigned",
+			nodeScale: types.NodeScales{
+				Node: nodeName,
+				PodScales: []*v1beta1.PodScale{
+					{
+						Spec: v1beta1.PodScaleSpec{
+							Namespace: firstNamespace,
+							Pod:       firstName,
+						},
+					},
+				},
+			},
+			pods: []corev1.Pod{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      firstName,
+						Namespace: firstNamespace,
+					},
+					Spec: corev1.PodSpec{
+						Overhead: corev1.ResourceList{
+							corev1.ResourceCPU:    *resource.NewScaledQuantity(10, resource.Milli),
+							corev1.ResourceMemory: *resource.NewScaledQuantity(10, resource.Mega),
+						},
+						NodeName: nodeName,
+					},
+					Status: corev1.PodStatus{
+						QOSClass: corev1.PodQOSGuaranteed,
+					},
+				},
+			},
+			node: &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: nodeName,
+				},
+				Status: corev1.NodeStatus{
+					Allocatable: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
+					},
+				},
+			},
+			headroom: 20,
+			asserts: func(t *testing.T, cm *ContentionManager, ns types.NodeScales, n *corev1.Node, p []corev1.Pod) {
+				require.Equal(t, resource.NewScaledQuantity(70, resource.Milli).MilliValue(), cm.CPUCapacity.MilliValue())
+				require.Equal(t, resource.NewScaledQuantity(70, resource.Mega).MilliValue(), cm.MemoryCapacity.MilliValue())
+			},
+		},
 	}
 
 	for _, tt := range testcases {
 		t.Run(tt.description, func(t *testing.T) {
 
-			cm := NewContentionManager(tt.node, tt.nodeScale, tt.pods, tt.solver)
+			cm := NewContentionManager(tt.node, tt.nodeScale, tt.pods, tt.solver, tt.headroom)
 			tt.asserts(t, cm, tt.nodeScale, tt.node, tt.pods)
 		})
 	}
@@ -293,7 +370,7 @@ func TestSolve(t *testing.T) {
 		{
 			description: "should get the desired capped resources",
 			ContentionManager: ContentionManager{
-				solverFn:       proportional,
+				Solver:         proportionalSolver{},
 				CPUCapacity:    resource.NewScaledQuantity(100, resource.Milli),
 				MemoryCapacity: resource.NewScaledQuantity(100, resource.Mega),
 				PodScales: []*v1beta1.PodScale{
@@ -355,7 +432,7 @@ func TestSolve(t *testing.T) {
 		{
 			description: "should get the half of desired capped resources",
 			ContentionManager: ContentionManager{
-				solverFn:       proportional,
+				Solver:         proportionalSolver{},
 				CPUCapacity:    resource.NewScaledQuantity(100, resource.Milli),
 				MemoryCapacity: resource.NewScaledQuantity(100, resource.Mega),
 				PodScales: []*v1beta1.PodScale{
@@ -478,3 +555,329 @@ func TestSolve(t *testing.T) {
 		})
 	}
 }
+
+func TestPriorityWeight(t *testing.T) {
+	testcases := []struct {
+		description string
+		priority    int32
+		expected    float64
+	}{
+		{
+			description: "should not weight pods without priority",
+			priority:    0,
+			expected:    1,
+		},
+		{
+			description: "should weight pods on a logarithmic scale",
+			priority:    1000,
+			expected:    4,
+		},
+		{
+			description: "should limit the weight of system critical pods",
+			priority:    2000000000,
+			expected:    1 + math.Log10(2000000000),
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			require.InDelta(t, tt.expected, priorityWeight(tt.priority), 1e-9)
+		})
+	}
+}
+
+func TestFloor(t *testing.T) {
+	tuned := &v1beta1.ServiceLevelAgreement{
+		Spec: v1beta1.ServiceLevelAgreementSpec{
+			ControllerParameters: &v1beta1.ControllerParameters{MinCPU: resource.NewMilliQuantity(20, resource.DecimalSI)},
+		},
+	}
+
+	testcases := []struct {
+		description string
+		name        corev1.ResourceName
+		sla         *v1beta1.ServiceLevelAgreement
+		expected    int64
+	}{
+		{
+			description: "should use the default minimum CPU without agreement",
+			name:        corev1.ResourceCPU,
+			expected:    5,
+		},
+		{
+			description: "should use the minimum CPU of the agreement",
+			name:        corev1.ResourceCPU,
+			sla:         tuned,
+			expected:    20,
+		},
+		{
+			description: "should use the minimum memory",
+			name:        corev1.ResourceMemory,
+			sla:         tuned,
+			expected:    MinMemory.MilliValue(),
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			require.Equal(t, tt.expected, floor(tt.name, tt.sla))
+		})
+	}
+}
+
+func TestHeadroomFor(t *testing.T) {
+	c := &Controller{headroom: 10}
+
+	testcases := []struct {
+		description string
+		labels      map[string]string
+		expected    float64
+	}{
+		{
+			description: "should use the default headroom without label",
+			labels:      nil,
+			expected:    10,
+		},
+		{
+			description: "should use the headroom set by the label",
+			labels:      map[string]string{HeadroomLabel: "25.5"},
+			expected:    25.5,
+		},
+		{
+			description: "should use the default headroom with an out of range label",
+			labels:      map[string]string{HeadroomLabel: "100"},
+			expected:    10,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: tt.labels}}
+			require.Equal(t, tt.expected, c.headroomFor(node))
+		})
+	}
+}
+
+func TestBurstableLimits(t *testing.T) {
+	cm := ContentionManager{
+		Solver:         proportionalSolver{},
+		CPUCapacity:    resource.NewScaledQuantity(100, resource.Milli),
+		MemoryCapacity: resource.NewScaledQuantity(100, resource.Mega),
+		PodScales: []*v1beta1.PodScale{
+			{
+				Status: v1beta1.PodScaleStatus{
+					CappedResources: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(50, resource.Mega),
+					},
+					CappedLimits: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(200, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(40, resource.Mega),
+					},
+				},
+			},
+			{
+				Status: v1beta1.PodScaleStatus{
+					CappedResources: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(50, resource.Mega),
+					},
+				},
+			},
+		},
+	}
+
+	podscales := cm.Solve()
+
+	// contentions are solved on the requests only
+	require.Equal(t, int64(50), podscales[0].Status.ActualResources.Cpu().MilliValue())
+	require.Equal(t, int64(200), podscales[0].Status.ActualLimits.Cpu().MilliValue())
+	// the limits are never lower than the requests
+	require.Equal(t, 0, podscales[0].Status.ActualLimits.Memory().Cmp(*resource.NewScaledQuantity(50, resource.Mega)))
+	require.Nil(t, podscales[1].Status.ActualLimits)
+}
+
+func TestSupportsQOS(t *testing.T) {
+	ns := types.NodeScales{
+		PodScales: []*v1beta1.PodScale{
+			{
+				Spec: v1beta1.PodScaleSpec{Namespace: "default", Pod: "burstable"},
+				Status: v1beta1.PodScaleStatus{
+					CappedLimits: corev1.ResourceList{corev1.ResourceCPU: *resource.NewScaledQuantity(200, resource.Milli)},
+				},
+			},
+			{
+				Spec: v1beta1.PodScaleSpec{Namespace: "default", Pod: "guaranteed"},
+			},
+		},
+	}
+
+	testcases := []struct {
+		description string
+		name        string
+		qos         corev1.PodQOSClass
+		expected    bool
+	}{
+		{
+			description: "should support guaranteed pods",
+			name:        "guaranteed",
+			qos:         corev1.PodQOSGuaranteed,
+			expected:    true,
+		},
+		{
+			description: "should support burstable pods with separate limits",
+			name:        "burstable",
+			qos:         corev1.PodQOSBurstable,
+			expected:    true,
+		},
+		{
+			description: "should not support burstable pods without separate limits",
+			name:        "guaranteed",
+			qos:         corev1.PodQOSBurstable,
+			expected:    false,
+		},
+		{
+			description: "should not support best effort pods",
+			name:        "burstable",
+			qos:         corev1.PodQOSBestEffort,
+			expected:    false,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: tt.name, Namespace: "default"},
+				Status:     corev1.PodStatus{QOSClass: tt.qos},
+			}
+			require.Equal(t, tt.expected, supportsQOS(pod, ns))
+		})
+	}
+}
+
+func TestResizeInfeasible(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		description string
+		annotations map[string]string
+		expected    bool
+	}{
+		{
+			description: "should overcommit a node without annotation",
+			annotations: nil,
+			expected:    false,
+		},
+		{
+			description: "should not overcommit a node within the cooldown",
+			annotations: map[string]string{ResizeInfeasibleAnnotation: now.Add(-time.Minute).Format(time.RFC3339)},
+			expected:    true,
+		},
+		{
+			description: "should overcommit a node after the cooldown",
+			annotations: map[string]string{ResizeInfeasibleAnnotation: now.Add(-ResizeInfeasibleCooldown).Format(time.RFC3339)},
+			expected:    false,
+		},
+		{
+			description: "should ignore an invalid annotation",
+			annotations: map[string]string{ResizeInfeasibleAnnotation: "yesterday"},
+			expected:    false,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: tt.annotations}}
+			require.Equal(t, tt.expected, resizeInfeasible(node, now))
+		})
+	}
+}
+
+func TestWithoutOvercommit(t *testing.T) {
+	newPod := func(name string, cpu int64) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name: "container",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{
+								corev1.ResourceCPU:    *resource.NewScaledQuantity(cpu, resource.Milli),
+								corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
+							},
+						},
+					},
+					{
+						Name: "sidecar",
+						Resources: corev1.ResourceRequirements{
+							Requests: corev1.ResourceList{corev1.ResourceCPU: *resource.NewScaledQuantity(cpu, resource.Milli)},
+						},
+					},
+				},
+			},
+		}
+	}
+	newPodScale := func(name string, cpu int64) *v1beta1.PodScale {
+		return &v1beta1.PodScale{
+			Spec: v1beta1.PodScaleSpec{Namespace: "default", Pod: name, Container: "container"},
+			Status: v1beta1.PodScaleStatus{
+				CappedResources: corev1.ResourceList{
+					corev1.ResourceCPU:    *resource.NewScaledQuantity(cpu, resource.Milli),
+					corev1.ResourceMemory: *resource.NewScaledQuantity(100, resource.Mega),
+				},
+			},
+		}
+	}
+
+	cm := ContentionManager{
+		Solver:         proportionalSolver{},
+		CPUCapacity:    resource.NewScaledQuantity(1000, resource.Milli),
+		MemoryCapacity: resource.NewScaledQuantity(150, resource.Mega),
+		PodScales:      []*v1beta1.PodScale{newPodScale("foo", 300), newPodScale("bar", 300)},
+	}
+
+	cm.withoutOvercommit([]corev1.Pod{newPod("foo", 100), newPod("bar", 200), newPod("untracked", 500)})
+
+	// only the scaled containers of the tracked pods are considered, the capacity never grows
+	require.Equal(t, int64(300), cm.CPUCapacity.MilliValue())
+	require.Equal(t, 0, cm.MemoryCapacity.Cmp(*resource.NewScaledQuantity(150, resource.Mega)))
+
+	podscales := cm.Solve()
+	require.Equal(t, int64(150), podscales[0].Status.ActualResources.Cpu().MilliValue())
+	require.Equal(t, int64(150), podscales[1].Status.ActualResources.Cpu().MilliValue())
+}
+
+func TestSolveDecision(t *testing.T) {
+	cm := ContentionManager{
+		Solver:         proportionalSolver{},
+		CPUCapacity:    resource.NewScaledQuantity(100, resource.Milli),
+		MemoryCapacity: resource.NewScaledQuantity(100, resource.Mega),
+		PodScales: []*v1beta1.PodScale{
+			{
+				Status: v1beta1.PodScaleStatus{
+					CappedResources: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(50, resource.Mega),
+					},
+					Decisions: []v1beta1.Decision{{CapReason: "memory raised to minResources"}},
+				},
+			},
+			{
+				Status: v1beta1.PodScaleStatus{
+					CappedResources: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewScaledQuantity(100, resource.Milli),
+						corev1.ResourceMemory: *resource.NewScaledQuantity(50, resource.Mega),
+					},
+				},
+			},
+		},
+	}
+
+	podscales := cm.Solve()
+
+	decision := podscales[0].Status.LastDecision()
+	require.Equal(t, podscales[0].Status.ActualResources, decision.Actual)
+	require.Equal(t, "memory raised to minResources, cpu reduced by node contention", decision.CapReason)
+	require.Nil(t, podscales[1].Status.LastDecision())
+}
@@ -0,0 +1,305 @@
+package contentionmanager
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// SolverLabel is the node label used to override the contention solver policy on a single node
+	SolverLabel = "systemautoscaler.polimi.it/contention-solver"
+	// HeadroomLabel is the node label used to override the percentage of allocatable resources
+	// left unassigned on a node, usually set on all the nodes of a node pool
+	HeadroomLabel = "systemautoscaler.polimi.it/headroom"
+	// ResizeInfeasibleAnnotation is the node annotation set by the resource updater with the time
+	// of the last resize that the node could not actuate
+	ResizeInfeasibleAnnotation = "systemautoscaler.polimi.it/resize-infeasible"
+	// ResizeInfeasibleCooldown is the time after an infeasible resize during which the node is not overcommitted
+	ResizeInfeasibleCooldown = 10 * time.Minute
+)
+
+// SolverPolicy is the name of a policy used to solve resource contentions
+type SolverPolicy string
+
+const (
+	// Proportional divides the node resources proportionally to the desired ones
+	Proportional SolverPolicy = "proportional"
+	// MaxMinFairness divides the node resources equally, never assigning more than the desired ones
+	MaxMinFairness SolverPolicy = "max-min-fairness"
+	// PriorityWeighted divides the node resources proportionally to the desired ones weighted by the pod priority
+	PriorityWeighted SolverPolicy = "priority-weighted"
+	// MinViolation satisfies first the pods violating their service level agreement, the most violated first
+	MinViolation SolverPolicy = "min-violation"
+)
+
+// Request is the amount of a resource requested by a pod scale during a contention
+type Request struct {
+	// Desired is the amount of resource desired by the pod, in milli units
+	Desired int64
+	// Weight is the importance of the pod, it is always positive
+	Weight float64
+	// Error is the control error of the service level agreement of the pod.
+	// Positive values mean that the pod needs more resources.
+	Error float64
+	// Priority is the priority of the service level agreement of the pod
+	Priority int32
+	// Min is the minimum amount of resource to assign to the pod, in milli units
+	Min int64
+	// Floor is the amount of resource always assigned to the pod, in milli units,
+	// even when the minimum resources of all the pods exceed the available ones
+	Floor int64
+}
+
+// Solver solves resource contentions on the node.
+type Solver interface {
+	// Solve divides the available amount of a resource among the requests,
+	// whose total exceeds it. The result is aligned to the requests.
+	Solve(requests []Request, available int64) []int64
+}
+
+// NewSolver returns the solver implementing the given policy
+func NewSolver(policy SolverPolicy) (Solver, error) {
+	switch policy {
+	case Proportional:
+		return proportionalSolver{}, nil
+	case MaxMinFairness:
+		return maxMinFairnessSolver{}, nil
+	case PriorityWeighted:
+		return priorityWeightedSolver{}, nil
+	case MinViolation:
+		return minViolationSolver{}, nil
+	default:
+		return nil, fmt.Errorf("unknown contention solver policy %q", policy)
+	}
+}
+
+// solveByPriority divides the available resources among the requests by priority tiers.
+// Every request is first assigned its minimum resources, or its floor when it has no
+// minimum, then the tiers are satisfied starting from the highest priority. The first
+// tier that cannot be fully satisfied divides the remaining resources with the solver,
+// while the lower ones keep their minimum. If the minimum resources exceed the available
+// ones, they are ignored and the available resources are divided with solveOverFloors.
+func solveByPriority(solver Solver, requests []Request, available int64) []int64 {
+	actual := make([]int64, len(requests))
+
+	var minimums int64
+	for i, r := range requests {
+		actual[i] = minInt64(maxInt64(r.Min, r.Floor), r.Desired)
+		minimums += actual[i]
+	}
+
+	if minimums > available {
+		return solveOverFloors(solver, requests, available)
+	}
+	available -= minimums
+
+	tiers := make(map[int32][]int)
+	priorities := make([]int32, 0)
+	for i, r := range requests {
+		if _, ok := tiers[r.Priority]; !ok {
+			priorities = append(priorities, r.Priority)
+		}
+		tiers[r.Priority] = append(tiers[r.Priority], i)
+	}
+	sort.Slice(priorities, func(a, b int) bool { return priorities[a] > priorities[b] })
+
+	for _, priority := range priorities {
+		tier := tiers[priority]
+
+		var desired int64
+		extra := make([]Request, len(tier))
+		for j, i := range tier {
+			extra[j] = requests[i]
+			extra[j].Desired = requests[i].Desired - actual[i]
+			desired += extra[j].Desired
+		}
+
+		if desired <= available {
+			for j, i := range tier {
+				actual[i] += extra[j].Desired
+			}
+			available -= desired
+			continue
+		}
+
+		for j, share := range solver.Solve(extra, available) {
+			actual[tier[j]] += share
+		}
+		break
+	}
+
+	return actual
+}
+
+// solveOverFloors divides the available resources without considering priorities.
+// Every request is first assigned its floor, then the solver divides the remaining
+// resources among the requests. If the floors exceed the available resources, they
+// are scaled down proportionally so that the node is never overcommitted.
+func solveOverFloors(solver Solver, requests []Request, available int64) []int64 {
+	actual := make([]int64, len(requests))
+	extra := make([]Request, len(requests))
+
+	var floors int64
+	for i, r := range requests {
+		actual[i] = minInt64(r.Floor, r.Desired)
+		floors += actual[i]
+		extra[i] = r
+		extra[i].Desired = r.Desired - actual[i]
+	}
+
+	if floors > available {
+		for i := range actual {
+			actual[i] = proportional(actual[i], floors, available)
+		}
+		return actual
+	}
+
+	for i, share := range solver.Solve(extra, available-floors) {
+		actual[i] += share
+	}
+	return actual
+}
+
+// proportional is the default policy to handle resource contentions.
+// It divides the node resources based on the amount of resources requested
+// with respect to the total amount and it adjust them according to
+// the actual node capacity.
+func proportional(desired, totalDesired, totalAvailable int64) int64 {
+	quota := float64(desired) / float64(totalDesired)
+	return int64(quota * float64(totalAvailable))
+}
+
+// proportionalSolver solves contentions with the proportional policy
+type proportionalSolver struct{}
+
+func (proportionalSolver) Solve(requests []Request, available int64) []int64 {
+	var totalDesired int64
+	for _, r := range requests {
+		totalDesired += r.Desired
+	}
+
+	actual := make([]int64, len(requests))
+	if totalDesired == 0 {
+		return actual
+	}
+	for i, r := range requests {
+		actual[i] = proportional(r.Desired, totalDesired, available)
+	}
+	return actual
+}
+
+// maxMinFairnessSolver maximizes the resources assigned to the smallest requests.
+// Requests smaller than the fair share are fully satisfied and what they leave
+// is divided among the bigger ones.
+type maxMinFairnessSolver struct{}
+
+func (maxMinFairnessSolver) Solve(requests []Request, available int64) []int64 {
+	return waterFill(requests, available, func(r Request) float64 { return 1 })
+}
+
+// priorityWeightedSolver divides the resources proportionally to the desired ones
+// multiplied by the weight of the request. Requests are never assigned more than
+// the desired resources, what they leave is divided among the others.
+type priorityWeightedSolver struct{}
+
+func (priorityWeightedSolver) Solve(requests []Request, available int64) []int64 {
+	return waterFill(requests, available, func(r Request) float64 { return float64(r.Desired) * r.Weight })
+}
+
+// minViolationSolver minimizes the expected violations of the service level agreements.
+// The pods violating their agreement are satisfied first, giving priority to the most
+// violated ones, while the remaining resources are divided proportionally among the
+// pods that are meeting it and can tolerate a reduction.
+type minViolationSolver struct{}
+
+func (minViolationSolver) Solve(requests []Request, available int64) []int64 {
+	actual := make([]int64, len(requests))
+
+	var violating, meeting []int
+	for i, r := range requests {
+		if r.Error > 0 {
+			violating = append(violating, i)
+		} else {
+			meeting = append(meeting, i)
+		}
+	}
+
+	sort.SliceStable(violating, func(a, b int) bool {
+		return requests[violating[a]].Error > requests[violating[b]].Error
+	})
+
+	for _, i := range violating {
+		actual[i] = minInt64(requests[i].Desired, available)
+		available -= actual[i]
+	}
+
+	meetingRequests := make([]Request, len(meeting))
+	for j, i := range meeting {
+		meetingRequests[j] = requests[i]
+	}
+	for j, share := range (proportionalSolver{}).Solve(meetingRequests, available) {
+		actual[meeting[j]] = minInt64(share, requests[meeting[j]].Desired)
+	}
+
+	return actual
+}
+
+// waterFill divides the available resources proportionally to the given share
+// function without exceeding the desired resources. The requests satisfied with
+// less than their share release the rest to the others.
+func waterFill(requests []Request, available int64, share func(Request) float64) []int64 {
+	actual := make([]int64, len(requests))
+
+	pending := make([]int, 0, len(requests))
+	for i, r := range requests {
+		if r.Desired > 0 && share(r) > 0 {
+			pending = append(pending, i)
+		}
+	}
+
+	remaining := float64(available)
+	for len(pending) > 0 {
+		var totalShare float64
+		for _, i := range pending {
+			totalShare += share(requests[i])
+		}
+
+		assigned := 0.0
+		unsatisfied := make([]int, 0, len(pending))
+		for _, i := range pending {
+			if float64(requests[i].Desired) <= remaining*share(requests[i])/totalShare {
+				actual[i] = requests[i].Desired
+				assigned += float64(requests[i].Desired)
+			} else {
+				unsatisfied = append(unsatisfied, i)
+			}
+		}
+
+		// no request can be satisfied, divide the remaining resources
+		if len(unsatisfied) == len(pending) {
+			for _, i := range pending {
+				actual[i] = int64(remaining * share(requests[i]) / totalShare)
+			}
+			break
+		}
+		remaining -= assigned
+		pending = unsatisfied
+	}
+
+	return actual
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,236 @@
+package contentionmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// sum returns the total resources assigned by a solver
+func sum(actual []int64) int64 {
+	var total int64
+	for _, a := range actual {
+		total += a
+	}
+	return total
+}
+
+func TestSolvers(t *testing.T) {
+
+	testcases := []struct {
+		description string
+		policy      SolverPolicy
+		requests    []Request
+		available   int64
+		expected    []int64
+	}{
+		{
+			description: "proportional should divide the resources based on the desired ones",
+			policy:      Proportional,
+			requests:    []Request{{Desired: 100}, {Desired: 300}},
+			available:   200,
+			expected:    []int64{50, 150},
+		},
+		{
+			description: "max-min fairness should fully satisfy the smallest requests",
+			policy:      MaxMinFairness,
+			requests:    []Request{{Desired: 50}, {Desired: 300}, {Desired: 400}},
+			available:   450,
+			expected:    []int64{50, 200, 200},
+		},
+		{
+			description: "max-min fairness should divide equally when no request can be satisfied",
+			policy:      MaxMinFairness,
+			requests:    []Request{{Desired: 300}, {Desired: 400}},
+			available:   200,
+			expected:    []int64{100, 100},
+		},
+		{
+			description: "priority weighted should favour the heaviest requests",
+			policy:      PriorityWeighted,
+			requests:    []Request{{Desired: 100, Weight: 3}, {Desired: 100, Weight: 1}},
+			available:   100,
+			expected:    []int64{75, 25},
+		},
+		{
+			description: "priority weighted should not exceed the desired resources",
+			policy:      PriorityWeighted,
+			requests:    []Request{{Desired: 100, Weight: 10}, {Desired: 200, Weight: 1}},
+			available:   200,
+			expected:    []int64{100, 100},
+		},
+		{
+			description: "min violation should satisfy the most violated requests first",
+			policy:      MinViolation,
+			requests:    []Request{{Desired: 100, Error: 0.5}, {Desired: 100, Error: 2}, {Desired: 100, Error: 1}},
+			available:   250,
+			expected:    []int64{50, 100, 100},
+		},
+		{
+			description: "min violation should squeeze the requests meeting the agreement",
+			policy:      MinViolation,
+			requests:    []Request{{Desired: 100, Error: 1}, {Desired: 100, Error: -1}, {Desired: 300, Error: 0}},
+			available:   300,
+			expected:    []int64{100, 50, 150},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			solver, err := NewSolver(tt.policy)
+			require.Nil(t, err)
+
+			actual := solver.Solve(tt.requests, tt.available)
+			require.Equal(t, tt.expected, actual)
+
+			var total int64
+			for _, a := range actual {
+				total += a
+			}
+			require.LessOrEqual(t, total, tt.available)
+		})
+	}
+}
+
+func TestNewSolver(t *testing.T) {
+	_, err := NewSolver("unknown")
+	require.Error(t, err)
+}
+
+func TestSolverFor(t *testing.T) {
+	c := &Controller{solver: proportionalSolver{}}
+
+	testcases := []struct {
+		description string
+		labels      map[string]string
+		expected    Solver
+	}{
+		{
+			description: "should use the default solver without label",
+			labels:      nil,
+			expected:    proportionalSolver{},
+		},
+		{
+			description: "should use the solver set by the label",
+			labels:      map[string]string{SolverLabel: string(MaxMinFairness)},
+			expected:    maxMinFairnessSolver{},
+		},
+		{
+			description: "should use the default solver with an invalid label",
+			labels:      map[string]string{SolverLabel: "unknown"},
+			expected:    proportionalSolver{},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Labels: tt.labels}}
+			require.Equal(t, tt.expected, c.solverFor(node))
+		})
+	}
+}
+
+func TestSolveByPriority(t *testing.T) {
+
+	testcases := []struct {
+		description string
+		requests    []Request
+		available   int64
+		expected    []int64
+	}{
+		{
+			description: "should use the solver when all the requests have the same priority",
+			requests:    []Request{{Desired: 100}, {Desired: 300}},
+			available:   200,
+			expected:    []int64{50, 150},
+		},
+		{
+			description: "should satisfy the requests with higher priority first",
+			requests:    []Request{{Desired: 100, Priority: 0}, {Desired: 300, Priority: 10}},
+			available:   350,
+			expected:    []int64{50, 300},
+		},
+		{
+			description: "should not squeeze the lower tiers below their minimum",
+			requests:    []Request{{Desired: 100, Priority: 0, Min: 80}, {Desired: 300, Priority: 10}},
+			available:   350,
+			expected:    []int64{80, 270},
+		},
+		{
+			description: "should divide the contended tier with the solver",
+			requests: []Request{
+				{Desired: 100, Priority: 10, Min: 50},
+				{Desired: 200, Priority: 5},
+				{Desired: 200, Priority: 5},
+				{Desired: 100, Priority: 0, Min: 50},
+			},
+			available: 350,
+			expected:  []int64{100, 100, 100, 50},
+		},
+		{
+			description: "should ignore priorities when the minimum resources exceed the available ones",
+			requests:    []Request{{Desired: 100, Priority: 0, Min: 100}, {Desired: 300, Priority: 10, Min: 300}},
+			available:   200,
+			expected:    []int64{50, 150},
+		},
+		{
+			description: "should not squeeze the lower tiers without minimum below their floor",
+			requests:    []Request{{Desired: 100, Priority: 0, Floor: 5}, {Desired: 300, Priority: 10, Floor: 5}},
+			available:   300,
+			expected:    []int64{5, 295},
+		},
+		{
+			description: "should assign the floor when the minimum resources exceed the available ones",
+			requests:    []Request{{Desired: 100, Min: 100, Floor: 5}, {Desired: 10, Min: 10, Floor: 5}},
+			available:   50,
+			expected:    []int64{43, 7},
+		},
+		{
+			description: "should scale down the floors when they exceed the available resources",
+			requests:    []Request{{Desired: 100, Min: 100, Floor: 50}, {Desired: 100, Min: 100, Floor: 30}},
+			available:   40,
+			expected:    []int64{25, 15},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			actual := solveByPriority(proportionalSolver{}, tt.requests, tt.available)
+			require.Equal(t, tt.expected, actual)
+			require.LessOrEqual(t, sum(actual), tt.available)
+		})
+	}
+}
+
+func TestSolveByPriorityMinViolation(t *testing.T) {
+
+	testcases := []struct {
+		description string
+		requests    []Request
+		available   int64
+		expected    []int64
+	}{
+		{
+			description: "should not starve the pods meeting their agreement",
+			requests:    []Request{{Desired: 300, Error: 1, Floor: 5}, {Desired: 100, Floor: 5}},
+			available:   300,
+			expected:    []int64{295, 5},
+		},
+		{
+			description: "should assign the floor when the violating pods exceed the available resources",
+			requests:    []Request{{Desired: 300, Error: 1, Min: 300, Floor: 5}, {Desired: 100, Min: 100, Floor: 5}},
+			available:   300,
+			expected:    []int64{295, 5},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			actual := solveByPriority(minViolationSolver{}, tt.requests, tt.available)
+			require.Equal(t, tt.expected, actual)
+			require.LessOrEqual(t, sum(actual), tt.available)
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package e2e_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -103,6 +104,7 @@ var _ = BeforeSuite(func(done Done) {
 		saClient,
 		metricClient,
 		informers,
+		false,
 		recommenderOut,
 	)
 
@@ -111,6 +113,8 @@ var _ = BeforeSuite(func(done Done) {
 		kubeClient,
 		saClient,
 		informers,
+		resupd.ResizePolicy{},
+		false,
 		contentionManagerOut,
 	)
 
@@ -265,3 +269,13 @@ func newPodScale(sla *sa.ServiceLevelAgreement, pod *corev1.Pod, selectorLabels
 		},
 	}
 }
+
+// createPodScale creates the pod scale along with its status, which is ignored on creation because of the status subresource
+func createPodScale(ctx context.Context, podScale *sa.PodScale) (*sa.PodScale, error) {
+	created, err := saClient.SystemautoscalerV1beta1().PodScales(podScale.Namespace).Create(ctx, podScale, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	created.Status = podScale.Status
+	return saClient.SystemautoscalerV1beta1().PodScales(podScale.Namespace).UpdateStatus(ctx, created, metav1.UpdateOptions{})
+}
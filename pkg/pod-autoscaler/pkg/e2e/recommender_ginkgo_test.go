@@ -46,7 +46,7 @@ var _ = Describe("Recommender controller", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			podScale := newPodScale(sla, pod, labels)
-			podScale, err = saClient.SystemautoscalerV1beta1().PodScales(namespace).Create(ctx, podScale, metav1.CreateOptions{})
+			podScale, err = createPodScale(ctx, podScale)
 			Expect(err).ShouldNot(HaveOccurred())
 
 			Eventually(func() bool {
@@ -107,7 +107,7 @@ var _ = Describe("Recommender controller", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			podScale1 := newPodScale(sla, pod1, labels)
-			podScale1, err = saClient.SystemautoscalerV1beta1().PodScales(namespace).Create(ctx, podScale1, metav1.CreateOptions{})
+			podScale1, err = createPodScale(ctx, podScale1)
 			Expect(err).ShouldNot(HaveOccurred())
 
 			Eventually(func() bool {
@@ -132,7 +132,7 @@ var _ = Describe("Recommender controller", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			podScale2 := newPodScale(sla, pod2, labels)
-			podScale2, err = saClient.SystemautoscalerV1beta1().PodScales(namespace).Create(ctx, podScale2, metav1.CreateOptions{})
+			podScale2, err = createPodScale(ctx, podScale2)
 			Expect(err).ShouldNot(HaveOccurred())
 
 			err = saClient.SystemautoscalerV1beta1().PodScales(namespace).Delete(ctx, podScale1.Name, metav1.DeleteOptions{})
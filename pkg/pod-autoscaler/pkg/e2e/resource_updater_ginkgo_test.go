@@ -50,7 +50,7 @@ var _ = Describe("Resource Updater controller", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			podScale := newPodScale(sla, pod, labels)
-			podScale, err = saClient.SystemautoscalerV1beta1().PodScales(namespace).Create(ctx, podScale, metav1.CreateOptions{})
+			podScale, err = createPodScale(ctx, podScale)
 			Expect(err).ShouldNot(HaveOccurred())
 
 			updatedPodScale := podScale.DeepCopy()
@@ -1,13 +1,18 @@
 package metricsgetter
 
 import (
+	"context"
+	"fmt"
+
 	"github.com/kubernetes-sigs/custom-metrics-apiserver/pkg/dynamicmapper"
 	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/rest"
 	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+	resourcemetricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
 	metricsclient "k8s.io/metrics/pkg/client/custom_metrics"
 )
 
@@ -15,17 +20,20 @@ import (
 type MetricGetter interface {
 	PodMetrics(p *corev1.Pod, metricType metrics.MetricType) (*metricsv1beta2.MetricValue, error)
 	ServiceMetrics(s *corev1.Service, metricType metrics.MetricType) (*metricsv1beta2.MetricValue, error)
+	ContainerWorkingSet(p *corev1.Pod, container string) (*resource.Quantity, error)
 }
 
 // DefaultGetter is the standard implementation of MetriGetter
 type DefaultGetter struct {
-	client metricsclient.CustomMetricsClient
+	client         metricsclient.CustomMetricsClient
+	resourceClient resourcemetricsclient.Interface
 }
 
 // NewDefaultGetter creates a new DefaultGetter
 func NewDefaultGetter(cfg *rest.Config, m *dynamicmapper.RegeneratingDiscoveryRESTMapper, ag metricsclient.AvailableAPIsGetter) *DefaultGetter {
 	return &DefaultGetter{
-		client: metricsclient.NewForConfig(cfg, m, ag),
+		client:         metricsclient.NewForConfig(cfg, m, ag),
+		resourceClient: resourcemetricsclient.NewForConfigOrDie(cfg),
 	}
 }
 
@@ -39,9 +47,28 @@ func (d *DefaultGetter) ServiceMetrics(s *corev1.Service, metricType metrics.Met
 	return d.client.NamespacedMetrics(s.Namespace).GetForObject(corev1.SchemeGroupVersion.WithKind("Service").GroupKind(), s.Name, metricType.String(), labels.Everything())
 }
 
+// ContainerWorkingSet retrieves the memory working set of a Pod container from the resource metrics api
+func (d *DefaultGetter) ContainerWorkingSet(p *corev1.Pod, container string) (*resource.Quantity, error) {
+	podMetrics, err := d.resourceClient.MetricsV1beta1().PodMetricses(p.Namespace).Get(context.TODO(), p.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range podMetrics.Containers {
+		if c.Name == container {
+			return c.Usage.Memory(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("no resource metrics found for container %s of pod %s/%s", container, p.Namespace, p.Name)
+}
+
 // FakeGetter is used to mock the custom metrics api, especially during e2e tests
 type FakeGetter struct {
 	ResponseTime int64
+	// WorkingSet is the memory working set of the containers in bytes.
+	// Zero means that the resource metrics are not available.
+	WorkingSet int64
 }
 
 // GetMetrics always return a MetricValue of 5
@@ -57,3 +84,11 @@ func (d *FakeGetter) ServiceMetrics(s *corev1.Service, metricType metrics.Metric
 		Value: *resource.NewQuantity(d.ResponseTime, resource.BinarySI),
 	}, nil
 }
+
+// ContainerWorkingSet always returns the configured working set
+func (d *FakeGetter) ContainerWorkingSet(p *corev1.Pod, container string) (*resource.Quantity, error) {
+	if d.WorkingSet == 0 {
+		return nil, fmt.Errorf("no resource metrics found for container %s of pod %s/%s", container, p.Namespace, p.Name)
+	}
+	return resource.NewQuantity(d.WorkingSet, resource.BinarySI), nil
+}
@@ -0,0 +1,126 @@
+package resourceupdater
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// fieldManager is the manager of the fields written by the resource updater through server-side apply
+const fieldManager = "system-autoscaler-" + controllerAgentName
+
+// objectReference identifies the object of an apply configuration
+type objectReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// podApplyConfiguration contains the only fields of a pod owned by the resource updater,
+// which are the resources of the scaled container
+type podApplyConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        objectReference `json:"metadata"`
+	Spec            struct {
+		Containers []containerApplyConfiguration `json:"containers"`
+	} `json:"spec"`
+}
+
+type containerApplyConfiguration struct {
+	Name      string                      `json:"name"`
+	Resources corev1.ResourceRequirements `json:"resources"`
+}
+
+// podScaleApplyConfiguration contains the only fields of a pod scale owned by the resource updater,
+// which are the desired resources in the spec and the whole status, written through the status
+// subresource. The fields identifying the pod are left to the podscale controller.
+type podScaleApplyConfiguration struct {
+	metav1.TypeMeta `json:",inline"`
+	Metadata        objectReference            `json:"metadata"`
+	Spec            *podScaleSpecConfiguration `json:"spec,omitempty"`
+	Status          *v1beta1.PodScaleStatus    `json:"status,omitempty"`
+}
+
+type podScaleSpecConfiguration struct {
+	DesiredResources corev1.ResourceList `json:"desired,omitempty"`
+}
+
+// podApply returns the apply configuration of the resources of a container of the pod
+func podApply(pod *corev1.Pod, container string) ([]byte, error) {
+	configuration := podApplyConfiguration{
+		TypeMeta: metav1.TypeMeta{APIVersion: corev1.SchemeGroupVersion.String(), Kind: "Pod"},
+		Metadata: objectReference{Name: pod.Name, Namespace: pod.Namespace},
+	}
+	configuration.Spec.Containers = []containerApplyConfiguration{{
+		Name:      container,
+		Resources: containerResources(pod, container),
+	}}
+	return json.Marshal(configuration)
+}
+
+// podScaleApply returns the apply configuration of the spec of the pod scale
+func podScaleApply(podScale *v1beta1.PodScale) ([]byte, error) {
+	configuration := newPodScaleApplyConfiguration(podScale)
+	configuration.Spec = &podScaleSpecConfiguration{DesiredResources: podScale.Spec.DesiredResources}
+	return json.Marshal(configuration)
+}
+
+// podScaleStatusApply returns the apply configuration of the status of the pod scale
+func podScaleStatusApply(podScale *v1beta1.PodScale) ([]byte, error) {
+	configuration := newPodScaleApplyConfiguration(podScale)
+	configuration.Status = &podScale.Status
+	return json.Marshal(configuration)
+}
+
+func newPodScaleApplyConfiguration(podScale *v1beta1.PodScale) podScaleApplyConfiguration {
+	return podScaleApplyConfiguration{
+		TypeMeta: metav1.TypeMeta{APIVersion: v1beta1.SchemeGroupVersion.String(), Kind: "PodScale"},
+		Metadata: objectReference{Name: podScale.Name, Namespace: podScale.Namespace},
+	}
+}
+
+// applyOptions returns the options of the apply patches. The conflicts are forced since the
+// resource updater is the only writer of the applied fields.
+func applyOptions(dryRun bool) metav1.PatchOptions {
+	force := true
+	opts := metav1.PatchOptions{
+		FieldManager: fieldManager,
+		Force:        &force,
+	}
+	if dryRun {
+		opts.DryRun = []string{metav1.DryRunAll}
+	}
+	return opts
+}
+
+// applyPod applies the resources of a container of the pod through the pod spec
+func (c *Controller) applyPod(pod *corev1.Pod, container string, opts metav1.PatchOptions) (*corev1.Pod, error) {
+	data, err := podApply(pod, container)
+	if err != nil {
+		return nil, err
+	}
+	return c.kubernetesClientset.CoreV1().Pods(pod.Namespace).Patch(context.TODO(), pod.Name, types.ApplyPatchType, data, opts)
+}
+
+// applyPodScale applies the desired resources and then the status of the pod scale
+func (c *Controller) applyPodScale(podScale *v1beta1.PodScale, opts metav1.PatchOptions) (*v1beta1.PodScale, error) {
+	data, err := podScaleApply(podScale)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = c.podScalesClientset.SystemautoscalerV1beta1().PodScales(podScale.Namespace).Patch(context.TODO(), podScale.Name, types.ApplyPatchType, data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = podScaleStatusApply(podScale)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.podScalesClientset.SystemautoscalerV1beta1().PodScales(podScale.Namespace).Patch(context.TODO(), podScale.Name, types.ApplyPatchType, data, opts, "status")
+}
@@ -0,0 +1,84 @@
+package resourceupdater
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPodApply(t *testing.T) {
+	resources := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", ResourceVersion: "42", Labels: map[string]string{"app": "foo"}},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{
+				{Name: "sidecar", Image: "sidecar"},
+				{Name: "container", Image: "app", Resources: resources},
+			},
+		},
+	}
+
+	data, err := podApply(pod, "container")
+	require.Nil(t, err)
+
+	// only the resources of the scaled container are applied, without any resource version
+	require.JSONEq(t, `{
+		"apiVersion": "v1",
+		"kind": "Pod",
+		"metadata": {"name": "pod", "namespace": "default"},
+		"spec": {"containers": [{"name": "container", "resources": {"requests": {"cpu": "500m"}, "limits": {"cpu": "500m"}}}]}
+	}`, string(data))
+}
+
+func TestPodScaleApply(t *testing.T) {
+	podScale := &v1beta1.PodScale{
+		ObjectMeta: metav1.ObjectMeta{Name: "podscale", Namespace: "default", ResourceVersion: "42"},
+		Spec: v1beta1.PodScaleSpec{
+			Namespace:        "default",
+			Pod:              "pod",
+			DesiredResources: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		},
+		Status: v1beta1.PodScaleStatus{
+			ActualResources: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			ResizeStatus:    v1beta1.ResizeInProgress,
+		},
+	}
+
+	data, err := podScaleApply(podScale)
+	require.Nil(t, err)
+
+	applied := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(data, &applied))
+	require.Equal(t, "systemautoscaler.polimi.it/v1beta1", applied["apiVersion"])
+	require.Equal(t, "PodScale", applied["kind"])
+	require.Equal(t, map[string]interface{}{"name": "podscale", "namespace": "default"}, applied["metadata"])
+
+	// the fields identifying the pod are owned by the podscale controller
+	require.Equal(t, map[string]interface{}{"desired": map[string]interface{}{"cpu": "1"}}, applied["spec"])
+	require.NotContains(t, applied, "status")
+
+	data, err = podScaleStatusApply(podScale)
+	require.Nil(t, err)
+
+	applied = map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(data, &applied))
+	require.NotContains(t, applied, "spec")
+	require.Equal(t, map[string]interface{}{
+		"actual":       map[string]interface{}{"cpu": "500m"},
+		"resizeStatus": "InProgress",
+	}, applied["status"])
+
+	opts := applyOptions(true)
+	require.Equal(t, fieldManager, opts.FieldManager)
+	require.True(t, *opts.Force)
+	require.Equal(t, []string{metav1.DryRunAll}, opts.DryRun)
+	require.Nil(t, applyOptions(false).DryRun)
+}
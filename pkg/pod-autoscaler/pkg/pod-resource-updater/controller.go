@@ -1,7 +1,6 @@
 package resourceupdater
 
 import (
-	"context"
 	"fmt"
 	"log"
 
@@ -14,19 +13,43 @@ import (
 	podscalesclientset "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned"
 	samplescheme "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned/scheme"
 	"github.com/lterrac/system-autoscaler/pkg/podscale-controller/pkg/types"
+	"github.com/lterrac/system-autoscaler/pkg/queue"
+	"github.com/modern-go/concurrent"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 )
 
-const controllerAgentName = "pod-resource-updater"
+const (
+	controllerAgentName = "pod-resource-updater"
+
+	// ResizeFailed is used as part of the event 'reason' fired when the new resources
+	// of a pod cannot be actuated
+	ResizeFailed = "ResizeFailed"
+
+	// maxRetries is the number of times the update of a pod scale is retried before being dropped
+	maxRetries = 5
+
+	// ResizeRolledBack is used as part of the event 'reason' fired when a resize that the kubelet
+	// could not actuate is reverted
+	ResizeRolledBack = "ResizeRolledBack"
+
+	// ResizeSkipped is used as part of the event 'reason' fired when the new resources
+	// of a pod are not actuated because of the resize policy
+	ResizeSkipped = "ResizeSkipped"
+
+	// resizeSweepPeriod is the period between two evictions of the resizes of deleted pod scales
+	resizeSweepPeriod = time.Minute
+)
 
 // Controller is the controller that recommends resources to the pods.
 // For each Pod Scale assigned to the recommender, it will have a pod saved in a list.
@@ -44,6 +67,7 @@ type Controller struct {
 
 	podScalesSynced cache.InformerSynced
 	podSynced       cache.InformerSynced
+	slaSynced       cache.InformerSynced
 
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
@@ -51,6 +75,26 @@ type Controller struct {
 
 	log *logger.Logger
 
+	// policy defines which changes of the resources are actuated
+	policy ResizePolicy
+
+	// shadow disables the resize of all the pods, whose pod scales are still updated
+	shadow bool
+
+	// resizeSupported tells whether the pods are resized through the resize subresource
+	resizeSupported bool
+
+	// resizes contains the resizes not yet actuated by the kubelet.
+	// Key: namespace/name of the pod scale, Value: pending resize
+	resizes concurrent.Map
+
+	// workqueue contains the keys of the pod scales to update
+	workqueue queue.Queue
+
+	// pending contains the pod scales waiting to be updated.
+	// Key: namespace/name of the pod scale, Value: pod scale
+	pending concurrent.Map
+
 	// in is the input channel.
 	in chan types.NodeScales
 }
@@ -59,6 +103,8 @@ type Controller struct {
 func NewController(kubernetesClientset *kubernetes.Clientset,
 	podScalesClientset podscalesclientset.Interface,
 	informers informers.Informers,
+	policy ResizePolicy,
+	shadow bool,
 	in chan types.NodeScales) *Controller {
 
 	// Create event broadcaster
@@ -68,6 +114,9 @@ func NewController(kubernetesClientset *kubernetes.Clientset,
 	klog.V(4).Info("Creating event broadcaster")
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartStructuredLogging(0)
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: kubernetesClientset.CoreV1().Events(corev1.NamespaceAll),
+	})
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
 
 	fileLogger, err := logger.NewFileLogger("/var/podscale.json")
@@ -85,7 +134,13 @@ func NewController(kubernetesClientset *kubernetes.Clientset,
 		listers:             informers.GetListers(),
 		podScalesSynced:     informers.PodScale.Informer().HasSynced,
 		podSynced:           informers.Pod.Informer().HasSynced,
+		slaSynced:           informers.ServiceLevelAgreement.Informer().HasSynced,
 		log:                 fileLogger,
+		policy:              policy,
+		shadow:              shadow,
+		workqueue:           queue.NewRetryingQueue("PodScaleQueue", maxRetries),
+		pending:             *concurrent.NewMap(),
+		resizes:             *concurrent.NewMap(),
 		in:                  in,
 	}
 
@@ -105,15 +160,22 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	klog.Info("Waiting for informer caches to sync")
 	if ok := cache.WaitForCacheSync(stopCh,
 		c.podScalesSynced,
-		c.podSynced); !ok {
+		c.podSynced,
+		c.slaSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
+	c.resizeSupported = supportsResize(c.kubernetesClientset.Discovery())
+	klog.Info("Resize subresource supported: ", c.resizeSupported)
+
 	klog.Info("Starting pod resource updater workers")
-	// Launch the workers to process podScale resources and recommend new pod scales
+	go wait.Until(c.runNodeScaleDispatcher, time.Second, stopCh)
+
+	// Launch the workers to update the pods and their pod scales
 	for i := 0; i < threadiness; i++ {
-		go wait.Until(c.runNodeScaleWorker, time.Second, stopCh)
+		go wait.Until(c.runPodScaleWorker, time.Second, stopCh)
 	}
+	go wait.Until(c.sweepResizes, resizeSweepPeriod, stopCh)
 
 	return nil
 }
@@ -121,44 +183,121 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 // Shutdown is called when the controller has finished its work
 func (c *Controller) Shutdown() {
 	utilruntime.HandleCrash()
+	c.workqueue.ShutDown()
 }
 
-func (c *Controller) runNodeScaleWorker() {
+// runNodeScaleDispatcher splits the node scales received from the contention manager
+// into pod scales and enqueues them, so that each pod scale is updated and retried separately.
+func (c *Controller) runNodeScaleDispatcher() {
 	for nodeScale := range c.in {
 		klog.Info("Processing ", nodeScale)
 		for _, podScale := range nodeScale.PodScales {
+			key := fmt.Sprintf("%s/%s", podScale.Namespace, podScale.Name)
+			// a newer pod scale replaces the one still waiting to be updated
+			c.pending.Store(key, podScale)
+			c.workqueue.Add(key)
+		}
+	}
+}
+
+func (c *Controller) runPodScaleWorker() {
+	for c.workqueue.ProcessNextItem(c.syncPodScale) {
+	}
+}
+
+// syncPodScale updates the pod scale waiting for the given key and its pod. When the update fails the pod
+// scale is put back, unless a newer one has been received in the meantime or the queue gives up on it.
+func (c *Controller) syncPodScale(key string) error {
+	value, ok := c.pending.LoadAndDelete(key)
+	if !ok {
+		return nil
+	}
+	podScale, ok := value.(*v1beta1.PodScale)
+	if !ok {
+		return nil
+	}
+
+	if err := c.updatePodScale(key, podScale); err != nil {
+		c.recorder.Event(podScale, corev1.EventTypeWarning, ResizeFailed, err.Error())
+		if !c.workqueue.Dropping(key) {
+			c.pending.LoadOrStore(key, podScale)
+		}
+		return err
+	}
+
+	return nil
+}
 
-			pod, err := c.listers.Pods(podScale.Spec.Namespace).Get(podScale.Spec.Pod)
-			if err != nil {
-				klog.Error("Error retrieving the pod: ", err)
-				return
-			}
-
-			newPod, err := syncPod(pod, *podScale)
-			if err != nil {
-				klog.Error("Error syncing the pod: ", err)
-				return
-			}
-
-			// try both updates in dry-run first and then actuate them consistently
-			updatedPod, updatedPodScale, err := c.AtomicResourceUpdate(newPod, podScale)
-
-			if err != nil {
-				klog.Error("Error while updating pod and podscale: ", err)
-				//TODO: We are using this channel as a workqueue. Why don't use one?
-				c.in <- nodeScale
-				return
-			}
-
-			//TODO: handle error
-			_ = c.log.Log(updatedPodScale)
-
-			klog.Info("Desired resources:", updatedPodScale.Spec.DesiredResources)
-			klog.Info("Capped resources:", updatedPodScale.Status.CappedResources)
-			klog.Info("Actual resources:", updatedPodScale.Status.ActualResources)
-			klog.Info("Pod resources:", updatedPod.Spec.Containers[0].Resources)
+// updatePodScale actuates the resources of a pod scale on its pod, according to the resize policy
+func (c *Controller) updatePodScale(key string, podScale *v1beta1.PodScale) error {
+	pod, err := c.listers.Pods(podScale.Spec.Namespace).Get(podScale.Spec.Pod)
+	if err != nil {
+		return fmt.Errorf("error: %s, cannot retrieve pod with name %s and namespace %s", err, podScale.Spec.Pod, podScale.Spec.Namespace)
+	}
+
+	// in shadow mode only the pod scale is updated with the recommendation
+	shadow, err := c.shadowed(podScale)
+	if err != nil {
+		return err
+	}
+	if shadow {
+		return c.updateShadowPodScale(key, pod, podScale)
+	}
+
+	// the outcome of the resizes is reported by the kubelet only through the resize subresource
+	if c.resizeSupported {
+		if busy, err := c.trackResize(key, podScale, pod); err != nil || busy {
+			return err
 		}
 	}
+
+	newPod, err := syncPod(pod, *podScale)
+	if err != nil {
+		return fmt.Errorf("error: %s, cannot sync pod with name %s and namespace %s", err, pod.Name, pod.Namespace)
+	}
+
+	// small or too frequent changes are not actuated to limit the churn on the API server
+	now := time.Now()
+	current, desired := containerResources(pod, podScale.Spec.Container), containerResources(newPod, podScale.Spec.Container)
+	if reason, skip := c.policy.skip(current, desired, podScale.Status.LastResizeTime, now); skip {
+		klog.Info("Skipping the resize of pod ", pod.Namespace, "/", pod.Name, ": ", reason)
+		c.recorder.Event(podScale, corev1.EventTypeNormal, ResizeSkipped, reason)
+		return c.keepPodScale(pod, podScale)
+	}
+
+	resized := !equality.Semantic.DeepEqual(current, desired)
+	newPodScale := podScale.DeepCopy()
+	if resized {
+		newPodScale.Status.LastResizeTime = &metav1.Time{Time: now}
+	}
+
+	// try both updates in dry-run first and then actuate them consistently
+	updatedPod, updatedPodScale, err := c.AtomicResourceUpdate(newPod, newPodScale)
+	if err != nil {
+		return err
+	}
+
+	if resized && c.resizeSupported {
+		c.resizes.Store(key, &pendingResize{started: now, previous: current, desired: desired})
+	}
+
+	//TODO: handle error
+	_ = c.log.Log(updatedPodScale)
+
+	klog.Info("Desired resources:", updatedPodScale.Spec.DesiredResources)
+	klog.Info("Capped resources:", updatedPodScale.Status.CappedResources)
+	klog.Info("Actual resources:", updatedPodScale.Status.ActualResources)
+	klog.Info("Pod resources:", updatedPod.Spec.Containers[0].Resources)
+	return nil
+}
+
+// keepPodScale records the recommendation of the pod scale without resizing its pod,
+// so that the controller state and the decisions are not lost
+func (c *Controller) keepPodScale(pod *corev1.Pod, podScale *v1beta1.PodScale) error {
+	if _, err := c.applyPodScale(deployedPodScale(pod, podScale), applyOptions(false)); err != nil {
+		return fmt.Errorf("error: %s, cannot update pod scale with name %s and namespace %s", err, podScale.Name, podScale.Namespace)
+	}
+	return nil
 }
 
 // AtomicResourceUpdate updates a Pod and its PodScale consistently in order to keep synchronized the two resources. Before performing the real update
@@ -175,23 +314,31 @@ func (c *Controller) AtomicResourceUpdate(pod *corev1.Pod, podScale *v1beta1.Pod
 	return c.updateResources(pod, podScale, false)
 }
 
-// updateResources performs Pod and PodScale resource update in dry-run mode or not whether the corresponding flag is passed
+// updateResources applies the resources of the Pod and the PodScale in dry-run mode or not whether the corresponding flag is passed.
+// Only the fields owned by the resource updater are sent, so that the changes made by other controllers do not cause conflicts.
 func (c *Controller) updateResources(pod *corev1.Pod, podScale *v1beta1.PodScale, dryRun bool) (newPod *corev1.Pod, newPodScale *v1beta1.PodScale, err error) {
 
-	opts := &metav1.UpdateOptions{}
+	opts := applyOptions(dryRun)
 
-	if dryRun {
-		opts.DryRun = []string{metav1.DryRunAll}
+	// the resize subresource only changes the resources, the pod spec is patched on older API servers
+	resizeStatus := v1beta1.PodResizeStatus("")
+	if c.resizeSupported {
+		newPod, resizeStatus, err = c.resizePod(pod, podScale.Spec.Container, opts)
+	} else {
+		newPod, err = c.applyPod(pod, podScale.Spec.Container, opts)
 	}
 
-	newPod, err = c.kubernetesClientset.CoreV1().Pods(podScale.Spec.Namespace).Update(context.TODO(), pod, *opts)
-
 	if err != nil {
 		klog.Error("Error updating the pod: ", err)
 		return nil, nil, err
 	}
 
-	newPodScale, err = c.podScalesClientset.SystemautoscalerV1beta1().PodScales(podScale.Namespace).Update(context.TODO(), podScale, *opts)
+	if resizeStatus != podScale.Status.ResizeStatus {
+		podScale = podScale.DeepCopy()
+		podScale.Status.ResizeStatus = resizeStatus
+	}
+
+	newPodScale, err = c.applyPodScale(podScale, opts)
 
 	if err != nil {
 		klog.Error("Error updating the pod scale: ", err)
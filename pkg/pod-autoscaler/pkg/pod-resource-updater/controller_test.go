@@ -0,0 +1,198 @@
+package resourceupdater
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	safake "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned/fake"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
+	"github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/logger"
+	"github.com/lterrac/system-autoscaler/pkg/podscale-controller/pkg/types"
+	"github.com/lterrac/system-autoscaler/pkg/queue"
+	"github.com/modern-go/concurrent"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// applyReactor answers the apply patches of the given kind, which are not supported by the fake
+// clientsets, with the applied object and records them in the patches
+func applyReactor(patches *[]k8stesting.PatchAction, newObject func() runtime.Object) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch := action.(k8stesting.PatchAction)
+		*patches = append(*patches, patch)
+		object := newObject()
+		return true, object, json.Unmarshal(patch.GetPatch(), object)
+	}
+}
+
+// newTestController returns a controller backed by fake clientsets, whose listers contain the given objects.
+// The apply patches sent to the pods and to the pod scales are recorded in the returned slices.
+func newTestController(t *testing.T, policy ResizePolicy, objects ...runtime.Object) (*Controller, *[]k8stesting.PatchAction, *[]k8stesting.PatchAction) {
+	pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	podScales := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	slas := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	kubernetesObjects := make([]runtime.Object, 0)
+	for _, object := range objects {
+		switch object.(type) {
+		case *corev1.Pod:
+			require.Nil(t, pods.Add(object))
+			kubernetesObjects = append(kubernetesObjects, object)
+		case *v1beta1.PodScale:
+			require.Nil(t, podScales.Add(object))
+		case *v1beta1.ServiceLevelAgreement:
+			require.Nil(t, slas.Add(object))
+		default:
+			kubernetesObjects = append(kubernetesObjects, object)
+		}
+	}
+
+	fileLogger, err := logger.NewFileLogger(filepath.Join(t.TempDir(), "podscale.json"))
+	require.Nil(t, err)
+
+	podPatches, podScalePatches := make([]k8stesting.PatchAction, 0), make([]k8stesting.PatchAction, 0)
+	kubernetesClientset := fake.NewSimpleClientset(kubernetesObjects...)
+	kubernetesClientset.PrependReactor("patch", "pods", applyReactor(&podPatches, func() runtime.Object { return &corev1.Pod{} }))
+	podScalesClientset := safake.NewSimpleClientset()
+	podScalesClientset.PrependReactor("patch", "podscales", applyReactor(&podScalePatches, func() runtime.Object { return &v1beta1.PodScale{} }))
+
+	c := &Controller{
+		kubernetesClientset: kubernetesClientset,
+		podScalesClientset:  podScalesClientset,
+		listers: informers.Listers{
+			PodLister:                   corelisters.NewPodLister(pods),
+			PodScaleLister:              salisters.NewPodScaleLister(podScales),
+			ServiceLevelAgreementLister: salisters.NewServiceLevelAgreementLister(slas),
+		},
+		recorder:  record.NewFakeRecorder(100),
+		log:       fileLogger,
+		policy:    policy,
+		workqueue: queue.NewRetryingQueue("PodScaleQueue", maxRetries),
+		pending:   *concurrent.NewMap(),
+		resizes:   *concurrent.NewMap(),
+	}
+	t.Cleanup(c.workqueue.ShutDown)
+	return c, &podPatches, &podScalePatches
+}
+
+// appliedStatus returns the status of the pod scale sent by the last status patch
+func appliedStatus(t *testing.T, patches []k8stesting.PatchAction) v1beta1.PodScaleStatus {
+	for i := len(patches) - 1; i >= 0; i-- {
+		if patches[i].GetSubresource() == "status" {
+			podScale := &v1beta1.PodScale{}
+			require.Nil(t, json.Unmarshal(patches[i].GetPatch(), podScale))
+			return podScale.Status
+		}
+	}
+	require.FailNow(t, "no status patch sent")
+	return v1beta1.PodScaleStatus{}
+}
+
+func TestUpdateSkippedPodScale(t *testing.T) {
+	deployed := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	}
+	recommended := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1050m"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	}
+	state := &v1beta1.ControllerState{XCPrec: resource.NewMilliQuantity(1050, resource.DecimalSI)}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "container", Resources: corev1.ResourceRequirements{Requests: deployed, Limits: deployed}}},
+		},
+		Status: corev1.PodStatus{QOSClass: corev1.PodQOSGuaranteed},
+	}
+	sla := &v1beta1.ServiceLevelAgreement{ObjectMeta: metav1.ObjectMeta{Name: "sla", Namespace: "default"}}
+	podScale := &v1beta1.PodScale{
+		ObjectMeta: metav1.ObjectMeta{Name: "podscale", Namespace: "default"},
+		Spec:       v1beta1.PodScaleSpec{Namespace: "default", Pod: "pod", SLA: "sla", Container: "container", DesiredResources: recommended},
+		Status: v1beta1.PodScaleStatus{
+			CappedResources: recommended,
+			ActualResources: recommended,
+			ControllerState: state,
+			Decisions:       []v1beta1.Decision{{Desired: recommended, Actual: recommended}},
+		},
+	}
+
+	c, podPatches, podScalePatches := newTestController(t, ResizePolicy{MinRelativeChange: 0.1}, pod, sla)
+
+	require.Nil(t, c.updatePodScale("default/podscale", podScale))
+
+	// the pod is not resized, but the recommendation is recorded with the deployed resources
+	require.Empty(t, *podPatches)
+	status := appliedStatus(t, *podScalePatches)
+	require.True(t, equality.Semantic.DeepEqual(deployed, status.ActualResources))
+	require.True(t, equality.Semantic.DeepEqual(state, status.ControllerState))
+	require.True(t, equality.Semantic.DeepEqual(podScale.Status.Decisions, status.Decisions))
+}
+
+func TestSyncPodScaleIsolation(t *testing.T) {
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	}
+	recommended := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	}
+
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "healthy", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName:   "node",
+			Containers: []corev1.Container{{Name: "container", Resources: corev1.ResourceRequirements{Requests: resources, Limits: resources}}},
+		},
+		Status: corev1.PodStatus{QOSClass: corev1.PodQOSGuaranteed},
+	}
+	sla := &v1beta1.ServiceLevelAgreement{ObjectMeta: metav1.ObjectMeta{Name: "sla", Namespace: "default"}}
+
+	newPodScale := func(name string) *v1beta1.PodScale {
+		return &v1beta1.PodScale{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       v1beta1.PodScaleSpec{Namespace: "default", Pod: name, SLA: "sla", Container: "container", DesiredResources: recommended},
+			Status:     v1beta1.PodScaleStatus{CappedResources: recommended, ActualResources: recommended},
+		}
+	}
+
+	c, podPatches, _ := newTestController(t, ResizePolicy{}, pod, sla)
+
+	// the pod of the failing pod scale does not exist
+	in := make(chan types.NodeScales, 1)
+	in <- types.NodeScales{Node: "node", PodScales: []*v1beta1.PodScale{newPodScale("failing"), newPodScale("healthy")}}
+	close(in)
+	c.in = in
+	c.runNodeScaleDispatcher()
+
+	require.True(t, c.workqueue.ProcessNextItem(c.syncPodScale))
+	require.True(t, c.workqueue.ProcessNextItem(c.syncPodScale))
+
+	// the healthy pod is resized even though the other pod scale of the node failed
+	require.NotEmpty(t, *podPatches)
+	_, ok := c.pending.Load("default/healthy")
+	require.False(t, ok)
+	_, ok = c.pending.Load("default/failing")
+	require.True(t, ok)
+
+	// the failing pod scale is dropped along with its pending update after the retries
+	for i := 0; i < maxRetries; i++ {
+		require.True(t, c.workqueue.ProcessNextItem(c.syncPodScale))
+	}
+	_, ok = c.pending.Load("default/failing")
+	require.False(t, ok)
+	require.Equal(t, 0, c.workqueue.Len())
+}
@@ -0,0 +1,220 @@
+package resourceupdater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	contentionmanager "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/contention-manager"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// pendingResize is a resize accepted by the API server and not yet observed in the container status
+type pendingResize struct {
+	started  time.Time
+	previous corev1.ResourceRequirements
+	desired  corev1.ResourceRequirements
+}
+
+// resizeOutcome is the outcome of a resize observed on the pod
+type resizeOutcome int
+
+const (
+	// resizeCompleted means that the kubelet actuated the resize
+	resizeCompleted resizeOutcome = iota
+	// resizePending means that the kubelet has not actuated the resize yet
+	resizePending
+	// resizeFailed means that the resize is infeasible or it timed out
+	resizeFailed
+)
+
+// containerObservation contains the resources of the containers reported by the kubelet,
+// which are not part of the pod types known by the client
+type containerObservation struct {
+	Status struct {
+		ContainerStatuses []struct {
+			Name string `json:"name"`
+			// AllocatedResources are the resources the kubelet admitted for the container
+			AllocatedResources corev1.ResourceList `json:"allocatedResources,omitempty"`
+			// Resources are the resources configured in the container runtime
+			Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
+		} `json:"containerStatuses,omitempty"`
+	} `json:"status"`
+}
+
+// observeResize returns the outcome of a pending resize of a container from the JSON representation of its pod,
+// along with the reason of a failure. The resize is completed when the container status reports the desired
+// requests or, on kubelets that do not report them, when the pod no longer reports a resize status.
+func observeResize(raw []byte, container string, resize *pendingResize, timeout time.Duration, now time.Time) (resizeOutcome, string, error) {
+	status, err := resizeStatus(raw)
+	if err != nil {
+		return resizePending, "", err
+	}
+
+	if status == v1beta1.ResizeInfeasible {
+		return resizeFailed, "the node cannot fit the new resources", nil
+	}
+
+	observation := containerObservation{}
+	if err = json.Unmarshal(raw, &observation); err != nil {
+		return resizePending, "", err
+	}
+
+	completed := status == ""
+	for _, s := range observation.Status.ContainerStatuses {
+		if s.Name != container {
+			continue
+		}
+		switch {
+		case s.Resources != nil:
+			completed = sameRequests(s.Resources.Requests, resize.desired.Requests)
+		case s.AllocatedResources != nil:
+			completed = completed && sameRequests(s.AllocatedResources, resize.desired.Requests)
+		}
+	}
+
+	if completed {
+		return resizeCompleted, "", nil
+	}
+
+	if elapsed := now.Sub(resize.started); timeout > 0 && elapsed >= timeout {
+		return resizeFailed, fmt.Sprintf("the new resources have not been applied within %s", timeout), nil
+	}
+
+	return resizePending, "", nil
+}
+
+// sameRequests tells whether the observed resources match the desired ones
+func sameRequests(observed, desired corev1.ResourceList) bool {
+	for name, quantity := range desired {
+		actual, ok := observed[name]
+		if !ok || quantity.Cmp(actual) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// trackResize checks the outcome of the last resize of the pod scale and rolls it back when it failed.
+// It returns true while the resize is pending or when it has just been rolled back, so that
+// no other resize is actuated in the meantime. The pod scale is recorded in both cases.
+func (c *Controller) trackResize(key string, podScale *v1beta1.PodScale, pod *corev1.Pod) (bool, error) {
+	value, ok := c.resizes.Load(key)
+	if !ok {
+		return false, nil
+	}
+	resize, ok := value.(*pendingResize)
+	if !ok {
+		c.resizes.Delete(key)
+		return false, nil
+	}
+
+	// the informer pods do not contain the resize fields, so the pod is retrieved in its JSON representation
+	raw, err := c.kubernetesClientset.CoreV1().RESTClient().
+		Get().
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		Do(context.TODO()).
+		Raw()
+	if err != nil {
+		return true, fmt.Errorf("error: %s, cannot retrieve pod with name %s and namespace %s", err, pod.Name, pod.Namespace)
+	}
+
+	outcome, reason, err := observeResize(raw, podScale.Spec.Container, resize, c.policy.Timeout, time.Now())
+	if err != nil {
+		return true, fmt.Errorf("error: %s, cannot observe the resize of pod %s/%s", err, pod.Namespace, pod.Name)
+	}
+
+	switch outcome {
+	case resizeCompleted:
+		c.resizes.Delete(key)
+		return false, nil
+	case resizePending:
+		klog.Info("Waiting for the resize of pod ", pod.Namespace, "/", pod.Name)
+		return true, c.keepPodScale(pod, podScale)
+	}
+
+	if err = c.rollbackResize(podScale, pod, resize, reason); err != nil {
+		return true, err
+	}
+	c.resizes.Delete(key)
+	return true, nil
+}
+
+// rollbackResize restores the resources the pod had before a failed resize and marks its node,
+// so that the contention manager stops overcommitting it.
+func (c *Controller) rollbackResize(podScale *v1beta1.PodScale, pod *corev1.Pod, resize *pendingResize, reason string) error {
+	newPod := pod.DeepCopy()
+	for i, container := range newPod.Spec.Containers {
+		if container.Name == podScale.Spec.Container {
+			newPod.Spec.Containers[i].Resources = resize.previous
+		}
+	}
+
+	newPodScale := podScale.DeepCopy()
+	newPodScale.Status.ActualResources = resize.previous.Requests
+	if len(newPodScale.Status.ActualLimits) > 0 {
+		newPodScale.Status.ActualLimits = resize.previous.Limits
+	}
+
+	if _, _, err := c.AtomicResourceUpdate(newPod, newPodScale); err != nil {
+		return fmt.Errorf("error: %s, cannot roll back the resize of pod %s/%s", err, pod.Namespace, pod.Name)
+	}
+
+	klog.Info("Rolled back the resize of pod ", pod.Namespace, "/", pod.Name, ": ", reason)
+	c.recorder.Eventf(podScale, corev1.EventTypeWarning, ResizeRolledBack, "The resize of pod %s/%s has been rolled back: %s", pod.Namespace, pod.Name, reason)
+
+	if err := c.markNode(pod.Spec.NodeName, time.Now()); err != nil {
+		klog.Error("Error marking node ", pod.Spec.NodeName, ": ", err)
+	}
+
+	return nil
+}
+
+// markNode records on the node the time of an infeasible resize
+func (c *Controller) markNode(name string, now time.Time) error {
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				contentionmanager.ResizeInfeasibleAnnotation: now.UTC().Format(time.RFC3339),
+			},
+		},
+	}
+
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.kubernetesClientset.CoreV1().Nodes().Patch(context.TODO(), name, types.MergePatchType, data, metav1.PatchOptions{})
+	return err
+}
+
+// sweepResizes stops tracking the resizes of the pod scales that no longer exist
+func (c *Controller) sweepResizes() {
+	c.resizes.Range(func(k, v interface{}) bool {
+		key, _ := k.(string)
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if err != nil {
+			c.resizes.Delete(k)
+			return true
+		}
+
+		_, err = c.listers.PodScales(namespace).Get(name)
+		if errors.IsNotFound(err) {
+			klog.V(4).Info("Evicting the resize of pod scale ", key)
+			c.resizes.Delete(k)
+		} else if err != nil {
+			klog.Error(err)
+		}
+		return true
+	})
+}
@@ -0,0 +1,160 @@
+package resourceupdater
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	contentionmanager "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/contention-manager"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestObserveResize(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	resize := &pendingResize{
+		started: now.Add(-time.Minute),
+		desired: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+		},
+	}
+
+	testcases := []struct {
+		description string
+		pod         string
+		timeout     time.Duration
+		expected    resizeOutcome
+	}{
+		{
+			description: "should complete when the container reports the desired requests",
+			pod:         `{"status": {"containerStatuses": [{"name": "container", "resources": {"requests": {"cpu": "0.5", "memory": "256Mi"}}}]}}`,
+			timeout:     0,
+			expected:    resizeCompleted,
+		},
+		{
+			description: "should wait while the container reports the old requests",
+			pod:         `{"status": {"containerStatuses": [{"name": "container", "resources": {"requests": {"cpu": "1", "memory": "256Mi"}}}]}}`,
+			timeout:     0,
+			expected:    resizePending,
+		},
+		{
+			description: "should wait for the allocated resources while the resize is in progress",
+			pod:         `{"status": {"resize": "InProgress", "containerStatuses": [{"name": "container", "allocatedResources": {"cpu": "500m", "memory": "256Mi"}}]}}`,
+			timeout:     2 * time.Minute,
+			expected:    resizePending,
+		},
+		{
+			description: "should complete when the kubelet does not report the container resources",
+			pod:         `{"status": {"containerStatuses": [{"name": "other"}]}}`,
+			timeout:     0,
+			expected:    resizeCompleted,
+		},
+		{
+			description: "should fail an infeasible resize",
+			pod:         `{"status": {"resize": "Infeasible"}}`,
+			timeout:     0,
+			expected:    resizeFailed,
+		},
+		{
+			description: "should fail a resize after the timeout",
+			pod:         `{"status": {"conditions": [{"type": "PodResizePending", "status": "True", "reason": "Deferred"}]}}`,
+			timeout:     time.Minute,
+			expected:    resizeFailed,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			outcome, reason, err := observeResize([]byte(tt.pod), "container", resize, tt.timeout, now)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, outcome)
+			require.Equal(t, tt.expected == resizeFailed, reason != "")
+		})
+	}
+}
+
+func TestRollbackResize(t *testing.T) {
+	previous := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("512Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1"), corev1.ResourceMemory: resource.MustParse("512Mi")},
+	}
+	desired := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("512Mi")},
+		Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4"), corev1.ResourceMemory: resource.MustParse("512Mi")},
+	}
+
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node"}}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+		Spec: corev1.PodSpec{
+			NodeName:   "node",
+			Containers: []corev1.Container{{Name: "container", Resources: desired}},
+		},
+		Status: corev1.PodStatus{QOSClass: corev1.PodQOSGuaranteed},
+	}
+	podScale := &v1beta1.PodScale{
+		ObjectMeta: metav1.ObjectMeta{Name: "podscale", Namespace: "default"},
+		Spec:       v1beta1.PodScaleSpec{Namespace: "default", Pod: "pod", Container: "container", DesiredResources: desired.Requests},
+		Status:     v1beta1.PodScaleStatus{ActualResources: desired.Requests, ResizeStatus: v1beta1.ResizeInfeasible},
+	}
+
+	c, podPatches, podScalePatches := newTestController(t, ResizePolicy{}, node, pod)
+	resize := &pendingResize{started: time.Now(), previous: previous, desired: desired}
+
+	require.Nil(t, c.rollbackResize(podScale, pod, resize, "the node cannot fit the new resources"))
+
+	// the pod gets back its previous resources, which are reported by the pod scale
+	require.NotEmpty(t, *podPatches)
+	applied := &corev1.Pod{}
+	require.Nil(t, json.Unmarshal((*podPatches)[len(*podPatches)-1].GetPatch(), applied))
+	require.True(t, equality.Semantic.DeepEqual(previous, applied.Spec.Containers[0].Resources))
+	require.True(t, equality.Semantic.DeepEqual(previous.Requests, appliedStatus(t, *podScalePatches).ActualResources))
+
+	// the node is marked, so that the contention manager stops overcommitting it
+	marked, err := c.kubernetesClientset.CoreV1().Nodes().Get(context.TODO(), "node", metav1.GetOptions{})
+	require.Nil(t, err)
+	require.Contains(t, marked.Annotations, contentionmanager.ResizeInfeasibleAnnotation)
+}
+
+func TestMarkNode(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node", Annotations: map[string]string{"owner": "team"}}}
+
+	c, _, _ := newTestController(t, ResizePolicy{}, node)
+
+	require.Nil(t, c.markNode("node", now))
+
+	marked, err := c.kubernetesClientset.CoreV1().Nodes().Get(context.TODO(), "node", metav1.GetOptions{})
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{
+		"owner": "team",
+		contentionmanager.ResizeInfeasibleAnnotation: "2021-01-01T00:00:00Z",
+	}, marked.Annotations)
+
+	require.Error(t, c.markNode("missing", now))
+}
+
+func TestSweepResizes(t *testing.T) {
+	podScale := &v1beta1.PodScale{ObjectMeta: metav1.ObjectMeta{Name: "alive", Namespace: "default"}}
+	c, _, _ := newTestController(t, ResizePolicy{}, podScale)
+
+	c.resizes.Store("default/alive", &pendingResize{})
+	c.resizes.Store("default/deleted", &pendingResize{})
+
+	c.sweepResizes()
+
+	keys := make([]string, 0)
+	c.resizes.Range(func(k, v interface{}) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	require.Equal(t, []string{"default/alive"}, keys)
+}
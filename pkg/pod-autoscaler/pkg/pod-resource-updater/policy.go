@@ -0,0 +1,111 @@
+package resourceupdater
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ResizePolicy defines which changes of the resources are worth resizing a pod.
+// The zero value resizes the pods at every change.
+type ResizePolicy struct {
+	// MinChange is the absolute change of each resource below which the pod is not resized
+	MinChange v1.ResourceList
+	// MinRelativeChange is the fraction of the current value of each resource below which the pod is not resized
+	MinRelativeChange float64
+	// MinInterval is the minimum time between two resizes of the same pod
+	MinInterval time.Duration
+	// Timeout is the time after which a resize not yet actuated by the kubelet is rolled back, zero means never
+	Timeout time.Duration
+}
+
+// NewResizePolicy parses the minimum absolute changes of cpu and memory, the minimum relative
+// change as a percentage in the range [0, 100), the minimum interval between two resizes and
+// the timeout of the resizes.
+func NewResizePolicy(minCPU, minMemory, minRelativeChange string, minInterval, timeout time.Duration) (ResizePolicy, error) {
+	policy := ResizePolicy{
+		MinChange:   make(v1.ResourceList),
+		MinInterval: minInterval,
+		Timeout:     timeout,
+	}
+
+	for name, value := range map[v1.ResourceName]string{v1.ResourceCPU: minCPU, v1.ResourceMemory: minMemory} {
+		quantity, err := resource.ParseQuantity(value)
+		if err != nil {
+			return ResizePolicy{}, fmt.Errorf("error: %s, cannot parse the minimum %s change %s", err, name, value)
+		}
+		if quantity.Sign() < 0 {
+			return ResizePolicy{}, fmt.Errorf("the minimum %s change %s is negative", name, value)
+		}
+		policy.MinChange[name] = quantity
+	}
+
+	relative, err := strconv.ParseFloat(minRelativeChange, 64)
+	if err != nil {
+		return ResizePolicy{}, err
+	}
+	if relative < 0 || relative >= 100 {
+		return ResizePolicy{}, fmt.Errorf("the minimum relative change percentage %s is not in the range [0, 100)", minRelativeChange)
+	}
+	policy.MinRelativeChange = relative / 100
+
+	if minInterval < 0 {
+		return ResizePolicy{}, fmt.Errorf("the minimum resize interval %s is negative", minInterval)
+	}
+
+	if timeout < 0 {
+		return ResizePolicy{}, fmt.Errorf("the resize timeout %s is negative", timeout)
+	}
+
+	return policy, nil
+}
+
+// skip tells whether the change from the current to the desired resources of a container should not be
+// actuated and why. Changes within the dead-band of every resource are skipped, as well as changes
+// happening less than the minimum interval after the last resize. Unchanged resources are never skipped,
+// so that the pod scale keeps being updated.
+func (p ResizePolicy) skip(current, desired v1.ResourceRequirements, lastResize *metav1.Time, now time.Time) (string, bool) {
+	resized, significant := false, false
+
+	for _, lists := range [][2]v1.ResourceList{
+		{current.Requests, desired.Requests},
+		{current.Limits, desired.Limits},
+	} {
+		for name, quantity := range lists[1] {
+			old := lists[0][name]
+			change := quantity.MilliValue() - old.MilliValue()
+			if change == 0 {
+				continue
+			}
+			resized = true
+			if math.Abs(float64(change)) >= p.threshold(name, old) {
+				significant = true
+			}
+		}
+	}
+
+	if !resized {
+		return "", false
+	}
+
+	if !significant {
+		return "the change of the resources is below the minimum change", true
+	}
+
+	if lastResize != nil && now.Sub(lastResize.Time) < p.MinInterval {
+		return fmt.Sprintf("the pod was resized %s ago, less than the minimum interval %s", now.Sub(lastResize.Time).Round(time.Second), p.MinInterval), true
+	}
+
+	return "", false
+}
+
+// threshold returns the smallest change of a resource, in milli units, that is actuated
+func (p ResizePolicy) threshold(name v1.ResourceName, current resource.Quantity) float64 {
+	absolute := p.MinChange[name]
+	return math.Max(float64(absolute.MilliValue()), p.MinRelativeChange*float64(current.MilliValue()))
+}
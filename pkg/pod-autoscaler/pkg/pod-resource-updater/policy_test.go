@@ -0,0 +1,119 @@
+package resourceupdater
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewResizePolicy(t *testing.T) {
+	policy, err := NewResizePolicy("10m", "1Mi", "5", time.Minute, 2*time.Minute)
+	require.Nil(t, err)
+	require.Equal(t, resource.MustParse("10m"), policy.MinChange[v1.ResourceCPU])
+	require.Equal(t, resource.MustParse("1Mi"), policy.MinChange[v1.ResourceMemory])
+	require.InDelta(t, 0.05, policy.MinRelativeChange, 1e-9)
+	require.Equal(t, time.Minute, policy.MinInterval)
+	require.Equal(t, 2*time.Minute, policy.Timeout)
+
+	for _, args := range [][]string{
+		{"-10m", "0", "0"},
+		{"0", "memory", "0"},
+		{"0", "0", "100"},
+		{"0", "0", "-1"},
+	} {
+		_, err := NewResizePolicy(args[0], args[1], args[2], 0, 0)
+		require.Error(t, err, args)
+	}
+
+	_, err = NewResizePolicy("0", "0", "0", -time.Second, 0)
+	require.Error(t, err)
+
+	_, err = NewResizePolicy("0", "0", "0", 0, -time.Second)
+	require.Error(t, err)
+}
+
+func TestResizePolicySkip(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	requirements := func(cpu, memory string) v1.ResourceRequirements {
+		resources := v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpu),
+			v1.ResourceMemory: resource.MustParse(memory),
+		}
+		return v1.ResourceRequirements{Requests: resources, Limits: resources}
+	}
+	policy := ResizePolicy{
+		MinChange: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse("50m"),
+			v1.ResourceMemory: resource.MustParse("16Mi"),
+		},
+		MinRelativeChange: 0.1,
+		MinInterval:       time.Minute,
+	}
+
+	testcases := []struct {
+		description string
+		policy      ResizePolicy
+		desired     v1.ResourceRequirements
+		lastResize  *metav1.Time
+		skip        bool
+	}{
+		{
+			description: "should never skip with the default policy",
+			policy:      ResizePolicy{},
+			desired:     requirements("1001m", "1Gi"),
+			lastResize:  &metav1.Time{Time: now},
+			skip:        false,
+		},
+		{
+			description: "should not skip unchanged resources",
+			policy:      policy,
+			desired:     requirements("1", "1Gi"),
+			lastResize:  &metav1.Time{Time: now},
+			skip:        false,
+		},
+		{
+			description: "should skip a change below the relative threshold",
+			policy:      policy,
+			desired:     requirements("1050m", "1Gi"),
+			skip:        true,
+		},
+		{
+			description: "should skip a change below the absolute threshold",
+			policy:      ResizePolicy{MinChange: policy.MinChange},
+			desired:     requirements("1", "1030Mi"),
+			skip:        true,
+		},
+		{
+			description: "should resize when any resource changes enough",
+			policy:      policy,
+			desired:     requirements("1050m", "512Mi"),
+			skip:        false,
+		},
+		{
+			description: "should skip a resize within the minimum interval",
+			policy:      policy,
+			desired:     requirements("2", "1Gi"),
+			lastResize:  &metav1.Time{Time: now.Add(-30 * time.Second)},
+			skip:        true,
+		},
+		{
+			description: "should resize after the minimum interval",
+			policy:      policy,
+			desired:     requirements("2", "1Gi"),
+			lastResize:  &metav1.Time{Time: now.Add(-time.Minute)},
+			skip:        false,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			reason, skip := tt.policy.skip(requirements("1", "1Gi"), tt.desired, tt.lastResize, now)
+			require.Equal(t, tt.skip, skip)
+			require.Equal(t, tt.skip, reason != "")
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package resourceupdater
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/discovery"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// resizeSubresource is the pod subresource used to resize the containers in place
+	resizeSubresource = "resize"
+
+	// podResizePending is the condition reporting that the kubelet cannot actuate the resize yet
+	podResizePending corev1.PodConditionType = "PodResizePending"
+	// podResizeInProgress is the condition reporting that the kubelet is actuating the resize
+	podResizeInProgress corev1.PodConditionType = "PodResizeInProgress"
+)
+
+// resizeObservation contains the fields reporting the status of an in-place resize,
+// which are not part of the pod types known by the client
+type resizeObservation struct {
+	Status struct {
+		// Resize is the status reported by the API servers before the resize conditions were introduced
+		Resize     v1beta1.PodResizeStatus `json:"resize,omitempty"`
+		Conditions []corev1.PodCondition   `json:"conditions,omitempty"`
+	} `json:"status"`
+}
+
+// supportsResize tells whether the API server serves the resize subresource of the pods
+func supportsResize(client discovery.DiscoveryInterface) bool {
+	resources, err := client.ServerResourcesForGroupVersion(corev1.SchemeGroupVersion.String())
+	if err != nil {
+		klog.Info("cannot discover the pod subresources, falling back to pod updates: ", err)
+		return false
+	}
+
+	for _, r := range resources.APIResources {
+		if r.Name == "pods/"+resizeSubresource {
+			return true
+		}
+	}
+	return false
+}
+
+// resizePod applies the resources of a container of the pod through the resize subresource
+// and returns the updated pod along with the status of the resize.
+func (c *Controller) resizePod(pod *corev1.Pod, container string, opts metav1.PatchOptions) (*corev1.Pod, v1beta1.PodResizeStatus, error) {
+	data, err := podApply(pod, container)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw, err := c.kubernetesClientset.CoreV1().RESTClient().
+		Patch(types.ApplyPatchType).
+		Namespace(pod.Namespace).
+		Resource("pods").
+		Name(pod.Name).
+		SubResource(resizeSubresource).
+		VersionedParams(&opts, metav1.ParameterCodec).
+		Body(data).
+		Do(context.TODO()).
+		Raw()
+	if err != nil {
+		return nil, "", err
+	}
+
+	newPod := &corev1.Pod{}
+	if err = json.Unmarshal(raw, newPod); err != nil {
+		return nil, "", fmt.Errorf("error: %s, cannot decode the resized pod %s/%s", err, pod.Namespace, pod.Name)
+	}
+
+	status, err := resizeStatus(raw)
+	if err != nil {
+		return nil, "", fmt.Errorf("error: %s, cannot decode the resize status of pod %s/%s", err, pod.Namespace, pod.Name)
+	}
+
+	return newPod, status, nil
+}
+
+// resizeStatus returns the status of the in-place resize of a pod from its JSON representation.
+// The resize conditions take precedence over the deprecated status.resize field.
+func resizeStatus(raw []byte) (v1beta1.PodResizeStatus, error) {
+	observation := resizeObservation{}
+	if err := json.Unmarshal(raw, &observation); err != nil {
+		return "", err
+	}
+
+	for _, condition := range observation.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case podResizePending:
+			if condition.Reason == string(v1beta1.ResizeInfeasible) {
+				return v1beta1.ResizeInfeasible, nil
+			}
+			return v1beta1.ResizeDeferred, nil
+		case podResizeInProgress:
+			return v1beta1.ResizeInProgress, nil
+		}
+	}
+
+	return observation.Status.Resize, nil
+}
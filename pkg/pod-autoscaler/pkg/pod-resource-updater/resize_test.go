@@ -0,0 +1,70 @@
+package resourceupdater
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSupportsResize(t *testing.T) {
+	client := &fakediscovery.FakeDiscovery{Fake: &k8stesting.Fake{}}
+	require.False(t, supportsResize(client))
+
+	client.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "pods"}, {Name: "pods/status"}},
+	}}
+	require.False(t, supportsResize(client))
+
+	client.Resources[0].APIResources = append(client.Resources[0].APIResources, metav1.APIResource{Name: "pods/resize"})
+	require.True(t, supportsResize(client))
+}
+
+func TestResizeStatus(t *testing.T) {
+	testcases := []struct {
+		description string
+		pod         string
+		expected    v1beta1.PodResizeStatus
+	}{
+		{
+			description: "should report a completed resize",
+			pod:         `{"status": {"conditions": [{"type": "Ready", "status": "True"}]}}`,
+			expected:    "",
+		},
+		{
+			description: "should read the deprecated resize field",
+			pod:         `{"status": {"resize": "Proposed"}}`,
+			expected:    v1beta1.ResizeProposed,
+		},
+		{
+			description: "should read the in progress condition",
+			pod:         `{"status": {"conditions": [{"type": "PodResizeInProgress", "status": "True"}]}}`,
+			expected:    v1beta1.ResizeInProgress,
+		},
+		{
+			description: "should read a deferred resize",
+			pod:         `{"status": {"conditions": [{"type": "PodResizePending", "status": "True", "reason": "Deferred"}]}}`,
+			expected:    v1beta1.ResizeDeferred,
+		},
+		{
+			description: "should prefer the conditions to the deprecated field",
+			pod:         `{"status": {"resize": "InProgress", "conditions": [{"type": "PodResizePending", "status": "True", "reason": "Infeasible"}]}}`,
+			expected:    v1beta1.ResizeInfeasible,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			status, err := resizeStatus([]byte(tt.pod))
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, status)
+		})
+	}
+
+	_, err := resizeStatus([]byte("{"))
+	require.Error(t, err)
+}
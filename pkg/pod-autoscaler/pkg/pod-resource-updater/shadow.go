@@ -0,0 +1,52 @@
+package resourceupdater
+
+import (
+	"fmt"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// shadowed tells whether the pod of the pod scale must be left untouched, either because the
+// resource updater runs in shadow mode or because the agreement of the pod scale enables it
+func (c *Controller) shadowed(podScale *v1beta1.PodScale) (bool, error) {
+	if c.shadow {
+		return true, nil
+	}
+
+	sla, err := c.listers.ServiceLevelAgreements(podScale.Spec.Namespace).Get(podScale.Spec.SLA)
+	if err != nil {
+		return false, fmt.Errorf("error: %s, cannot retrieve sla with name %s and namespace %s", err, podScale.Spec.SLA, podScale.Spec.Namespace)
+	}
+
+	return sla.Spec.Shadow, nil
+}
+
+// shadowPodScale returns the pod scale to record in shadow mode, where no resize is ever in progress
+func shadowPodScale(pod *corev1.Pod, podScale *v1beta1.PodScale) *v1beta1.PodScale {
+	newPodScale := deployedPodScale(pod, podScale)
+	newPodScale.Status.ResizeStatus = ""
+	return newPodScale
+}
+
+// updateShadowPodScale records the recommendation of the pod scale without resizing its pod
+func (c *Controller) updateShadowPodScale(key string, pod *corev1.Pod, podScale *v1beta1.PodScale) error {
+	// a resize started before enabling the shadow mode is no longer tracked
+	c.resizes.Delete(key)
+
+	updatedPodScale, err := c.applyPodScale(shadowPodScale(pod, podScale), applyOptions(false))
+	if err != nil {
+		klog.Error("Error updating the pod scale: ", err)
+		return err
+	}
+
+	//TODO: handle error
+	_ = c.log.Log(updatedPodScale)
+
+	klog.Info("Shadow mode, pod ", pod.Namespace, "/", pod.Name, " not resized")
+	klog.Info("Desired resources:", updatedPodScale.Spec.DesiredResources)
+	klog.Info("Capped resources:", updatedPodScale.Status.CappedResources)
+	klog.Info("Actual resources:", updatedPodScale.Status.ActualResources)
+	return nil
+}
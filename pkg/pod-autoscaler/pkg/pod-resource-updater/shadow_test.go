@@ -0,0 +1,148 @@
+package resourceupdater
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestShadowed(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.Nil(t, indexer.Add(&v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{Name: "shadow", Namespace: "default"},
+		Spec:       v1beta1.ServiceLevelAgreementSpec{Shadow: true},
+	}))
+	require.Nil(t, indexer.Add(&v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{Name: "active", Namespace: "default"},
+	}))
+
+	testcases := []struct {
+		description string
+		shadow      bool
+		sla         string
+		expected    bool
+		err         bool
+	}{
+		{
+			description: "should resize the pods of an active agreement",
+			sla:         "active",
+			expected:    false,
+		},
+		{
+			description: "should not resize the pods of a shadow agreement",
+			sla:         "shadow",
+			expected:    true,
+		},
+		{
+			description: "should not resize any pod in shadow mode",
+			shadow:      true,
+			sla:         "active",
+			expected:    true,
+		},
+		{
+			description: "should fail when the agreement does not exist",
+			sla:         "missing",
+			err:         true,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Controller{
+				listers: informers.Listers{ServiceLevelAgreementLister: salisters.NewServiceLevelAgreementLister(indexer)},
+				shadow:  tt.shadow,
+			}
+			podScale := &v1beta1.PodScale{Spec: v1beta1.PodScaleSpec{Namespace: "default", SLA: tt.sla}}
+
+			shadowed, err := c.shadowed(podScale)
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, shadowed)
+		})
+	}
+}
+
+func TestShadowPodScale(t *testing.T) {
+	deployed := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("1"),
+		corev1.ResourceMemory: resource.MustParse("512Mi"),
+	}
+	recommended := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+	burst := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+
+	testcases := []struct {
+		description    string
+		qos            corev1.PodQOSClass
+		resources      corev1.ResourceRequirements
+		expectedActual corev1.ResourceList
+		expectedLimits corev1.ResourceList
+	}{
+		{
+			description:    "should record the resources deployed on a guaranteed pod",
+			qos:            corev1.PodQOSGuaranteed,
+			resources:      corev1.ResourceRequirements{Requests: deployed, Limits: deployed},
+			expectedActual: deployed,
+		},
+		{
+			description:    "should record the limits deployed on a burstable pod",
+			qos:            corev1.PodQOSBurstable,
+			resources:      corev1.ResourceRequirements{Requests: deployed, Limits: burst},
+			expectedActual: deployed,
+			expectedLimits: burst,
+		},
+		{
+			description:    "should keep the actual resources of a pod without requests",
+			qos:            corev1.PodQOSBestEffort,
+			expectedActual: recommended,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pod := &corev1.Pod{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "container", Resources: tt.resources}},
+				},
+				Status: corev1.PodStatus{QOSClass: tt.qos},
+			}
+			podScale := &v1beta1.PodScale{
+				Spec: v1beta1.PodScaleSpec{Container: "container", DesiredResources: recommended},
+				Status: v1beta1.PodScaleStatus{
+					CappedResources: recommended,
+					ActualResources: recommended,
+					ActualLimits:    burst,
+					ResizeStatus:    v1beta1.ResizeInProgress,
+					Decisions:       []v1beta1.Decision{{Desired: recommended, Actual: recommended}},
+				},
+			}
+
+			shadowPodScale := shadowPodScale(pod, podScale)
+
+			require.Equal(t, recommended, shadowPodScale.Spec.DesiredResources)
+			require.Equal(t, recommended, shadowPodScale.Status.CappedResources)
+			require.Equal(t, podScale.Status.Decisions, shadowPodScale.Status.Decisions)
+			require.Equal(t, tt.expectedActual, shadowPodScale.Status.ActualResources)
+			require.Equal(t, tt.expectedLimits, shadowPodScale.Status.ActualLimits)
+			require.Empty(t, shadowPodScale.Status.ResizeStatus)
+
+			// the pod scale received from the contention manager is left untouched
+			require.Equal(t, recommended, podScale.Status.ActualResources)
+		})
+	}
+}
@@ -12,8 +12,15 @@ func syncPod(pod *v1.Pod, podScale v1beta1.PodScale) (*v1.Pod, error) {
 
 	newPod := pod.DeepCopy()
 
-	if newPod.Status.QOSClass != v1.PodQOSGuaranteed {
-		return nil, fmt.Errorf("the pod has %v but it should have 'guaranteed' QOS class", newPod.Status.QOSClass)
+	// Burstable pods get separate limits, which are never written to Guaranteed pods
+	// since the QOS class of a pod cannot change
+	limits := podScale.Status.ActualResources
+	switch {
+	case newPod.Status.QOSClass == v1.PodQOSGuaranteed:
+	case newPod.Status.QOSClass == v1.PodQOSBurstable && len(podScale.Status.ActualLimits) > 0:
+		limits = podScale.Status.ActualLimits
+	default:
+		return nil, fmt.Errorf("the pod has %v but it should have 'guaranteed' QOS class or 'burstable' with separate limits", newPod.Status.QOSClass)
 	}
 
 	if podScale.Status.ActualResources.Cpu().MilliValue() <= 0 {
@@ -27,7 +34,7 @@ func syncPod(pod *v1.Pod, podScale v1beta1.PodScale) (*v1.Pod, error) {
 	for i, container := range newPod.Spec.Containers {
 		if container.Name == podScale.Spec.Container {
 			container.Resources.Requests = podScale.Status.ActualResources
-			container.Resources.Limits = podScale.Status.ActualResources
+			container.Resources.Limits = limits
 			newPod.Spec.Containers[i] = container
 			break
 		}
@@ -36,3 +43,33 @@ func syncPod(pod *v1.Pod, podScale v1beta1.PodScale) (*v1.Pod, error) {
 	return newPod, nil
 
 }
+
+// containerResources returns the resources of the container with the given name, which are empty when the container does not exist
+func containerResources(pod *v1.Pod, name string) v1.ResourceRequirements {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return container.Resources
+		}
+	}
+	return v1.ResourceRequirements{}
+}
+
+// deployedPodScale returns the pod scale to record when its pod is not resized. The recommendation
+// and the decisions are kept, while the actual resources are the ones currently deployed on the pod,
+// so that the next recommendations start from the real state of the pod.
+func deployedPodScale(pod *v1.Pod, podScale *v1beta1.PodScale) *v1beta1.PodScale {
+	newPodScale := podScale.DeepCopy()
+	resources := containerResources(pod, podScale.Spec.Container)
+
+	// pods without requests keep the resources assigned to them by the podscale controller
+	if len(resources.Requests) > 0 {
+		newPodScale.Status.ActualResources = resources.Requests.DeepCopy()
+	}
+
+	newPodScale.Status.ActualLimits = nil
+	if pod.Status.QOSClass == v1.PodQOSBurstable {
+		newPodScale.Status.ActualLimits = resources.Limits.DeepCopy()
+	}
+
+	return newPodScale
+}
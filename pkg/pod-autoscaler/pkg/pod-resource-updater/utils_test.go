@@ -176,3 +176,74 @@ func TestSyncPod(t *testing.T) {
 		})
 	}
 }
+
+func TestSyncBurstablePod(t *testing.T) {
+	requests := v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewScaledQuantity(200, resource.Milli),
+		v1.ResourceMemory: *resource.NewScaledQuantity(200, resource.Mega),
+	}
+	limits := v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewScaledQuantity(400, resource.Milli),
+		v1.ResourceMemory: *resource.NewScaledQuantity(400, resource.Mega),
+	}
+
+	testcases := []struct {
+		description    string
+		podQOS         v1.PodQOSClass
+		actualLimits   v1.ResourceList
+		expectedLimits v1.ResourceList
+		success        bool
+	}{
+		{
+			description:    "should write separate limits to burstable pods",
+			podQOS:         v1.PodQOSBurstable,
+			actualLimits:   limits,
+			expectedLimits: limits,
+			success:        true,
+		},
+		{
+			description:    "should not write separate limits to guaranteed pods",
+			podQOS:         v1.PodQOSGuaranteed,
+			actualLimits:   limits,
+			expectedLimits: requests,
+			success:        true,
+		},
+		{
+			description:  "fail to update a burstable pod without separate limits",
+			podQOS:       v1.PodQOSBurstable,
+			actualLimits: nil,
+			success:      false,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pod := v1.Pod{
+				Spec: v1.PodSpec{
+					Containers: []v1.Container{{Name: "app"}},
+				},
+				Status: v1.PodStatus{
+					QOSClass: tt.podQOS,
+				},
+			}
+			podScale := v1beta1.PodScale{
+				Spec: v1beta1.PodScaleSpec{
+					Container: "app",
+				},
+				Status: v1beta1.PodScaleStatus{
+					ActualResources: requests,
+					ActualLimits:    tt.actualLimits,
+				},
+			}
+
+			newPod, err := syncPod(&pod, podScale)
+			if tt.success {
+				require.Nil(t, err)
+				require.Equal(t, requests, newPod.Spec.Containers[0].Resources.Requests)
+				require.Equal(t, tt.expectedLimits, newPod.Spec.Containers[0].Resources.Limits)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
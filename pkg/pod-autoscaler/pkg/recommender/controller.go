@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lterrac/system-autoscaler/pkg/forecaster"
 	"github.com/lterrac/system-autoscaler/pkg/informers"
 	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
 	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
 	"github.com/lterrac/system-autoscaler/pkg/queue"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
 
 	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
 	"github.com/lterrac/system-autoscaler/pkg/podscale-controller/pkg/types"
@@ -28,7 +31,12 @@ import (
 	samplescheme "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned/scheme"
 )
 
-const controllerAgentName = "recommender"
+const (
+	controllerAgentName = "recommender"
+
+	// logicSweepPeriod is the period between two evictions of the logics of deleted pod scales
+	logicSweepPeriod = time.Minute
+)
 
 // Controller is the controller that recommends resources to the pods.
 // For each Pod Scale assigned to the recommender, it will have a pod saved in a list.
@@ -55,6 +63,12 @@ type Controller struct {
 	// MetricClient is a client that polls the metrics from the pod.
 	MetricClient metricsgetter.MetricGetter
 
+	// forecaster predicts the load of the services whose agreement enables the forecasting
+	forecaster *forecaster.Forecaster
+
+	// shadow freezes the state of the logics of all the pods, which are never resized
+	shadow bool
+
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
 	recorder record.EventRecorder
@@ -65,7 +79,7 @@ type Controller struct {
 
 // Status represents the state of the controller
 type Status struct {
-	// Key: namespace-name of the pod scale, Value: assigned logic
+	// Key: namespace-name of the pod scale, Value: assigned logic state
 	logicMap concurrent.Map
 }
 
@@ -75,6 +89,7 @@ func NewController(
 	podScalesClientset podscalesclientset.Interface,
 	metricsClient metricsgetter.MetricGetter,
 	informers informers.Informers,
+	shadow bool,
 	out chan types.NodeScales,
 ) *Controller {
 
@@ -101,6 +116,8 @@ func NewController(
 		recommendNodeQueue:  queue.NewQueue("RecommendQueue"),
 		status:              status,
 		MetricClient:        metricsClient,
+		forecaster:          forecaster.NewForecaster(),
+		shadow:              shadow,
 		recorder:            recorder,
 		out:                 out,
 	}
@@ -131,6 +148,7 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 		go wait.Until(c.runNodeRecommenderWorker, time.Second, stopCh)
 	}
 	go wait.Until(c.runRecommenderWorker, 5*time.Second, stopCh)
+	go wait.Until(c.sweepLogics, logicSweepPeriod, stopCh)
 	klog.Info("Started recommender workers")
 
 	return nil
@@ -151,6 +169,9 @@ func (c *Controller) runRecommenderWorker() {
 		return
 	}
 
+	// The load of the services is recorded once per cycle, before recommending to their pods
+	c.observeServices()
+
 	for _, node := range nodes {
 		c.recommendNodeQueue.Enqueue(node)
 	}
@@ -224,32 +245,164 @@ func (c *Controller) recommendContainer(podScale *v1beta1.PodScale) (*v1beta1.Po
 	}
 
 	// Retrieve the logic
-	logicInterface, ok := c.status.logicMap.Load(key)
-	if !ok {
-		switch sla.Spec.RecommenderLogic {
-		case v1beta1.FixedGainControl:
-			logicInterface = newFixedGainControlLogic(podScale)
-		case v1beta1.AdaptiveGainControl:
-			logicInterface = newAdaptiveGainControlLogic(podScale)
-		default:
-			logicInterface = newFixedGainControlLogic(podScale)
-			//return nil, fmt.Errorf("illegal value %s as recommender logic", sla.Spec.RecommenderLogic)
-		}
-		c.status.logicMap.Store(key, logicInterface)
+	logic, err := c.logicFor(key, podScale, sla)
+	if err != nil {
+		return nil, err
 	}
-	logic, ok := logicInterface.(Logic)
-	if !ok {
-		return nil, fmt.Errorf("error: %s, failed to cast logic with name %s and namespace %s", err, podScale.Spec.SLA, podScale.Spec.Namespace)
+
+	// Idle pods do not run the logic. The pod is considered busy when its activity cannot be observed.
+	idle, err := c.idle(pod)
+	if err != nil {
+		klog.Info(err, ", the pod is considered busy")
+	} else if idle {
+		idle := recommendIdle(podScale, sla)
+		recordDecision(idle, nil, metav1.Now())
+		return idle, nil
+	}
+
+	// Pods leaving the idle mode immediately get back the resources they had before
+	if busy := restoreBusy(podScale, sla); busy != nil {
+		recordDecision(busy, nil, metav1.Now())
+		return busy, nil
 	}
 
 	// Retrieve the metrics
-	metrics, err := c.MetricClient.PodMetrics(pod, metrics.ResponseTime)
+	podMetrics, err := c.requiredMetrics(pod, podScale, sla)
+	if err != nil {
+		return nil, err
+	}
+
+	// Retrieve the load needed by the model based logics
+	if observer, ok := logic.(loadObserver); ok {
+		load, err := c.podLoad(pod)
+		if err != nil {
+			return nil, err
+		}
+		load.predictedThroughput = load.throughput * c.growth(podScale, sla)
+		observer.observe(load)
+	}
+
+	// The working set is optional, the memory is left unchanged when the resource metrics are not available
+	workingSet, err := c.MetricClient.ContainerWorkingSet(pod, sla.Spec.Service.Container)
 	if err != nil {
-		return nil, fmt.Errorf("error: %s, failed to get metrics from pod with name %s and namespace %s from lister", err, pod.GetName(), pod.GetNamespace())
+		klog.V(4).Info("cannot retrieve the working set of pod ", pod.GetName(), ": ", err)
+		workingSet = nil
 	}
 
 	// Compute the new resources
-	newPodScale, err := logic.computePodScale(pod, podScale, sla, metrics)
+	newPodScale, err := logic.computePodScale(pod, podScale, sla, podMetrics, workingSet)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pods are never resized in shadow mode, so the state of the logic is frozen to prevent its windup.
+	// The logic is restored from the frozen state at the next recommendation.
+	if c.shadow || sla.Spec.Shadow {
+		newPodScale.Status.ControllerState = podScale.Status.ControllerState.DeepCopy()
+		c.status.logicMap.Delete(key)
+	}
 
+	recordDecision(newPodScale, podMetrics, metav1.Now())
 	return newPodScale, nil
 }
+
+// requiredMetrics retrieves the metrics needed to evaluate the requirements of the service level agreement.
+// Throughput is a Service wide requirement so it is retrieved from the Service instead of the Pod.
+func (c *Controller) requiredMetrics(pod *corev1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) (map[metrics.MetricType]*metricsv1beta2.MetricValue, error) {
+	podMetrics := make(map[metrics.MetricType]*metricsv1beta2.MetricValue)
+
+	for _, requirement := range metrics.Requirements(sla.Spec.Metric) {
+		var metric *metricsv1beta2.MetricValue
+		var err error
+
+		if requirement.Metric == metrics.Throughput {
+			service, err := c.listers.Services(podScale.Spec.Namespace).Get(podScale.Spec.Service)
+			if err != nil {
+				return nil, fmt.Errorf("error: %s, cannot retrieve service with name %s and namespace %s", err, podScale.Spec.Service, podScale.Spec.Namespace)
+			}
+			metric, err = c.MetricClient.ServiceMetrics(service, requirement.Metric)
+			if err != nil && requirement.LowerBound {
+				// a service without traffic does not expose its throughput, which is then not evaluated
+				klog.V(2).Infof("cannot retrieve %s metric of service with name %s and namespace %s, the requirement is skipped: %s", requirement.Metric, service.GetName(), service.GetNamespace(), err)
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("error: %s, failed to get %s metric from service with name %s and namespace %s", err, requirement.Metric, service.GetName(), service.GetNamespace())
+			}
+		} else {
+			metric, err = c.MetricClient.PodMetrics(pod, requirement.Metric)
+			if err != nil {
+				return nil, fmt.Errorf("error: %s, failed to get %s metric from pod with name %s and namespace %s", err, requirement.Metric, pod.GetName(), pod.GetNamespace())
+			}
+		}
+
+		podMetrics[requirement.Metric] = metric
+	}
+
+	return podMetrics, nil
+}
+
+// podLoad retrieves the throughput and the mean response time of the pod
+func (c *Controller) podLoad(pod *corev1.Pod) (podLoad, error) {
+	throughput, err := c.MetricClient.PodMetrics(pod, metrics.Throughput)
+	if err != nil {
+		return podLoad{}, fmt.Errorf("error: %s, failed to get %s metric from pod with name %s and namespace %s", err, metrics.Throughput, pod.GetName(), pod.GetNamespace())
+	}
+
+	responseTime, err := c.MetricClient.PodMetrics(pod, metrics.ResponseTime)
+	if err != nil {
+		return podLoad{}, fmt.Errorf("error: %s, failed to get %s metric from pod with name %s and namespace %s", err, metrics.ResponseTime, pod.GetName(), pod.GetNamespace())
+	}
+
+	return podLoad{
+		throughput:   toFloat(&throughput.Value),
+		responseTime: toFloat(&responseTime.Value),
+	}, nil
+}
+
+// observeServices records the load of the services whose agreement enables the forecasting.
+// Each service is observed once, regardless of the number of its pod scales.
+func (c *Controller) observeServices() {
+	podScales, err := c.listers.PodScaleLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("list pod scales failed: %s", err))
+		return
+	}
+
+	observed := make(map[string]bool)
+	for _, podScale := range podScales {
+		key := podScale.Spec.Namespace + "/" + podScale.Spec.Service
+		if observed[key] {
+			continue
+		}
+
+		sla, err := c.listers.ServiceLevelAgreements(podScale.Spec.Namespace).Get(podScale.Spec.SLA)
+		if err != nil || sla.Spec.Forecast == nil {
+			continue
+		}
+		observed[key] = true
+
+		service, err := c.listers.Services(podScale.Spec.Namespace).Get(podScale.Spec.Service)
+		if err != nil {
+			klog.Info("cannot retrieve service ", podScale.Spec.Service, ", load not recorded: ", err)
+			continue
+		}
+
+		growth, err := c.forecaster.ObserveService(service, sla.Spec.Forecast, c.MetricClient)
+		if err != nil {
+			klog.Info("cannot retrieve the load of service ", service.GetName(), ", load not recorded: ", err)
+			continue
+		}
+		klog.Info("predicted throughput growth of service ", service.GetName(), " is: ", growth)
+	}
+}
+
+// growth returns the growth of the throughput of the service of the pod scale predicted by the
+// forecaster from the load recorded by observeServices. It is 1 when the agreement does not
+// enable the forecasting or the history of the service is not long enough.
+func (c *Controller) growth(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) float64 {
+	if sla.Spec.Forecast == nil {
+		return 1
+	}
+	return c.forecaster.Growth(podScale.Spec.Namespace + "/" + podScale.Spec.Service)
+}
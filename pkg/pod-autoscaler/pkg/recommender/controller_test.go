@@ -0,0 +1,172 @@
+package recommender
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/forecaster"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
+	"github.com/modern-go/concurrent"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+// countingGetter counts the service metrics retrieved for each service
+type countingGetter struct {
+	metricsgetter.FakeGetter
+	calls map[string]int
+}
+
+func (g *countingGetter) ServiceMetrics(s *corev1.Service, metricType metrics.MetricType) (*metricsv1beta2.MetricValue, error) {
+	g.calls[s.Name]++
+	return g.FakeGetter.ServiceMetrics(s, metricType)
+}
+
+func TestObserveServices(t *testing.T) {
+	slas := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.Nil(t, slas.Add(&v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{Name: "forecast", Namespace: "default"},
+		Spec:       v1beta1.ServiceLevelAgreementSpec{Forecast: &v1beta1.ForecastParameters{}},
+	}))
+	require.Nil(t, slas.Add(&v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{Name: "current", Namespace: "default"},
+	}))
+
+	services := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, name := range []string{"foo", "bar"} {
+		require.Nil(t, services.Add(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}))
+	}
+
+	podScales := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, podScale := range []struct {
+		name    string
+		service string
+		sla     string
+	}{
+		{"foo-1", "foo", "forecast"},
+		{"foo-2", "foo", "forecast"},
+		{"foo-3", "foo", "forecast"},
+		{"bar-1", "bar", "current"},
+	} {
+		require.Nil(t, podScales.Add(&v1beta1.PodScale{
+			ObjectMeta: metav1.ObjectMeta{Name: podScale.name, Namespace: "default"},
+			Spec:       v1beta1.PodScaleSpec{Namespace: "default", Service: podScale.service, SLA: podScale.sla},
+		}))
+	}
+
+	metricClient := &countingGetter{FakeGetter: metricsgetter.FakeGetter{ResponseTime: 10}, calls: make(map[string]int)}
+	c := &Controller{
+		listers: informers.Listers{
+			ServiceLister:               corelisters.NewServiceLister(services),
+			PodScaleLister:              salisters.NewPodScaleLister(podScales),
+			ServiceLevelAgreementLister: salisters.NewServiceLevelAgreementLister(slas),
+		},
+		MetricClient: metricClient,
+		forecaster:   forecaster.NewForecaster(),
+	}
+
+	c.observeServices()
+
+	// the throughput and the response time are retrieved once for the service with forecasting
+	require.Equal(t, 2, metricClient.calls["foo"])
+	require.Equal(t, 0, metricClient.calls["bar"])
+
+	// the prediction is not available until a whole season is recorded
+	podScale := &v1beta1.PodScale{Spec: v1beta1.PodScaleSpec{Namespace: "default", Service: "foo"}}
+	sla, err := c.listers.ServiceLevelAgreements("default").Get("forecast")
+	require.Nil(t, err)
+	require.Equal(t, float64(1), c.growth(podScale, sla))
+}
+
+func TestShadowFreezesLogic(t *testing.T) {
+	testcases := []struct {
+		description string
+		shadow      bool
+		slaShadow   bool
+		frozen      bool
+	}{
+		{
+			description: "should advance the state of the logic of an active agreement",
+			frozen:      false,
+		},
+		{
+			description: "should freeze the state of the logic of a shadow agreement",
+			slaShadow:   true,
+			frozen:      true,
+		},
+		{
+			description: "should freeze the state of the logic of all the agreements in shadow mode",
+			shadow:      true,
+			frozen:      true,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			resources := corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("512Mi"),
+			}
+
+			pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.Nil(t, pods.Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{Requests: resources, Limits: resources}}},
+				},
+			}))
+
+			slas := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.Nil(t, slas.Add(&v1beta1.ServiceLevelAgreement{
+				ObjectMeta: metav1.ObjectMeta{Name: "sla", Namespace: "default"},
+				Spec: v1beta1.ServiceLevelAgreementSpec{
+					Metric:           v1beta1.MetricRequirement{ResponseTime: *resource.NewQuantity(1, resource.DecimalSI)},
+					RecommenderLogic: v1beta1.FixedGainControl,
+					Service:          &v1beta1.Service{Container: "app"},
+					Shadow:           tt.slaShadow,
+				},
+			}))
+
+			c := &Controller{
+				listers: informers.Listers{
+					PodLister:                   corelisters.NewPodLister(pods),
+					ServiceLevelAgreementLister: salisters.NewServiceLevelAgreementLister(slas),
+				},
+				status:       &Status{logicMap: *concurrent.NewMap()},
+				MetricClient: podMetricGetter{metrics.RequestCount: 10, metrics.ResponseTime: 2},
+				shadow:       tt.shadow,
+			}
+
+			// the pod is never resized, so the pod scale recorded after each recommendation reports the same resources
+			podScale := &v1beta1.PodScale{
+				ObjectMeta: metav1.ObjectMeta{Name: "podscale", Namespace: "default"},
+				Spec:       v1beta1.PodScaleSpec{Namespace: "default", Pod: "pod", SLA: "sla", Container: "app", DesiredResources: resources},
+				Status:     v1beta1.PodScaleStatus{CappedResources: resources, ActualResources: resources},
+			}
+
+			first, err := c.recommendContainer(podScale)
+			require.Nil(t, err)
+			podScale.Status.ControllerState = first.Status.ControllerState
+
+			second, err := c.recommendContainer(podScale)
+			require.Nil(t, err)
+
+			if tt.frozen {
+				require.Nil(t, first.Status.ControllerState)
+				require.Nil(t, second.Status.ControllerState)
+				require.Equal(t, first.Spec.DesiredResources.Cpu().MilliValue(), second.Spec.DesiredResources.Cpu().MilliValue())
+			} else {
+				require.NotNil(t, first.Status.ControllerState)
+				require.NotEqual(t, first.Spec.DesiredResources.Cpu().MilliValue(), second.Spec.DesiredResources.Cpu().MilliValue())
+			}
+		})
+	}
+}
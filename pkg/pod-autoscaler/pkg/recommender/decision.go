@@ -0,0 +1,56 @@
+package recommender
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+// recordDecision appends the recommendation to the decision history of the pod scale.
+// The pod metrics are nil when the logic has not been run, so no error has been computed.
+func recordDecision(podScale *v1beta1.PodScale, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue, now metav1.Time) {
+	decision := v1beta1.Decision{
+		Timestamp: now,
+		Desired:   podScale.Spec.DesiredResources.DeepCopy(),
+		Capped:    podScale.Status.CappedResources.DeepCopy(),
+		CapReason: capReason(podScale.Spec.DesiredResources, podScale.Status.CappedResources),
+	}
+
+	for metric, value := range podMetrics {
+		if metric.IsResponseTime() {
+			responseTime := value.Value.DeepCopy()
+			decision.ResponseTime = &responseTime
+		}
+	}
+
+	if state := podScale.Status.ControllerState; podMetrics != nil && state != nil && state.PrevError != nil {
+		e := state.PrevError.DeepCopy()
+		decision.Error = &e
+	}
+
+	podScale.Status.RecordDecision(decision)
+}
+
+// capReason describes how the service level agreement bounds changed the desired resources
+func capReason(desired, capped v1.ResourceList) string {
+	reasons := make([]string, 0)
+	for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+		d, ok := desired[name]
+		c, found := capped[name]
+		if !ok || !found {
+			continue
+		}
+		switch d.Cmp(c) {
+		case 1:
+			reasons = append(reasons, fmt.Sprintf("%s capped by maxResources", name))
+		case -1:
+			reasons = append(reasons, fmt.Sprintf("%s raised to minResources", name))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
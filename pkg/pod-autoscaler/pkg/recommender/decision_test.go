@@ -0,0 +1,57 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+func TestRecordDecision(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	podScale := &v1beta1.PodScale{
+		Spec: v1beta1.PodScaleSpec{
+			DesiredResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("2"),
+				v1.ResourceMemory: resource.MustParse("64Mi"),
+			},
+		},
+		Status: v1beta1.PodScaleStatus{
+			CappedResources: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("1"),
+				v1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+			ControllerState: &v1beta1.ControllerState{PrevError: resource.NewMilliQuantity(-500, resource.DecimalSI)},
+		},
+	}
+	podMetrics := map[metrics.MetricType]*metricsv1beta2.MetricValue{
+		metrics.ResponseTimeP90: {Value: resource.MustParse("250m")},
+	}
+
+	for i := 0; i < v1beta1.MaxDecisions+2; i++ {
+		recordDecision(podScale, podMetrics, metav1.NewTime(start.Add(time.Duration(i)*time.Minute)))
+	}
+
+	// the history is bounded and the oldest decisions are dropped
+	require.Len(t, podScale.Status.Decisions, v1beta1.MaxDecisions)
+	require.Equal(t, start.Add(2*time.Minute), podScale.Status.Decisions[0].Timestamp.Time)
+
+	decision := podScale.Status.LastDecision()
+	require.Equal(t, start.Add(time.Duration(v1beta1.MaxDecisions+1)*time.Minute), decision.Timestamp.Time)
+	require.Equal(t, int64(250), decision.ResponseTime.MilliValue())
+	require.Equal(t, int64(-500), decision.Error.MilliValue())
+	require.Equal(t, podScale.Spec.DesiredResources, decision.Desired)
+	require.Equal(t, podScale.Status.CappedResources, decision.Capped)
+	require.Equal(t, "cpu capped by maxResources, memory raised to minResources", decision.CapReason)
+
+	// decisions taken without running the logic have no error
+	recordDecision(podScale, nil, metav1.NewTime(start))
+	require.Nil(t, podScale.Status.LastDecision().Error)
+	require.Nil(t, podScale.Status.LastDecision().ResponseTime)
+}
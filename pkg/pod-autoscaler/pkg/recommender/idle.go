@@ -0,0 +1,97 @@
+package recommender
+
+import (
+	"fmt"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog/v2"
+)
+
+// requestCount retrieves the number of requests served by the pod over the metrics window
+func (c *Controller) requestCount(pod *v1.Pod) (int64, error) {
+	count, err := c.MetricClient.PodMetrics(pod, metrics.RequestCount)
+	if err != nil {
+		return 0, fmt.Errorf("error: %s, failed to get %s metric from pod with name %s and namespace %s", err, metrics.RequestCount, pod.GetName(), pod.GetNamespace())
+	}
+	return count.Value.Value(), nil
+}
+
+// idle tells whether the pod served no requests over the metrics window. When the request count
+// is not available, the pod is idle if its response time is zero, since it measured no request.
+func (c *Controller) idle(pod *v1.Pod) (bool, error) {
+	requests, err := c.requestCount(pod)
+	if err == nil {
+		return requests == 0, nil
+	}
+	klog.V(2).Info("cannot retrieve the request count of pod ", pod.GetName(), ", checking its response time: ", err)
+
+	responseTime, err := c.MetricClient.PodMetrics(pod, metrics.ResponseTime)
+	if err != nil {
+		return false, fmt.Errorf("error: %s, failed to get %s metric from pod with name %s and namespace %s", err, metrics.ResponseTime, pod.GetName(), pod.GetNamespace())
+	}
+	return responseTime.Value.IsZero(), nil
+}
+
+// recommendIdle recommends the resources of a pod that does not serve any request.
+// The metrics of an idle pod carry no information, so the logic is not run: the pod either
+// holds its resources or moves to the idle allocation. The resources it had before becoming
+// idle are stored in the controller state, so that they can be restored once it is busy again.
+func recommendIdle(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) *v1beta1.PodScale {
+	newPodScale := podScale.DeepCopy()
+
+	state := newPodScale.Status.ControllerState
+	if state == nil {
+		state = &v1beta1.ControllerState{}
+		newPodScale.Status.ControllerState = state
+	}
+	if state.BusyResources == nil {
+		state.BusyResources = podScale.Status.ActualResources.DeepCopy()
+	}
+
+	desiredResources := podScale.Status.ActualResources.DeepCopy()
+	if sla.Spec.IdlePolicyOrDefault() == v1beta1.IdleScaleDown {
+		for name, quantity := range sla.Spec.IdleResourcesOrDefault() {
+			if name == v1.ResourceCPU || name == v1.ResourceMemory {
+				desiredResources[name] = quantity.DeepCopy()
+			}
+		}
+	}
+
+	klog.Info("pod ", podScale.Spec.Pod, " is idle, policy is: ", sla.Spec.IdlePolicyOrDefault())
+	return withResources(newPodScale, sla, desiredResources)
+}
+
+// restoreBusy recommends the resources a pod had before becoming idle and clears them from
+// the controller state. It returns nil when the pod was not idle.
+func restoreBusy(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) *v1beta1.PodScale {
+	state := podScale.Status.ControllerState
+	if state == nil || state.BusyResources == nil {
+		return nil
+	}
+
+	newPodScale := podScale.DeepCopy()
+	desiredResources := newPodScale.Status.ControllerState.BusyResources
+	newPodScale.Status.ControllerState.BusyResources = nil
+
+	klog.Info("pod ", podScale.Spec.Pod, " is no longer idle, restoring its resources")
+	return withResources(newPodScale, sla, desiredResources)
+}
+
+// withResources sets the desired resources of the pod scale along with the capped ones
+func withResources(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, desiredResources v1.ResourceList) *v1beta1.PodScale {
+	cappedResources := make(v1.ResourceList)
+	for name, quantity := range desiredResources {
+		desired := quantity.DeepCopy()
+		min, checkLower := sla.Spec.MinResources[name]
+		max, checkUpper := sla.Spec.MaxResources[name]
+		capped, _ := applyBounds(&desired, &min, &max, checkLower, checkUpper)
+		cappedResources[name] = *capped
+	}
+
+	podScale.Spec.DesiredResources = desiredResources
+	podScale.Status.CappedResources = cappedResources
+	podScale.Status.CappedLimits = computeLimits(sla, cappedResources)
+	return podScale
+}
@@ -0,0 +1,159 @@
+package recommender
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+// podMetricGetter returns the metrics of the pods. Missing metrics are not available.
+type podMetricGetter map[metrics.MetricType]int64
+
+func (g podMetricGetter) PodMetrics(p *v1.Pod, metricType metrics.MetricType) (*metricsv1beta2.MetricValue, error) {
+	value, ok := g[metricType]
+	if !ok {
+		return nil, fmt.Errorf("%s metric of pod %s is not available", metricType, p.Name)
+	}
+	return &metricsv1beta2.MetricValue{Value: *resource.NewQuantity(value, resource.DecimalSI)}, nil
+}
+
+func (g podMetricGetter) ServiceMetrics(s *v1.Service, metricType metrics.MetricType) (*metricsv1beta2.MetricValue, error) {
+	return nil, fmt.Errorf("service metrics are not available")
+}
+
+func (g podMetricGetter) ContainerWorkingSet(p *v1.Pod, container string) (*resource.Quantity, error) {
+	return nil, fmt.Errorf("resource metrics are not available")
+}
+
+func TestIdleDetection(t *testing.T) {
+	testcases := []struct {
+		description string
+		metrics     podMetricGetter
+		idle        bool
+		err         bool
+	}{
+		{
+			description: "should be idle without requests",
+			metrics:     podMetricGetter{metrics.RequestCount: 0, metrics.ResponseTime: 10},
+			idle:        true,
+		},
+		{
+			description: "should be busy with requests",
+			metrics:     podMetricGetter{metrics.RequestCount: 5, metrics.ResponseTime: 0},
+			idle:        false,
+		},
+		{
+			description: "should be idle without request count and response time",
+			metrics:     podMetricGetter{metrics.ResponseTime: 0},
+			idle:        true,
+		},
+		{
+			description: "should be busy without request count but with response time",
+			metrics:     podMetricGetter{metrics.ResponseTime: 10},
+			idle:        false,
+		},
+		{
+			description: "should fail without request count and response time",
+			metrics:     podMetricGetter{},
+			err:         true,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Controller{MetricClient: tt.metrics}
+
+			idle, err := c.idle(&v1.Pod{})
+			if tt.err {
+				require.Error(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tt.idle, idle)
+		})
+	}
+}
+
+func TestIdle(t *testing.T) {
+	busyResources := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("2"),
+		v1.ResourceMemory: resource.MustParse("1Gi"),
+	}
+	minResources := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("100m"),
+		v1.ResourceMemory: resource.MustParse("128Mi"),
+	}
+
+	testcases := []struct {
+		description string
+		idle        *v1beta1.IdleParameters
+		expected    v1.ResourceList
+	}{
+		{
+			description: "should hold the resources by default",
+			idle:        nil,
+			expected:    busyResources,
+		},
+		{
+			description: "should move to the minimum resources",
+			idle:        &v1beta1.IdleParameters{Policy: v1beta1.IdleScaleDown},
+			expected:    minResources,
+		},
+		{
+			description: "should move to the idle resources, keeping the missing ones",
+			idle: &v1beta1.IdleParameters{
+				Policy:    v1beta1.IdleScaleDown,
+				Resources: v1.ResourceList{v1.ResourceCPU: resource.MustParse("500m")},
+			},
+			expected: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("500m"),
+				v1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			sla := &v1beta1.ServiceLevelAgreement{
+				Spec: v1beta1.ServiceLevelAgreementSpec{
+					MinResources: minResources,
+					Idle:         tt.idle,
+				},
+			}
+			podScale := &v1beta1.PodScale{
+				Status: v1beta1.PodScaleStatus{
+					ActualResources: busyResources,
+					ControllerState: &v1beta1.ControllerState{
+						PrevError: resource.NewMilliQuantity(500, resource.DecimalSI),
+					},
+				},
+			}
+
+			idle := recommendIdle(podScale, sla)
+			require.Equal(t, tt.expected, idle.Spec.DesiredResources)
+			require.Equal(t, tt.expected, idle.Status.CappedResources)
+			require.Equal(t, busyResources, idle.Status.ControllerState.BusyResources)
+			require.Equal(t, podScale.Status.ControllerState.PrevError, idle.Status.ControllerState.PrevError)
+			require.Nil(t, podScale.Status.ControllerState.BusyResources)
+
+			// the busy resources are kept while the pod stays idle
+			idle.Status.ActualResources = idle.Status.CappedResources
+			idle = recommendIdle(idle, sla)
+			require.Equal(t, busyResources, idle.Status.ControllerState.BusyResources)
+
+			busy := restoreBusy(idle, sla)
+			require.NotNil(t, busy)
+			require.Equal(t, busyResources, busy.Spec.DesiredResources)
+			require.Equal(t, busyResources, busy.Status.CappedResources)
+			require.Nil(t, busy.Status.ControllerState.BusyResources)
+
+			require.Nil(t, restoreBusy(busy, sla))
+		})
+	}
+}
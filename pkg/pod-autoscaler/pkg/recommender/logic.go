@@ -7,6 +7,7 @@ import (
 	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
 
 	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/klog/v2"
@@ -14,7 +15,7 @@ import (
 
 // Logic is the logic with which the recommender suggests new resources
 type Logic interface {
-	computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, metric *metricsv1beta2.MetricValue) (*v1beta1.PodScale, error)
+	computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue, workingSet *resource.Quantity) (*v1beta1.PodScale, error)
 }
 
 // FixedGainControlLogic is the logic that apply control theory in order to recommendContainer new resources
@@ -22,34 +23,123 @@ type FixedGainControlLogic struct {
 	xcprec    float64
 	cores     float64
 	prevError float64
+	params    controlParameters
+	memory    *memoryLogic
 }
 
-// newFixedGainControlLogic returns a new control theory logic
-func newFixedGainControlLogic(podScale *v1beta1.PodScale) *FixedGainControlLogic {
-	return &FixedGainControlLogic{
+// newFixedGainControlLogic returns a new control theory logic tuned with the agreement controller parameters.
+// The controller state is restored from the PodScale, if it has been checkpointed.
+func newFixedGainControlLogic(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) *FixedGainControlLogic {
+	params := newControlParameters(sla.Spec.ControllerParameters)
+	logic := &FixedGainControlLogic{
 		xcprec:    float64(podScale.Status.ActualResources.Cpu().MilliValue()),
 		cores:     float64(podScale.Status.ActualResources.Cpu().MilliValue()),
 		prevError: 0.0,
+		params:    params,
+		memory:    newMemoryLogic(podScale, params),
 	}
+
+	if state := podScale.Status.ControllerState; state != nil {
+		restoreCPU(&logic.xcprec, state.XCPrec)
+		restoreCPU(&logic.cores, state.Cores)
+		restore(&logic.prevError, state.PrevError)
+	}
+
+	return logic
 }
 
-const (
-	// Control theory constants
-	maxScaleOut = 1.5
-	minCPU      = 5
-	BC          = 40
-	DC          = 80
-	minError    = -10
-	maxError    = 10
-	minBC       = 10
-	minDC       = 15
-	maxBC       = 100
-	maxDC       = 150
-)
+// checkpoint returns the current state of the controller
+func (logic *FixedGainControlLogic) checkpoint() *v1beta1.ControllerState {
+	state := &v1beta1.ControllerState{
+		XCPrec:    resource.NewMilliQuantity(int64(logic.xcprec), resource.DecimalSI),
+		Cores:     resource.NewMilliQuantity(int64(logic.cores), resource.DecimalSI),
+		PrevError: toQuantity(logic.prevError),
+	}
+	logic.memory.checkpoint(state)
+	return state
+}
+
+// controlParameters contains the control theory constants.
+// CPU values are expressed in millicores.
+type controlParameters struct {
+	maxScaleOut float64
+	minCPU      float64
+	BC          float64
+	DC          float64
+	minError    float64
+	maxError    float64
+	minBC       float64
+	minDC       float64
+	maxBC       float64
+	maxDC       float64
+	// memoryMargin and oomBumpRatio tune the memory recommendation
+	memoryMargin float64
+	oomBumpRatio float64
+	// kp, ki, kd and derivativeFilter tune the PID controller
+	kp               float64
+	ki               float64
+	kd               float64
+	derivativeFilter float64
+}
+
+// newControlParameters converts the agreement controller parameters, filling the missing ones with the default values
+func newControlParameters(parameters *v1beta1.ControllerParameters) controlParameters {
+	p := parameters.WithDefaults()
+	return controlParameters{
+		maxScaleOut:      toFloat(p.MaxScaleOut),
+		minCPU:           float64(p.MinCPU.MilliValue()),
+		BC:               toFloat(p.BC),
+		DC:               toFloat(p.DC),
+		minError:         toFloat(p.MinError),
+		maxError:         toFloat(p.MaxError),
+		minBC:            toFloat(p.MinBC),
+		minDC:            toFloat(p.MinDC),
+		maxBC:            toFloat(p.MaxBC),
+		maxDC:            toFloat(p.MaxDC),
+		memoryMargin:     toFloat(p.MemoryMargin),
+		oomBumpRatio:     toFloat(p.OOMBumpRatio),
+		kp:               toFloat(p.KP),
+		ki:               toFloat(p.KI),
+		kd:               toFloat(p.KD),
+		derivativeFilter: toFloat(p.DerivativeFilter),
+	}
+}
+
+func toFloat(q *resource.Quantity) float64 {
+	return float64(q.MilliValue()) / 1000
+}
+
+func toQuantity(value float64) *resource.Quantity {
+	return resource.NewMilliQuantity(int64(math.Round(value*1000)), resource.DecimalSI)
+}
+
+// restore sets the value to the checkpointed quantity, if any
+func restore(value *float64, q *resource.Quantity) {
+	if q != nil {
+		*value = toFloat(q)
+	}
+}
+
+// restoreCPU sets the value in millicores to the checkpointed CPU, if any
+func restoreCPU(value *float64, q *resource.Quantity) {
+	if q != nil {
+		*value = float64(q.MilliValue())
+	}
+}
 
 // computePodScale computes a new pod scale for a given pod.
-// It also requires the old pod scale, the service level agreement and the pod metrics.
-func (logic *FixedGainControlLogic) computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, metric *metricsv1beta2.MetricValue) (*v1beta1.PodScale, error) {
+// It also requires the old pod scale, the service level agreement, the pod metrics and the container working set, if available.
+func (logic *FixedGainControlLogic) computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue, workingSet *resource.Quantity) (*v1beta1.PodScale, error) {
+	return recommendPodScale(pod, podScale, sla, podMetrics, workingSet, logic.computeCPUResource, logic.memory, logic.checkpoint)
+}
+
+// cpuRecommendation computes the cpu resources recommended for the container by a logic
+type cpuRecommendation func(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue) (*resource.Quantity, error)
+
+// recommendPodScale computes a new pod scale with the cpu recommended by computeCPU and the memory
+// recommended by the memory logic, both bounded by the agreement. The state returned by checkpoint
+// after the recommendation is stored in the new pod scale.
+func recommendPodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue, workingSet *resource.Quantity, computeCPU cpuRecommendation, memory *memoryLogic, checkpoint func() *v1beta1.ControllerState) (*v1beta1.PodScale, error) {
 
 	container, err := ContainerToScale(*pod, sla.Spec.Service.Container)
 
@@ -59,8 +149,13 @@ func (logic *FixedGainControlLogic) computePodScale(pod *v1.Pod, podScale *v1bet
 	}
 
 	// Compute the cpu and memory value for the pod
-	desiredCPU := logic.computeCPUResource(container, podScale, sla, metric)
-	desiredMemory := logic.computeMemoryResource(container, podScale, sla, metric)
+	desiredCPU, err := computeCPU(container, podScale, sla, podMetrics)
+
+	if err != nil {
+		return nil, err
+	}
+
+	desiredMemory := memory.computeMemoryResource(pod, container, podScale, workingSet)
 
 	desiredResources := make(v1.ResourceList)
 	desiredResources[v1.ResourceCPU] = *desiredCPU
@@ -76,55 +171,40 @@ func (logic *FixedGainControlLogic) computePodScale(pod *v1.Pod, podScale *v1bet
 	newPodScale := podScale.DeepCopy()
 	newPodScale.Spec.DesiredResources = desiredResources
 	newPodScale.Status.CappedResources = cappedResources
+	newPodScale.Status.CappedLimits = computeLimits(sla, cappedResources)
+	newPodScale.Status.ControllerState = checkpoint()
 
 	return newPodScale, nil
 }
 
-// computeMemoryResource computes memory resources for a given pod.
-func (logic *FixedGainControlLogic) computeMemoryResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, metric *metricsv1beta2.MetricValue) *resource.Quantity {
-
-	// Retrieve the value of actual and desired cpu resources
-	// TODO: maybe can be deleted
-	desiredResource := podScale.Spec.DesiredResources.Memory()
-	//actualResource := podScale.Status.ActualResources.Memory()
-
-	// Compute the new desired value
-	newDesiredResource := resource.NewMilliQuantity(desiredResource.MilliValue(), resource.BinarySI)
-
-	// For logging purpose
-	//klog.Info("Computing memory resource for Pod: ", pod.GetName(), ", actual value: ", actualResource, ", desired value: ", desiredResource, ", new value: ", newDesiredResource)
-
-	return newDesiredResource
-}
-
-// computeMemoryResource computes memory resources for a given pod.
-func (logic *FixedGainControlLogic) computeCPUResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, metric *metricsv1beta2.MetricValue) *resource.Quantity {
+// computeCPUResource computes cpu resources for a given pod.
+func (logic *FixedGainControlLogic) computeCPUResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue) (*resource.Quantity, error) {
 
 	actualCpu := podScale.Status.ActualResources.Cpu().MilliValue()
 	logic.cores = float64(actualCpu)
-	logic.xcprec = logic.cores - BC*logic.prevError
+	logic.xcprec = logic.cores - logic.params.BC*logic.prevError
 
-	responseTime := float64(metric.Value.MilliValue()) / 1000
-	// The response time is in seconds
-	setPoint := float64(sla.Spec.Metric.ResponseTime.MilliValue()) / 1000
-	e := 1/setPoint - 1/responseTime
-	e = math.Min(math.Max(e, minError), maxError)
+	e, err := computeError(sla, podMetrics)
+	if err != nil {
+		return nil, err
+	}
+	e = math.Min(math.Max(e, logic.params.minError), logic.params.maxError)
 	logic.prevError = e
-	xc := float64(logic.xcprec + BC*e)
+	xc := float64(logic.xcprec + logic.params.BC*e)
 	oldcores := logic.cores
-	cores := math.Min(math.Max(minCPU, xc+DC*e), oldcores*maxScaleOut)
+	cores := math.Min(math.Max(logic.params.minCPU, xc+logic.params.DC*e), oldcores*logic.params.maxScaleOut)
 
 	newDesiredResource := resource.NewMilliQuantity(int64(cores), resource.BinarySI)
 
 	klog.Info("xc is: ", xc, ", e is: ", e, ", xcprex is: ", logic.xcprec)
 
 	// For logging purpose
-	klog.Info("BC: ", BC, ", DC: ", DC)
-	klog.Info("response time is: ", responseTime, ", set point is: ", setPoint, " and error is: ", e)
+	klog.Info("BC: ", logic.params.BC, ", DC: ", logic.params.DC)
+	klog.Info("error is: ", e)
 	klog.Info("xc is: ", xc, ", cores is: ", cores, ", xcprex is: ", logic.xcprec)
 	//klog.Info("Computing CPU resource for Pod: ", pod.GetName(), ", actual value: ", actualResource, ", desired value: ", desiredResource, ", new value: ", newDesiredResource)
 
-	return newDesiredResource
+	return newDesiredResource, nil
 }
 
 // ContainerToScale returns the desired container from the given pod
@@ -138,6 +218,80 @@ func ContainerToScale(pod v1.Pod, container string) (v1.Container, error) {
 	return v1.Container{}, fmt.Errorf("the container %s does not exists within the pod %s", container, pod.Name)
 }
 
+// computeError returns the control error given the requirements set in the agreement.
+// Positive values mean that the pod needs more resources. The response time error is
+// the difference between the inverse of the set point and the inverse of the response time,
+// while the other requirements contribute with their relative violation.
+// When more requirements are set, the most violated one is followed. The requirements that
+// cannot be observed, like the throughput of a service without traffic, are skipped and the
+// error is zero when none is left.
+func computeError(sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue) (float64, error) {
+	requirements := metrics.Requirements(sla.Spec.Metric)
+
+	if len(requirements) == 0 {
+		return 0, fmt.Errorf("the service level agreement %s does not set any metric requirement", sla.Name)
+	}
+
+	e := math.Inf(-1)
+	observed := false
+	for _, requirement := range requirements {
+		metric, ok := podMetrics[requirement.Metric]
+		if !ok && !requirement.LowerBound {
+			return 0, fmt.Errorf("missing %s metric required by service level agreement %s", requirement.Metric, sla.Name)
+		}
+
+		if !ok || !requirement.Observed(metric.Value) {
+			klog.V(2).Info(requirement.Metric, " is not observed, the requirement is skipped")
+			continue
+		}
+		observed = true
+
+		var requirementError float64
+		if requirement.Metric.IsResponseTime() {
+			// The response time is in seconds
+			responseTime := float64(metric.Value.MilliValue()) / 1000
+			setPoint := float64(requirement.Target.MilliValue()) / 1000
+			requirementError = 1/setPoint - 1/responseTime
+		} else {
+			requirementError = requirement.Violation(metric.Value)
+		}
+
+		klog.Info(requirement.Metric, " is: ", metric.Value.String(), ", set point is: ", requirement.Target.String(), " and error is: ", requirementError)
+		e = math.Max(e, requirementError)
+	}
+
+	if !observed {
+		return 0, nil
+	}
+
+	return e, nil
+}
+
+// computeLimits returns the limits of the Burstable pods, which are the requests scaled by the burst ratio
+// without exceeding the upper bounds of the agreement. Guaranteed pods do not have separate limits.
+func computeLimits(sla *v1beta1.ServiceLevelAgreement, requests v1.ResourceList) v1.ResourceList {
+	if !sla.Spec.IsBurstable() {
+		return nil
+	}
+
+	burstRatio := sla.Spec.BurstRatioOrDefault()
+	ratio := toFloat(&burstRatio)
+
+	limits := make(v1.ResourceList)
+	for name, request := range requests {
+		limit := resource.NewMilliQuantity(int64(float64(request.MilliValue())*ratio), request.Format)
+		if max, ok := sla.Spec.MaxResources[name]; ok && limit.Cmp(max) > 0 {
+			limit = &max
+		}
+		if limit.Cmp(request) < 0 {
+			limit = &request
+		}
+		limits[name] = limit.DeepCopy()
+	}
+
+	return limits
+}
+
 func applyBounds(value *resource.Quantity, min *resource.Quantity, max *resource.Quantity, checkLower bool, checkUpper bool) (*resource.Quantity, bool) {
 	if checkUpper && value.MilliValue() > max.MilliValue() {
 		return max, true
@@ -155,92 +309,77 @@ type AdaptiveGainControlLogic struct {
 	prevError float64
 	bc        float64
 	dc        float64
+	params    controlParameters
+	memory    *memoryLogic
 }
 
-// newAdaptiveGainControlLogic returns a new adaptive gain feedback controller
-func newAdaptiveGainControlLogic(podScale *v1beta1.PodScale) *AdaptiveGainControlLogic {
-	return &AdaptiveGainControlLogic{
+// newAdaptiveGainControlLogic returns a new adaptive gain feedback controller tuned with the agreement controller parameters.
+// The controller state is restored from the PodScale, if it has been checkpointed.
+func newAdaptiveGainControlLogic(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) *AdaptiveGainControlLogic {
+	params := newControlParameters(sla.Spec.ControllerParameters)
+	logic := &AdaptiveGainControlLogic{
 		xcprec:    float64(podScale.Status.ActualResources.Cpu().MilliValue()),
 		cores:     float64(podScale.Status.ActualResources.Cpu().MilliValue()),
 		prevError: 0.0,
-		bc:        BC,
-		dc:        DC,
+		bc:        params.BC,
+		dc:        params.DC,
+		params:    params,
+		memory:    newMemoryLogic(podScale, params),
 	}
-}
-
-// computePodScale computes a new pod scale for a given pod.
-// It also requires the old pod scale, the service level agreement and the pod metrics.
-func (logic *AdaptiveGainControlLogic) computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, metric *metricsv1beta2.MetricValue) (*v1beta1.PodScale, error) {
 
-	container, err := ContainerToScale(*pod, sla.Spec.Service.Container)
-
-	if err != nil {
-		klog.Info(err)
-		return nil, err
+	if state := podScale.Status.ControllerState; state != nil {
+		restoreCPU(&logic.xcprec, state.XCPrec)
+		restoreCPU(&logic.cores, state.Cores)
+		restore(&logic.prevError, state.PrevError)
+		restore(&logic.bc, state.BC)
+		restore(&logic.dc, state.DC)
 	}
 
-	// Compute the cpu and memory value for the pod
-	desiredCPU := logic.computeCPUResource(container, podScale, sla, metric)
-	desiredMemory := logic.computeMemoryResource(container, podScale, sla, metric)
-
-	desiredResources := make(v1.ResourceList)
-	desiredResources[v1.ResourceCPU] = *desiredCPU
-	desiredResources[v1.ResourceMemory] = *desiredMemory
-
-	cappedResources := make(v1.ResourceList)
-	cappedCPU, _ := applyBounds(desiredCPU, sla.Spec.MinResources.Cpu(), sla.Spec.MaxResources.Cpu(), sla.Spec.MinResources != nil, sla.Spec.MaxResources != nil)
-	cappedMemory, _ := applyBounds(desiredMemory, sla.Spec.MinResources.Memory(), sla.Spec.MaxResources.Memory(), sla.Spec.MinResources != nil, sla.Spec.MaxResources != nil)
-	cappedResources[v1.ResourceCPU] = *cappedCPU
-	cappedResources[v1.ResourceMemory] = *cappedMemory
-
-	// Copy the current PodScale and edit the desired value
-	newPodScale := podScale.DeepCopy()
-	newPodScale.Spec.DesiredResources = desiredResources
-	newPodScale.Status.CappedResources = cappedResources
-
-	return newPodScale, nil
+	return logic
 }
 
-// computeMemoryResource computes memory resources for a given pod.
-func (logic *AdaptiveGainControlLogic) computeMemoryResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, metric *metricsv1beta2.MetricValue) *resource.Quantity {
-
-	// Retrieve the value of actual and desired cpu resources
-	// TODO: maybe can be deleted
-	desiredResource := podScale.Spec.DesiredResources.Memory()
-	//actualResource := podScale.Status.ActualResources.Memory()
-
-	// Compute the new desired value
-	newDesiredResource := resource.NewMilliQuantity(desiredResource.MilliValue(), resource.BinarySI)
-
-	// For logging purpose
-	//klog.Info("Computing memory resource for Pod: ", pod.GetName(), ", actual value: ", actualResource, ", desired value: ", desiredResource, ", new value: ", newDesiredResource)
+// checkpoint returns the current state of the controller
+func (logic *AdaptiveGainControlLogic) checkpoint() *v1beta1.ControllerState {
+	state := &v1beta1.ControllerState{
+		XCPrec:    resource.NewMilliQuantity(int64(logic.xcprec), resource.DecimalSI),
+		Cores:     resource.NewMilliQuantity(int64(logic.cores), resource.DecimalSI),
+		PrevError: toQuantity(logic.prevError),
+		BC:        toQuantity(logic.bc),
+		DC:        toQuantity(logic.dc),
+	}
+	logic.memory.checkpoint(state)
+	return state
+}
 
-	return newDesiredResource
+// computePodScale computes a new pod scale for a given pod.
+// It also requires the old pod scale, the service level agreement, the pod metrics and the container working set, if available.
+func (logic *AdaptiveGainControlLogic) computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue, workingSet *resource.Quantity) (*v1beta1.PodScale, error) {
+	return recommendPodScale(pod, podScale, sla, podMetrics, workingSet, logic.computeCPUResource, logic.memory, logic.checkpoint)
 }
 
-// computeMemoryResource computes memory resources for a given pod.
-func (logic *AdaptiveGainControlLogic) computeCPUResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, metric *metricsv1beta2.MetricValue) *resource.Quantity {
+// computeCPUResource computes cpu resources for a given pod.
+func (logic *AdaptiveGainControlLogic) computeCPUResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue) (*resource.Quantity, error) {
 
 	actualCpu := podScale.Status.ActualResources.Cpu().MilliValue()
 	logic.cores = float64(actualCpu)
 	logic.xcprec = logic.cores - logic.bc*logic.prevError
 
-	responseTime := float64(metric.Value.MilliValue()) / 1000
-	// The response time is in seconds
-	setPoint := float64(sla.Spec.Metric.ResponseTime.MilliValue()) / 1000
-	e := 1/setPoint - 1/responseTime
-	e = math.Min(math.Max(e, minError), maxError)
+	e, err := computeError(sla, podMetrics)
+	if err != nil {
+		return nil, err
+	}
+	e = math.Min(math.Max(e, logic.params.minError), logic.params.maxError)
 	logic.prevError = e
 	xc := float64(logic.xcprec + logic.bc*e)
 	oldcores := logic.cores
-	cores := math.Min(math.Max(minCPU, xc+logic.dc*e), oldcores*maxScaleOut)
+	cores := math.Min(math.Max(logic.params.minCPU, xc+logic.dc*e), oldcores*logic.params.maxScaleOut)
 
 	// Adapt the gains
-	logic.bc = math.Min(math.Max(logic.bc*math.Sqrt(math.Abs(e)*2), minBC), maxBC)
-	logic.dc = math.Min(math.Max(logic.bc*DC/BC, minDC), maxDC)
+	logic.bc = math.Min(math.Max(logic.bc*math.Sqrt(math.Abs(e)*2), logic.params.minBC), logic.params.maxBC)
+	logic.dc = math.Min(math.Max(logic.bc*logic.params.DC/logic.params.BC, logic.params.minDC), logic.params.maxDC)
 
 	newDesiredResource := resource.NewMilliQuantity(int64(cores), resource.BinarySI)
 	klog.Infof("error is  %v,  bc is %v, dc is %v", e, logic.bc, logic.dc)
-	klog.Infof("response_time is %v, old cores are %v, new cores are %v", responseTime, oldcores, cores)
-	return newDesiredResource
+	klog.Infof("old cores are %v, new cores are %v", oldcores, cores)
+	return newDesiredResource, nil
 }
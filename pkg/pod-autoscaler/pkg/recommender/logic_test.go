@@ -3,10 +3,12 @@ package recommender
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"k8s.io/klog/v2"
 
 	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
 	"github.com/stretchr/testify/require"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -104,17 +106,16 @@ func TestControlTheoryLogic(t *testing.T) {
 				},
 			}
 
-			metricsMap := metricsv1beta2.MetricValue{
-				Value: *resource.NewQuantity(int64(tt.currentResponseTime), resource.BinarySI),
+			metricsMap := map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime: {
+					Value: *resource.NewQuantity(int64(tt.currentResponseTime), resource.BinarySI),
+				},
 			}
 
-			fixedGainControl := FixedGainControlLogic{
-				xcprec: float64(tt.lowerBound+tt.upperBound) / 2,
-				cores:  float64(tt.lowerBound+tt.upperBound) / 2,
-			}
+			fixedGainControl := newFixedGainControlLogic(podScale, sla)
 
 			for i := 0; i < 200; i++ {
-				podScale, err := fixedGainControl.computePodScale(pod, podScale, sla, &metricsMap)
+				podScale, err := fixedGainControl.computePodScale(pod, podScale, sla, metricsMap, nil)
 				require.Nil(t, err)
 				require.GreaterOrEqual(t, podScale.Status.CappedResources.Cpu().MilliValue(), tt.lowerBound)
 				x, _ := json.Marshal(podScale)
@@ -122,13 +123,10 @@ func TestControlTheoryLogic(t *testing.T) {
 				require.LessOrEqual(t, podScale.Status.CappedResources.Cpu().MilliValue(), tt.upperBound)
 			}
 
-			adaptiveGainControl := AdaptiveGainControlLogic{
-				xcprec: float64(tt.lowerBound+tt.upperBound) / 2,
-				cores:  float64(tt.lowerBound+tt.upperBound) / 2,
-			}
+			adaptiveGainControl := newAdaptiveGainControlLogic(podScale, sla)
 
 			for i := 0; i < 200; i++ {
-				podScale, err := adaptiveGainControl.computePodScale(pod, podScale, sla, &metricsMap)
+				podScale, err := adaptiveGainControl.computePodScale(pod, podScale, sla, metricsMap, nil)
 				require.Nil(t, err)
 				require.GreaterOrEqual(t, podScale.Status.CappedResources.Cpu().MilliValue(), tt.lowerBound)
 				x, _ := json.Marshal(podScale)
@@ -136,6 +134,149 @@ func TestControlTheoryLogic(t *testing.T) {
 				require.LessOrEqual(t, podScale.Status.CappedResources.Cpu().MilliValue(), tt.upperBound)
 			}
 
+			pidControl := newPIDControlLogic(podScale, sla)
+
+			for i := 0; i < 200; i++ {
+				podScale, err := pidControl.computePodScale(pod, podScale, sla, metricsMap, nil)
+				require.Nil(t, err)
+				require.GreaterOrEqual(t, podScale.Status.CappedResources.Cpu().MilliValue(), tt.lowerBound)
+				require.LessOrEqual(t, podScale.Status.CappedResources.Cpu().MilliValue(), tt.upperBound)
+			}
+
+		})
+	}
+}
+
+func TestComputeError(t *testing.T) {
+
+	testcases := []struct {
+		description string
+		requirement v1beta1.MetricRequirement
+		metrics     map[metrics.MetricType]*metricsv1beta2.MetricValue
+		expected    float64
+		error       bool
+	}{
+		{
+			description: "should use the response time error",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime: {Value: *resource.NewMilliQuantity(200, resource.BinarySI)},
+			},
+			expected: 5,
+		},
+		{
+			description: "should track the response time percentile instead of the mean",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime:           *resource.NewMilliQuantity(100, resource.BinarySI),
+				ResponseTimePercentile: v1beta1.P99,
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime:    {Value: *resource.NewMilliQuantity(50, resource.BinarySI)},
+				metrics.ResponseTimeP99: {Value: *resource.NewMilliQuantity(200, resource.BinarySI)},
+			},
+			expected: 5,
+		},
+		{
+			description: "should use the relative violation of the error rate",
+			requirement: v1beta1.MetricRequirement{
+				ErrorRate: resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ErrorRate: {Value: *resource.NewMilliQuantity(300, resource.BinarySI)},
+			},
+			expected: 2,
+		},
+		{
+			description: "should not release the cpu while the error rate is honored",
+			requirement: v1beta1.MetricRequirement{
+				ErrorRate: resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ErrorRate: {Value: *resource.NewQuantity(0, resource.BinarySI)},
+			},
+			expected: 0,
+		},
+		{
+			description: "should follow the most violated requirement",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime:   *resource.NewMilliQuantity(100, resource.BinarySI),
+				Throughput:     resource.NewQuantity(10, resource.BinarySI),
+				CPUUtilization: resource.NewMilliQuantity(500, resource.BinarySI),
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime:   {Value: *resource.NewMilliQuantity(50, resource.BinarySI)},
+				metrics.Throughput:     {Value: *resource.NewQuantity(5, resource.BinarySI)},
+				metrics.CPUUtilization: {Value: *resource.NewMilliQuantity(250, resource.BinarySI)},
+			},
+			expected: 1,
+		},
+		{
+			description: "should compare the response time error with the relative violations",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.BinarySI),
+				ErrorRate:    resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime: {Value: *resource.NewMilliQuantity(125, resource.BinarySI)},
+				metrics.ErrorRate:    {Value: *resource.NewMilliQuantity(400, resource.BinarySI)},
+			},
+			expected: 3,
+		},
+		{
+			description: "should skip the throughput of a service without traffic",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.BinarySI),
+				Throughput:   resource.NewQuantity(10, resource.BinarySI),
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime: {Value: *resource.NewMilliQuantity(50, resource.BinarySI)},
+				metrics.Throughput:   {Value: *resource.NewQuantity(0, resource.BinarySI)},
+			},
+			expected: -10,
+		},
+		{
+			description: "should not violate a throughput requirement without traffic",
+			requirement: v1beta1.MetricRequirement{
+				Throughput: resource.NewQuantity(10, resource.BinarySI),
+			},
+			metrics:  map[metrics.MetricType]*metricsv1beta2.MetricValue{},
+			expected: 0,
+		},
+		{
+			description: "should fail when a required metric is missing",
+			requirement: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.BinarySI),
+				ErrorRate:    resource.NewMilliQuantity(100, resource.BinarySI),
+			},
+			metrics: map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime: {Value: *resource.NewMilliQuantity(50, resource.BinarySI)},
+			},
+			error: true,
+		},
+		{
+			description: "should fail when no requirement is set",
+			requirement: v1beta1.MetricRequirement{},
+			metrics:     map[metrics.MetricType]*metricsv1beta2.MetricValue{},
+			error:       true,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			sla := &v1beta1.ServiceLevelAgreement{
+				Spec: v1beta1.ServiceLevelAgreementSpec{
+					Metric: tt.requirement,
+				},
+			}
+			actual, err := computeError(sla, tt.metrics)
+			if tt.error {
+				require.Error(t, err)
+			} else {
+				require.Nil(t, err)
+				require.InDelta(t, tt.expected, actual, 0.001)
+			}
 		})
 	}
 }
@@ -183,3 +324,170 @@ func TestBounds(t *testing.T) {
 		}
 	}
 }
+
+func TestNewControlParameters(t *testing.T) {
+
+	testcases := []struct {
+		description string
+		parameters  *v1beta1.ControllerParameters
+		expected    controlParameters
+	}{
+		{
+			description: "should use the default parameters",
+			parameters:  nil,
+			expected: controlParameters{
+				maxScaleOut:      1.5,
+				minCPU:           5,
+				BC:               40,
+				DC:               80,
+				minError:         -10,
+				maxError:         10,
+				minBC:            10,
+				minDC:            15,
+				maxBC:            100,
+				maxDC:            150,
+				memoryMargin:     0.2,
+				oomBumpRatio:     1.5,
+				kp:               80,
+				ki:               40,
+				kd:               20,
+				derivativeFilter: 0.5,
+			},
+		},
+		{
+			description: "should override only the parameters set in the agreement",
+			parameters: &v1beta1.ControllerParameters{
+				BC:           resource.NewQuantity(20, resource.DecimalSI),
+				MaxScaleOut:  resource.NewMilliQuantity(2500, resource.DecimalSI),
+				MinCPU:       resource.NewMilliQuantity(100, resource.DecimalSI),
+				MemoryMargin: resource.NewMilliQuantity(100, resource.DecimalSI),
+			},
+			expected: controlParameters{
+				maxScaleOut:      2.5,
+				minCPU:           100,
+				BC:               20,
+				DC:               80,
+				minError:         -10,
+				maxError:         10,
+				minBC:            10,
+				minDC:            15,
+				maxBC:            100,
+				maxDC:            150,
+				memoryMargin:     0.1,
+				oomBumpRatio:     1.5,
+				kp:               80,
+				ki:               40,
+				kd:               20,
+				derivativeFilter: 0.5,
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			require.Equal(t, tt.expected, newControlParameters(tt.parameters))
+		})
+	}
+}
+
+func TestControllerStateRestore(t *testing.T) {
+
+	sla := &v1beta1.ServiceLevelAgreement{}
+	podScale := &v1beta1.PodScale{
+		Status: v1beta1.PodScaleStatus{
+			ActualResources: map[corev1.ResourceName]resource.Quantity{
+				corev1.ResourceCPU: *resource.NewMilliQuantity(500, resource.BinarySI),
+			},
+		},
+	}
+
+	t.Run("should start from the actual resources without a checkpoint", func(t *testing.T) {
+		logic := newAdaptiveGainControlLogic(podScale, sla)
+		require.Equal(t, float64(500), logic.xcprec)
+		require.Equal(t, float64(500), logic.cores)
+		require.Equal(t, float64(0), logic.prevError)
+		require.Equal(t, float64(40), logic.bc)
+		require.Equal(t, float64(80), logic.dc)
+	})
+
+	t.Run("should restore the checkpointed state", func(t *testing.T) {
+		logic := &AdaptiveGainControlLogic{
+			xcprec:    420,
+			cores:     480,
+			prevError: -1.25,
+			bc:        12.5,
+			dc:        25,
+			memory: &memoryLogic{
+				lastOOM:   metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)),
+				oomMemory: 300,
+			},
+		}
+
+		restoredPodScale := podScale.DeepCopy()
+		restoredPodScale.Status.ControllerState = logic.checkpoint()
+
+		restored := newAdaptiveGainControlLogic(restoredPodScale, sla)
+		require.Equal(t, logic.xcprec, restored.xcprec)
+		require.Equal(t, logic.cores, restored.cores)
+		require.Equal(t, logic.prevError, restored.prevError)
+		require.Equal(t, logic.bc, restored.bc)
+		require.Equal(t, logic.dc, restored.dc)
+		require.True(t, logic.memory.lastOOM.Equal(&restored.memory.lastOOM))
+		require.Equal(t, logic.memory.oomMemory, restored.memory.oomMemory)
+
+		fixed := newFixedGainControlLogic(restoredPodScale, sla)
+		require.Equal(t, logic.prevError, fixed.prevError)
+	})
+}
+
+func TestComputeLimits(t *testing.T) {
+	requests := corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(400, resource.BinarySI),
+		corev1.ResourceMemory: *resource.NewQuantity(100, resource.BinarySI),
+	}
+
+	testcases := []struct {
+		description string
+		sla         v1beta1.ServiceLevelAgreementSpec
+		expected    corev1.ResourceList
+	}{
+		{
+			description: "should not compute limits for guaranteed pods",
+			sla:         v1beta1.ServiceLevelAgreementSpec{QOSClass: corev1.PodQOSGuaranteed},
+			expected:    nil,
+		},
+		{
+			description: "should scale the requests by the default burst ratio",
+			sla:         v1beta1.ServiceLevelAgreementSpec{QOSClass: corev1.PodQOSBurstable},
+			expected: corev1.ResourceList{
+				corev1.ResourceCPU:    *resource.NewMilliQuantity(800, resource.BinarySI),
+				corev1.ResourceMemory: *resource.NewQuantity(200, resource.BinarySI),
+			},
+		},
+		{
+			description: "should not exceed the upper bounds",
+			sla: v1beta1.ServiceLevelAgreementSpec{
+				QOSClass:   corev1.PodQOSBurstable,
+				BurstRatio: resource.NewMilliQuantity(1500, resource.DecimalSI),
+				MaxResources: corev1.ResourceList{
+					corev1.ResourceCPU: *resource.NewMilliQuantity(500, resource.BinarySI),
+				},
+			},
+			expected: corev1.ResourceList{
+				corev1.ResourceCPU:    *resource.NewMilliQuantity(500, resource.BinarySI),
+				corev1.ResourceMemory: *resource.NewQuantity(150, resource.BinarySI),
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			limits := computeLimits(&v1beta1.ServiceLevelAgreement{Spec: tt.sla}, requests)
+			require.Equal(t, len(tt.expected), len(limits))
+			for name, expected := range tt.expected {
+				limit := limits[name]
+				require.Equal(t, 0, limit.Cmp(expected), "%s limit is %s", name, limit.String())
+			}
+		})
+	}
+}
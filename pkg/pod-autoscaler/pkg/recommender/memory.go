@@ -0,0 +1,100 @@
+package recommender
+
+import (
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// oomKilledReason is the termination reason of the containers killed because out of memory
+const oomKilledReason = "OOMKilled"
+
+// memoryLogic recommends the memory of a container from its working set.
+// The recommendation follows the working set plus a safety margin, so it never
+// shrinks below the memory in use. When the container is killed because out of
+// memory, the memory it had is bumped immediately and kept as lower bound, since
+// the working set observed after the restart does not reflect the memory it needs.
+// Memory values are expressed in bytes.
+type memoryLogic struct {
+	margin    float64
+	oomBump   float64
+	lastOOM   metav1.Time
+	oomMemory int64
+}
+
+// newMemoryLogic returns a new memory logic tuned with the controller parameters.
+// The out of memory state is restored from the PodScale, if it has been checkpointed.
+func newMemoryLogic(podScale *v1beta1.PodScale, params controlParameters) *memoryLogic {
+	logic := &memoryLogic{
+		margin:  params.memoryMargin,
+		oomBump: params.oomBumpRatio,
+	}
+
+	if state := podScale.Status.ControllerState; state != nil {
+		if state.LastOOM != nil {
+			logic.lastOOM = *state.LastOOM.DeepCopy()
+		}
+		if state.OOMMemory != nil {
+			logic.oomMemory = state.OOMMemory.Value()
+		}
+	}
+
+	return logic
+}
+
+// checkpoint adds the out of memory state to the controller state
+func (logic *memoryLogic) checkpoint(state *v1beta1.ControllerState) {
+	if logic.lastOOM.IsZero() {
+		return
+	}
+	state.LastOOM = logic.lastOOM.DeepCopy()
+	state.OOMMemory = resource.NewQuantity(logic.oomMemory, resource.BinarySI)
+}
+
+// computeMemoryResource computes memory resources for a given container.
+// If the working set is not available, the desired memory is left unchanged.
+func (logic *memoryLogic) computeMemoryResource(pod *v1.Pod, container v1.Container, podScale *v1beta1.PodScale, workingSet *resource.Quantity) *resource.Quantity {
+	actual := podScale.Status.ActualResources.Memory().Value()
+	if actual == 0 {
+		actual = container.Resources.Requests.Memory().Value()
+	}
+
+	if killedAt, ok := lastOOMKill(pod, container.Name); ok && killedAt.After(logic.lastOOM.Time) {
+		logic.lastOOM = killedAt
+		logic.oomMemory = maxInt64(logic.oomMemory, int64(float64(actual)*logic.oomBump))
+		klog.Info("Container ", container.Name, " of pod ", pod.GetName(), " has been killed because out of memory, bumping memory to ", logic.oomMemory)
+	}
+
+	var desired int64
+	if workingSet != nil {
+		desired = int64(float64(workingSet.Value()) * (1 + logic.margin))
+		klog.V(4).Info("working set is: ", workingSet.String(), ", desired memory is: ", desired)
+	} else {
+		desired = podScale.Spec.DesiredResources.Memory().Value()
+	}
+
+	return resource.NewQuantity(maxInt64(desired, logic.oomMemory), resource.BinarySI)
+}
+
+// lastOOMKill returns the time the container was last killed because out of memory, if any
+func lastOOMKill(pod *v1.Pod, container string) (metav1.Time, bool) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != container {
+			continue
+		}
+		terminated := status.LastTerminationState.Terminated
+		if terminated != nil && terminated.Reason == oomKilledReason {
+			return terminated.FinishedAt, true
+		}
+	}
+	return metav1.Time{}, false
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,93 @@
+package recommender
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestComputeMemoryResource(t *testing.T) {
+	killedAt := metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	oomKilled := []corev1.ContainerStatus{
+		{
+			Name: "container",
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: oomKilledReason, FinishedAt: killedAt},
+			},
+		},
+	}
+
+	testcases := []struct {
+		description string
+		statuses    []corev1.ContainerStatus
+		workingSet  *resource.Quantity
+		state       *v1beta1.ControllerState
+		expected    int64
+	}{
+		{
+			description: "should follow the working set plus the margin",
+			workingSet:  resource.NewQuantity(50, resource.BinarySI),
+			expected:    60,
+		},
+		{
+			description: "should keep the desired memory without working set",
+			workingSet:  nil,
+			expected:    200,
+		},
+		{
+			description: "should bump the actual memory after an out of memory kill",
+			statuses:    oomKilled,
+			workingSet:  resource.NewQuantity(10, resource.BinarySI),
+			expected:    150,
+		},
+		{
+			description: "should follow the working set when it exceeds the bumped memory",
+			statuses:    oomKilled,
+			workingSet:  resource.NewQuantity(200, resource.BinarySI),
+			expected:    240,
+		},
+		{
+			description: "should not bump twice for the same out of memory kill",
+			statuses:    oomKilled,
+			workingSet:  resource.NewQuantity(10, resource.BinarySI),
+			state: &v1beta1.ControllerState{
+				LastOOM:   &killedAt,
+				OOMMemory: resource.NewQuantity(120, resource.BinarySI),
+			},
+			expected: 120,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod"},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "container"}}},
+				Status:     corev1.PodStatus{ContainerStatuses: tt.statuses},
+			}
+			podScale := &v1beta1.PodScale{
+				Spec: v1beta1.PodScaleSpec{
+					DesiredResources: corev1.ResourceList{corev1.ResourceMemory: *resource.NewQuantity(200, resource.BinarySI)},
+				},
+				Status: v1beta1.PodScaleStatus{
+					ActualResources: corev1.ResourceList{corev1.ResourceMemory: *resource.NewQuantity(100, resource.BinarySI)},
+					ControllerState: tt.state,
+				},
+			}
+
+			logic := newMemoryLogic(podScale, newControlParameters(nil))
+			memory := logic.computeMemoryResource(pod, pod.Spec.Containers[0], podScale, tt.workingSet)
+			require.Equal(t, tt.expected, memory.Value())
+
+			state := &v1beta1.ControllerState{}
+			logic.checkpoint(state)
+			require.Equal(t, tt.statuses != nil, state.LastOOM != nil)
+		})
+	}
+}
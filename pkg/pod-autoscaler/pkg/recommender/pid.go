@@ -0,0 +1,109 @@
+package recommender
+
+import (
+	"math"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+// PIDControlLogic is the logic that applies a discrete PID controller to recommend new resources.
+// The derivative term is smoothed by a first order low pass filter. To prevent the integral
+// windup, the integral term tracks the CPU actually assigned to the container, which can differ
+// from the recommended one when the Contention Manager squeezes it, and it is frozen while
+// the output is saturated by the bounds.
+type PIDControlLogic struct {
+	integral   float64
+	cores      float64
+	prevError  float64
+	derivative float64
+	params     controlParameters
+	memory     *memoryLogic
+}
+
+// newPIDControlLogic returns a new PID controller tuned with the agreement controller parameters.
+// The controller state is restored from the PodScale, if it has been checkpointed.
+func newPIDControlLogic(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) *PIDControlLogic {
+	params := newControlParameters(sla.Spec.ControllerParameters)
+	logic := &PIDControlLogic{
+		integral:   float64(podScale.Status.ActualResources.Cpu().MilliValue()),
+		cores:      float64(podScale.Status.ActualResources.Cpu().MilliValue()),
+		prevError:  0.0,
+		derivative: 0.0,
+		params:     params,
+		memory:     newMemoryLogic(podScale, params),
+	}
+
+	if state := podScale.Status.ControllerState; state != nil {
+		restoreCPU(&logic.integral, state.XCPrec)
+		restoreCPU(&logic.cores, state.Cores)
+		restore(&logic.prevError, state.PrevError)
+		restore(&logic.derivative, state.Derivative)
+	}
+
+	return logic
+}
+
+// checkpoint returns the current state of the controller
+func (logic *PIDControlLogic) checkpoint() *v1beta1.ControllerState {
+	state := &v1beta1.ControllerState{
+		XCPrec:     resource.NewMilliQuantity(int64(logic.integral), resource.DecimalSI),
+		Cores:      resource.NewMilliQuantity(int64(logic.cores), resource.DecimalSI),
+		PrevError:  toQuantity(logic.prevError),
+		Derivative: toQuantity(logic.derivative),
+	}
+	logic.memory.checkpoint(state)
+	return state
+}
+
+// computePodScale computes a new pod scale for a given pod.
+// It also requires the old pod scale, the service level agreement, the pod metrics and the container working set, if available.
+func (logic *PIDControlLogic) computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue, workingSet *resource.Quantity) (*v1beta1.PodScale, error) {
+	return recommendPodScale(pod, podScale, sla, podMetrics, workingSet, logic.computeCPUResource, logic.memory, logic.checkpoint)
+}
+
+// computeCPUResource computes cpu resources for a given pod.
+func (logic *PIDControlLogic) computeCPUResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue) (*resource.Quantity, error) {
+
+	// Track the CPU actually assigned to the container
+	actualCPU := float64(podScale.Status.ActualResources.Cpu().MilliValue())
+	logic.integral += actualCPU - logic.cores
+
+	e, err := computeError(sla, podMetrics)
+	if err != nil {
+		return nil, err
+	}
+	e = math.Min(math.Max(e, logic.params.minError), logic.params.maxError)
+
+	// First order low pass filter on the variation of the error
+	logic.derivative = logic.params.derivativeFilter*logic.derivative + (1-logic.params.derivativeFilter)*(e-logic.prevError)
+	logic.prevError = e
+
+	lower, upper := logic.params.minCPU, actualCPU*logic.params.maxScaleOut
+	if min, ok := sla.Spec.MinResources[v1.ResourceCPU]; ok {
+		lower = math.Max(lower, float64(min.MilliValue()))
+	}
+	if max, ok := sla.Spec.MaxResources[v1.ResourceCPU]; ok {
+		upper = math.Min(upper, float64(max.MilliValue()))
+	}
+
+	integral := logic.integral + logic.params.ki*e
+	output := integral + logic.params.kp*e + logic.params.kd*logic.derivative
+	cores := math.Min(math.Max(lower, output), upper)
+
+	// Stop integrating while the error pushes the output beyond the bounds
+	if !(output > upper && e > 0) && !(output < lower && e < 0) {
+		logic.integral = integral
+	}
+	logic.cores = cores
+
+	klog.Info("error is: ", e, ", derivative is: ", logic.derivative, ", integral is: ", logic.integral)
+	klog.Info("KP: ", logic.params.kp, ", KI: ", logic.params.ki, ", KD: ", logic.params.kd)
+	klog.Info("old cores are: ", actualCPU, ", output is: ", output, ", cores is: ", cores)
+
+	return resource.NewMilliQuantity(int64(cores), resource.BinarySI), nil
+}
@@ -0,0 +1,108 @@
+package recommender
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+func TestPIDControlLogic(t *testing.T) {
+
+	type step struct {
+		// responseTime is expressed in milliseconds
+		responseTime int64
+		expected     int64
+	}
+
+	testcases := []struct {
+		description string
+		parameters  *v1beta1.ControllerParameters
+		actual      int64
+		steps       []step
+	}{
+		{
+			description: "should increase the cpu when the response time is too high",
+			parameters: &v1beta1.ControllerParameters{
+				KP: resource.NewQuantity(10, resource.DecimalSI),
+				KI: resource.NewQuantity(5, resource.DecimalSI),
+				KD: resource.NewQuantity(0, resource.DecimalSI),
+			},
+			actual: 500,
+			steps:  []step{{responseTime: 200, expected: 575}},
+		},
+		{
+			description: "should not wind up the integral term while saturated",
+			parameters: &v1beta1.ControllerParameters{
+				KP: resource.NewQuantity(10, resource.DecimalSI),
+				KI: resource.NewQuantity(5, resource.DecimalSI),
+				KD: resource.NewQuantity(0, resource.DecimalSI),
+			},
+			actual: 1000,
+			steps: []step{
+				{responseTime: 200, expected: 1000},
+				{responseTime: 200, expected: 1000},
+				{responseTime: 200, expected: 1000},
+				{responseTime: 50, expected: 850},
+			},
+		},
+		{
+			description: "should filter the derivative term",
+			parameters: &v1beta1.ControllerParameters{
+				KP:               resource.NewQuantity(0, resource.DecimalSI),
+				KI:               resource.NewQuantity(0, resource.DecimalSI),
+				KD:               resource.NewQuantity(10, resource.DecimalSI),
+				DerivativeFilter: resource.NewMilliQuantity(500, resource.DecimalSI),
+			},
+			actual: 500,
+			steps: []step{
+				{responseTime: 200, expected: 525},
+				{responseTime: 200, expected: 512},
+			},
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			sla := &v1beta1.ServiceLevelAgreement{
+				Spec: v1beta1.ServiceLevelAgreementSpec{
+					Metric: v1beta1.MetricRequirement{
+						ResponseTime: *resource.NewMilliQuantity(100, resource.DecimalSI),
+					},
+					RecommenderLogic: v1beta1.PIDControl,
+					MaxResources: corev1.ResourceList{
+						corev1.ResourceCPU: *resource.NewMilliQuantity(1000, resource.DecimalSI),
+					},
+					ControllerParameters: tt.parameters,
+				},
+			}
+			podScale := &v1beta1.PodScale{
+				Status: v1beta1.PodScaleStatus{
+					ActualResources: corev1.ResourceList{
+						corev1.ResourceCPU: *resource.NewMilliQuantity(tt.actual, resource.DecimalSI),
+					},
+				},
+			}
+
+			logic := newPIDControlLogic(podScale, sla)
+
+			for _, s := range tt.steps {
+				podMetrics := map[metrics.MetricType]*metricsv1beta2.MetricValue{
+					metrics.ResponseTime: {Value: *resource.NewMilliQuantity(s.responseTime, resource.DecimalSI)},
+				}
+
+				cpu, err := logic.computeCPUResource(corev1.Container{}, podScale, sla, podMetrics)
+				require.Nil(t, err)
+				require.Equal(t, s.expected, cpu.MilliValue())
+
+				// the resources are assigned as recommended
+				podScale = podScale.DeepCopy()
+				podScale.Status.ActualResources[corev1.ResourceCPU] = *cpu
+			}
+		})
+	}
+}
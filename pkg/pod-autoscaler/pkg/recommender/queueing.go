@@ -0,0 +1,185 @@
+package recommender
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+const (
+	// bisectionSteps is the number of steps used to invert the queueing model
+	bisectionSteps = 64
+	// maxModelCPU is the largest CPU, in cores, recommended by the queueing model
+	maxModelCPU = 1024
+)
+
+// podLoad is the load observed on a pod
+type podLoad struct {
+	// throughput is the rate of the requests served by the pod, in requests per second
+	throughput float64
+	// responseTime is the mean response time of the pod, in seconds
+	responseTime float64
+	// predictedThroughput is the throughput expected at the forecast horizon, in requests per second.
+	// It is equal to the throughput when the forecasting is disabled.
+	predictedThroughput float64
+}
+
+// loadObserver is implemented by the logics that need the load of the pod,
+// regardless of the requirements set in the service level agreement.
+type loadObserver interface {
+	observe(load podLoad)
+}
+
+// QueueingModelLogic is the logic that models the container as a M/M/c queue, where each
+// core is a server. At every recommendation the service demand of the requests is estimated
+// from the observed throughput, response time and CPU, then the model is inverted to find the
+// CPU meeting the response time target with the predicted throughput. Unlike the feedback
+// logics, it reaches the new allocation in a single step after a load change.
+type QueueingModelLogic struct {
+	load      podLoad
+	cores     float64
+	prevError float64
+	params    controlParameters
+	memory    *memoryLogic
+}
+
+// newQueueingModelLogic returns a new queueing model logic tuned with the agreement controller parameters
+func newQueueingModelLogic(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) *QueueingModelLogic {
+	params := newControlParameters(sla.Spec.ControllerParameters)
+	logic := &QueueingModelLogic{
+		cores:     float64(podScale.Status.ActualResources.Cpu().MilliValue()),
+		prevError: 0.0,
+		params:    params,
+		memory:    newMemoryLogic(podScale, params),
+	}
+
+	if state := podScale.Status.ControllerState; state != nil {
+		restoreCPU(&logic.cores, state.Cores)
+		restore(&logic.prevError, state.PrevError)
+	}
+
+	return logic
+}
+
+// observe sets the load used by the next recommendation
+func (logic *QueueingModelLogic) observe(load podLoad) {
+	logic.load = load
+}
+
+// checkpoint returns the current state of the controller
+func (logic *QueueingModelLogic) checkpoint() *v1beta1.ControllerState {
+	state := &v1beta1.ControllerState{
+		Cores:     resource.NewMilliQuantity(int64(logic.cores), resource.DecimalSI),
+		PrevError: toQuantity(logic.prevError),
+	}
+	logic.memory.checkpoint(state)
+	return state
+}
+
+// computePodScale computes a new pod scale for a given pod.
+// It also requires the old pod scale, the service level agreement, the pod metrics and the container working set, if available.
+func (logic *QueueingModelLogic) computePodScale(pod *v1.Pod, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue, workingSet *resource.Quantity) (*v1beta1.PodScale, error) {
+	return recommendPodScale(pod, podScale, sla, podMetrics, workingSet, logic.computeCPUResource, logic.memory, logic.checkpoint)
+}
+
+// computeCPUResource computes cpu resources for a given pod.
+// The CPU is left unchanged when the pod does not serve any request, since the service demand cannot be estimated.
+func (logic *QueueingModelLogic) computeCPUResource(container v1.Container, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement, podMetrics map[metrics.MetricType]*metricsv1beta2.MetricValue) (*resource.Quantity, error) {
+
+	target := meanResponseTimeTarget(sla.Spec.Metric)
+	if target <= 0 {
+		return nil, fmt.Errorf("the %s recommender logic requires a response time requirement in service level agreement %s", v1beta1.QueueingModel, sla.Name)
+	}
+
+	e, err := computeError(sla, podMetrics)
+	if err != nil {
+		return nil, err
+	}
+	logic.prevError = e
+
+	// CPU values are expressed in cores within the model
+	actualCPU := float64(podScale.Status.ActualResources.Cpu().MilliValue()) / 1000
+	arrivals, responseTime := logic.load.throughput, logic.load.responseTime
+
+	if actualCPU <= 0 || arrivals <= 0 || responseTime <= 0 {
+		klog.Info("no load observed, keeping the cpu unchanged")
+		logic.cores = actualCPU * 1000
+		return resource.NewMilliQuantity(int64(logic.cores), resource.BinarySI), nil
+	}
+
+	demand := estimateDemand(actualCPU, arrivals, responseTime)
+	cpu := requiredCPU(demand, logic.load.predictedThroughput, target)
+	logic.cores = math.Max(logic.params.minCPU, cpu*1000)
+
+	klog.Info("throughput is: ", arrivals, ", predicted throughput is: ", logic.load.predictedThroughput, ", response time is: ", responseTime, ", target is: ", target)
+	klog.Info("service demand is: ", demand, ", old cores are: ", actualCPU, ", cores is: ", cpu)
+
+	return resource.NewMilliQuantity(int64(math.Ceil(logic.cores)), resource.BinarySI), nil
+}
+
+// meanResponseTimeTarget returns the target of the mean response time in seconds.
+// The percentiles are converted to the mean assuming exponentially distributed response times,
+// whose p-th percentile is the mean multiplied by -ln(1 - p).
+func meanResponseTimeTarget(requirement v1beta1.MetricRequirement) float64 {
+	target := toFloat(&requirement.ResponseTime)
+	if percentile, ok := metrics.ResponseTimePercentiles[metrics.ResponseTimeMetric(requirement.ResponseTimePercentile)]; ok {
+		target /= -math.Log(1 - percentile/100)
+	}
+	return target
+}
+
+// mmcResponseTime returns the mean response time of a M/M/c queue with the given cores,
+// service demand in core seconds and arrival rate, using the approximation R = D / (1 - U^c),
+// where U is the utilization of the cores. Below one core the queue is a M/M/1 with a slower server.
+func mmcResponseTime(cpu, demand, arrivals float64) float64 {
+	utilization := arrivals * demand / cpu
+	if utilization >= 1 {
+		return math.Inf(1)
+	}
+	if cpu < 1 {
+		return demand / cpu / (1 - utilization)
+	}
+	return demand / (1 - math.Pow(utilization, cpu))
+}
+
+// estimateDemand returns the service demand that explains the observed response time
+// with the given cores and arrival rate. The response time grows with the demand,
+// so the model is inverted by bisection on the stable demands.
+func estimateDemand(cpu, arrivals, responseTime float64) float64 {
+	low, high := 0.0, cpu/arrivals
+	for i := 0; i < bisectionSteps; i++ {
+		demand := (low + high) / 2
+		if mmcResponseTime(cpu, demand, arrivals) > responseTime {
+			high = demand
+		} else {
+			low = demand
+		}
+	}
+	return low
+}
+
+// requiredCPU returns the least cores meeting the target response time with the given
+// service demand and arrival rate. The response time decreases with the cores, so the
+// model is inverted by bisection, starting from the cores that keep the queue stable.
+// When the target cannot be met, maxModelCPU is returned and capped by the agreement.
+func requiredCPU(demand, arrivals, target float64) float64 {
+	low, high := arrivals*demand, float64(maxModelCPU)
+	if mmcResponseTime(high, demand, arrivals) > target {
+		return high
+	}
+	for i := 0; i < bisectionSteps; i++ {
+		cpu := (low + high) / 2
+		if mmcResponseTime(cpu, demand, arrivals) > target {
+			low = cpu
+		} else {
+			high = cpu
+		}
+	}
+	return high
+}
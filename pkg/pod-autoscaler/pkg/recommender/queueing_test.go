@@ -0,0 +1,99 @@
+package recommender
+
+import (
+	"math"
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+func TestQueueingModel(t *testing.T) {
+	// a single core serving 5 requests per second of 100ms is a M/M/1 queue with utilization 0.5
+	require.InDelta(t, 0.2, mmcResponseTime(1, 0.1, 5), 1e-9)
+	require.InDelta(t, 0.1, estimateDemand(1, 5, 0.2), 1e-9)
+	require.True(t, math.IsInf(mmcResponseTime(0.5, 0.1, 5), 1))
+
+	// the required cpu is the least one meeting the target
+	cpu := requiredCPU(0.1, 9, 0.2)
+	require.LessOrEqual(t, mmcResponseTime(cpu, 0.1, 9), 0.2)
+	require.Greater(t, mmcResponseTime(cpu*0.99, 0.1, 9), 0.2)
+
+	// the response time decreases with the cores, also across one core
+	require.Greater(t, mmcResponseTime(0.9, 0.1, 5), mmcResponseTime(1, 0.1, 5))
+	require.Greater(t, mmcResponseTime(1, 0.1, 5), mmcResponseTime(1.1, 0.1, 5))
+
+	require.Equal(t, maxModelCPU, int(requiredCPU(0.1, 9, 0.05)))
+}
+
+func TestMeanResponseTimeTarget(t *testing.T) {
+	requirement := v1beta1.MetricRequirement{ResponseTime: *resource.NewMilliQuantity(1000, resource.DecimalSI)}
+	require.InDelta(t, 1, meanResponseTimeTarget(requirement), 1e-9)
+
+	requirement.ResponseTimePercentile = v1beta1.P90
+	require.InDelta(t, 1/math.Log(10), meanResponseTimeTarget(requirement), 1e-9)
+}
+
+func TestQueueingModelLogic(t *testing.T) {
+
+	testcases := []struct {
+		description string
+		load        podLoad
+		expected    int64
+	}{
+		{
+			description: "should jump to the cpu meeting the target after a load step",
+			load:        podLoad{throughput: 9, responseTime: 1, predictedThroughput: 9},
+			expected:    1452,
+		},
+		{
+			description: "should size the cpu for the predicted throughput",
+			load:        podLoad{throughput: 9, responseTime: 1, predictedThroughput: 18},
+			expected:    2403,
+		},
+		{
+			description: "should release the cpu when the response time is below the target",
+			load:        podLoad{throughput: 2, responseTime: 0.1, predictedThroughput: 2},
+			expected:    584,
+		},
+		{
+			description: "should keep the cpu without load",
+			load:        podLoad{},
+			expected:    1000,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			sla := &v1beta1.ServiceLevelAgreement{
+				Spec: v1beta1.ServiceLevelAgreementSpec{
+					Metric: v1beta1.MetricRequirement{
+						ResponseTime: *resource.NewMilliQuantity(200, resource.DecimalSI),
+					},
+					RecommenderLogic: v1beta1.QueueingModel,
+				},
+			}
+			podScale := &v1beta1.PodScale{
+				Status: v1beta1.PodScaleStatus{
+					ActualResources: corev1.ResourceList{
+						corev1.ResourceCPU: *resource.NewMilliQuantity(1000, resource.DecimalSI),
+					},
+				},
+			}
+			podMetrics := map[metrics.MetricType]*metricsv1beta2.MetricValue{
+				metrics.ResponseTime: {Value: *toQuantity(tt.load.responseTime)},
+			}
+
+			logic := newQueueingModelLogic(podScale, sla)
+			logic.observe(tt.load)
+
+			cpu, err := logic.computeCPUResource(corev1.Container{}, podScale, sla, podMetrics)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, cpu.MilliValue())
+		})
+	}
+}
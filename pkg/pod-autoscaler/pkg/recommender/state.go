@@ -0,0 +1,107 @@
+package recommender
+
+import (
+	"fmt"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// logicState is the logic assigned to a PodScale along with the configuration it has been created with.
+type logicState struct {
+	// uid is the UID of the PodScale owning the logic. It prevents a new PodScale
+	// with the same name of a deleted one from inheriting its state.
+	uid types.UID
+	// recommenderLogic, metric and parameters are the agreement fields used to build the logic
+	recommenderLogic v1beta1.RecommendLogic
+	metric           v1beta1.MetricRequirement
+	parameters       *v1beta1.ControllerParameters
+	logic            Logic
+}
+
+// newLogicState creates the logic requested by the agreement for the PodScale
+func newLogicState(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) *logicState {
+	var logic Logic
+	switch sla.Spec.RecommenderLogic {
+	case v1beta1.FixedGainControl:
+		logic = newFixedGainControlLogic(podScale, sla)
+	case v1beta1.AdaptiveGainControl:
+		logic = newAdaptiveGainControlLogic(podScale, sla)
+	case v1beta1.PIDControl:
+		logic = newPIDControlLogic(podScale, sla)
+	case v1beta1.QueueingModel:
+		logic = newQueueingModelLogic(podScale, sla)
+	default:
+		logic = newFixedGainControlLogic(podScale, sla)
+		//return nil, fmt.Errorf("illegal value %s as recommender logic", sla.Spec.RecommenderLogic)
+	}
+
+	return &logicState{
+		uid:              podScale.UID,
+		recommenderLogic: sla.Spec.RecommenderLogic,
+		metric:           *sla.Spec.Metric.DeepCopy(),
+		parameters:       sla.Spec.ControllerParameters.DeepCopy(),
+		logic:            logic,
+	}
+}
+
+// outdated returns true if the logic belongs to another PodScale or the agreement
+// changed the logic, the targets or the parameters it has been created with.
+func (s *logicState) outdated(podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) bool {
+	return s.uid != podScale.UID ||
+		s.recommenderLogic != sla.Spec.RecommenderLogic ||
+		!equality.Semantic.DeepEqual(s.metric, sla.Spec.Metric) ||
+		!equality.Semantic.DeepEqual(s.parameters, sla.Spec.ControllerParameters)
+}
+
+// logicFor returns the logic assigned to the PodScale, creating it when missing or outdated.
+func (c *Controller) logicFor(key string, podScale *v1beta1.PodScale, sla *v1beta1.ServiceLevelAgreement) (Logic, error) {
+	stateInterface, ok := c.status.logicMap.Load(key)
+	if ok {
+		state, ok := stateInterface.(*logicState)
+		if !ok {
+			return nil, fmt.Errorf("failed to cast logic of pod scale %s", key)
+		}
+		if !state.outdated(podScale, sla) {
+			return state.logic, nil
+		}
+		klog.Info("Resetting the logic of pod scale ", key)
+		if state.uid == podScale.UID {
+			// the checkpointed state refers to the previous configuration of the agreement
+			podScale = podScale.DeepCopy()
+			podScale.Status.ControllerState = nil
+		}
+	}
+
+	state := newLogicState(podScale, sla)
+	c.status.logicMap.Store(key, state)
+	return state.logic, nil
+}
+
+// sweepLogics evicts the logics of the PodScales that no longer exist and the load histories of the deleted Services.
+func (c *Controller) sweepLogics() {
+	c.status.logicMap.Range(func(k, v interface{}) bool {
+		key, _ := k.(string)
+		state, ok := v.(*logicState)
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if !ok || err != nil {
+			c.status.logicMap.Delete(k)
+			return true
+		}
+
+		podScale, err := c.listers.PodScales(namespace).Get(name)
+		if errors.IsNotFound(err) || (err == nil && podScale.UID != state.uid) {
+			klog.V(4).Info("Evicting the logic of pod scale ", key)
+			c.status.logicMap.Delete(key)
+		} else if err != nil {
+			klog.Error(err)
+		}
+		return true
+	})
+
+	c.forecaster.Sweep(c.listers.ServiceLister)
+}
@@ -0,0 +1,127 @@
+package recommender
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/forecaster"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
+	"github.com/modern-go/concurrent"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newPodScale(name string, uid types.UID) *v1beta1.PodScale {
+	return &v1beta1.PodScale{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       uid,
+		},
+	}
+}
+
+func TestLogicFor(t *testing.T) {
+	podScale := newPodScale("podscale", "uid")
+	sla := &v1beta1.ServiceLevelAgreement{
+		Spec: v1beta1.ServiceLevelAgreementSpec{
+			Metric: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.DecimalSI),
+			},
+			RecommenderLogic: v1beta1.FixedGainControl,
+		},
+	}
+
+	testcases := []struct {
+		description string
+		podScale    func() *v1beta1.PodScale
+		sla         func() *v1beta1.ServiceLevelAgreement
+		reset       bool
+	}{
+		{
+			description: "should keep the logic when nothing changed",
+			podScale:    func() *v1beta1.PodScale { return podScale },
+			sla:         func() *v1beta1.ServiceLevelAgreement { return sla },
+			reset:       false,
+		},
+		{
+			description: "should reset the logic of a recreated pod scale",
+			podScale:    func() *v1beta1.PodScale { return newPodScale("podscale", "new-uid") },
+			sla:         func() *v1beta1.ServiceLevelAgreement { return sla },
+			reset:       true,
+		},
+		{
+			description: "should reset the logic when the recommender logic changes",
+			podScale:    func() *v1beta1.PodScale { return podScale },
+			sla: func() *v1beta1.ServiceLevelAgreement {
+				newSLA := sla.DeepCopy()
+				newSLA.Spec.RecommenderLogic = v1beta1.AdaptiveGainControl
+				return newSLA
+			},
+			reset: true,
+		},
+		{
+			description: "should reset the logic when the target changes",
+			podScale:    func() *v1beta1.PodScale { return podScale },
+			sla: func() *v1beta1.ServiceLevelAgreement {
+				newSLA := sla.DeepCopy()
+				newSLA.Spec.Metric.ResponseTime = *resource.NewMilliQuantity(200, resource.DecimalSI)
+				return newSLA
+			},
+			reset: true,
+		},
+		{
+			description: "should reset the logic when the controller parameters change",
+			podScale:    func() *v1beta1.PodScale { return podScale },
+			sla: func() *v1beta1.ServiceLevelAgreement {
+				newSLA := sla.DeepCopy()
+				newSLA.Spec.ControllerParameters = &v1beta1.ControllerParameters{
+					BC: resource.NewQuantity(10, resource.DecimalSI),
+				}
+				return newSLA
+			},
+			reset: true,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Controller{status: &Status{logicMap: *concurrent.NewMap()}}
+
+			logic, err := c.logicFor("default/podscale", podScale, sla)
+			require.Nil(t, err)
+
+			newLogic, err := c.logicFor("default/podscale", tt.podScale(), tt.sla())
+			require.Nil(t, err)
+			require.Equal(t, tt.reset, logic != newLogic)
+		})
+	}
+}
+
+func TestSweepLogics(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.Nil(t, indexer.Add(newPodScale("alive", "alive")))
+	require.Nil(t, indexer.Add(newPodScale("recreated", "new")))
+
+	c := &Controller{
+		listers:    informers.Listers{PodScaleLister: salisters.NewPodScaleLister(indexer)},
+		status:     &Status{logicMap: *concurrent.NewMap()},
+		forecaster: forecaster.NewForecaster(),
+	}
+	c.status.logicMap.Store("default/alive", &logicState{uid: "alive"})
+	c.status.logicMap.Store("default/recreated", &logicState{uid: "old"})
+	c.status.logicMap.Store("default/deleted", &logicState{uid: "deleted"})
+
+	c.sweepLogics()
+
+	keys := make([]string, 0)
+	c.status.logicMap.Range(func(k, v interface{}) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	require.Equal(t, []string{"default/alive"}, keys)
+}
@@ -92,6 +92,7 @@ var _ = BeforeSuite(func(done Done) {
 		saClient,
 		informers,
 		metricClient,
+		false,
 	)
 
 	By("starting informers")
@@ -275,3 +276,13 @@ func newPodScale(sla *sa.ServiceLevelAgreement, service *corev1.Service, pod *co
 		},
 	}
 }
+
+// createPodScale creates the pod scale along with its status, which is ignored on creation because of the status subresource
+func createPodScale(ctx context.Context, podScale *sa.PodScale) (*sa.PodScale, error) {
+	created, err := saClient.SystemautoscalerV1beta1().PodScales(podScale.Namespace).Create(ctx, podScale, metav1.CreateOptions{})
+	if err != nil {
+		return nil, err
+	}
+	created.Status = podScale.Status
+	return saClient.SystemautoscalerV1beta1().PodScales(podScale.Namespace).UpdateStatus(ctx, created, metav1.UpdateOptions{})
+}
@@ -59,7 +59,7 @@ var _ = Describe("Replica updater controller", func() {
 			var podScales []*sa.PodScale
 			for _, pod := range podList.Items {
 				podScale := newPodScale(sla, svc, &pod, labels)
-				podScale, err = saClient.SystemautoscalerV1beta1().PodScales(namespace).Create(ctx, podScale, metav1.CreateOptions{})
+				podScale, err = createPodScale(ctx, podScale)
 				Expect(err).ShouldNot(HaveOccurred())
 				podScales = append(podScales, podScale)
 			}
@@ -22,6 +22,7 @@ import (
 var (
 	masterURL  string
 	kubeconfig string
+	shadow     bool
 )
 
 func main() {
@@ -73,6 +74,7 @@ func main() {
 		client,
 		informers,
 		metricsGetter,
+		shadow,
 	)
 
 	// notice that there is no need to run Start methods in a separate goroutine. (i.e. go kubeInformerFactory.Start(stopCh)
@@ -93,4 +95,5 @@ func main() {
 func init() {
 	flag.StringVar(&kubeconfig, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
 	flag.StringVar(&masterURL, "master", "", "The address of the Kubernetes API server. Overrides any value in kubeconfig. Only required if out-of-cluster.")
+	flag.BoolVar(&shadow, "shadow", false, "Recommend the replicas of the deployments without scaling them. It can be enabled on single agreements through the shadow field.")
 }
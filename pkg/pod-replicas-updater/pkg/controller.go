@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/forecaster"
 	saclientset "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned"
 	samplescheme "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned/scheme"
 	"github.com/lterrac/system-autoscaler/pkg/informers"
@@ -13,6 +14,7 @@ import (
 	"github.com/lterrac/system-autoscaler/pkg/queue"
 	"github.com/modern-go/concurrent"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -26,6 +28,9 @@ import (
 
 const (
 	controllerAgentName = "pod-replica-updater"
+
+	// logicSweepPeriod is the period between two evictions of the logics of deleted service level agreements
+	logicSweepPeriod = time.Minute
 )
 
 // Controller is the component that controls the number of replicas of a pod.
@@ -42,6 +47,7 @@ type Controller struct {
 
 	podScaleSynced cache.InformerSynced
 	podSynced      cache.InformerSynced
+	nodeSynced     cache.InformerSynced
 
 	// recorder is an event recorder for recording Event resources to the
 	// Kubernetes API.
@@ -50,7 +56,13 @@ type Controller struct {
 	// MetricClient is a client that polls the metrics from the pod.
 	MetricClient metricsgetter.MetricGetter
 
-	// Key: namespace-name of the application, Value: assigned logic
+	// shadow disables the update of the replicas of all the deployments
+	shadow bool
+
+	// forecaster predicts the load of the services whose agreement enables the forecasting
+	forecaster *forecaster.Forecaster
+
+	// Key: namespace-name of the application, Value: assigned logic state
 	logicMap concurrent.Map
 
 	// workqueue contains all the servicelevelagreements that needs a recommendation
@@ -61,7 +73,8 @@ type Controller struct {
 func NewController(kubernetesClientset *kubernetes.Clientset,
 	saClientSet saclientset.Interface,
 	informers informers.Informers,
-	metricClient metricsgetter.MetricGetter) *Controller {
+	metricClient metricsgetter.MetricGetter,
+	shadow bool) *Controller {
 
 	// Create event broadcaster
 	// Add sample-controller types to the default Kubernetes Scheme so Events can be
@@ -72,6 +85,9 @@ func NewController(kubernetesClientset *kubernetes.Clientset,
 	eventBroadcaster.StartStructuredLogging(0)
 	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: controllerAgentName})
 
+	// the pods of each node are retrieved through the node name index of the pod informer
+	utilruntime.Must(informers.IndexPodsByNode())
+
 	// Instantiate the Controller
 	controller := &Controller{
 		saClientSet:         saClientSet,
@@ -80,7 +96,10 @@ func NewController(kubernetesClientset *kubernetes.Clientset,
 		listers:             informers.GetListers(),
 		podScaleSynced:      informers.PodScale.Informer().HasSynced,
 		podSynced:           informers.Pod.Informer().HasSynced,
+		nodeSynced:          informers.Node.Informer().HasSynced,
 		MetricClient:        metricClient,
+		shadow:              shadow,
+		forecaster:          forecaster.NewForecaster(),
 		workqueue:           queue.NewQueue("SLAQueue"),
 	}
 
@@ -100,12 +119,14 @@ func (c *Controller) Run(threadiness int, stopCh <-chan struct{}) error {
 	klog.Info("Waiting for informer caches to sync")
 	if ok := cache.WaitForCacheSync(stopCh,
 		c.podScaleSynced,
-		c.podSynced); !ok {
+		c.podSynced,
+		c.nodeSynced); !ok {
 		return fmt.Errorf("failed to wait for caches to sync")
 	}
 
 	klog.Info("Starting pod replica updater workers")
 	go wait.Until(c.runWorker, 5*time.Second, stopCh)
+	go wait.Until(c.sweepLogics, logicSweepPeriod, stopCh)
 	for i := 0; i < threadiness; i++ {
 		go wait.Until(c.runSLAWorker, time.Second, stopCh)
 	}
@@ -211,19 +232,34 @@ func (c *Controller) handleSLA(key string) error {
 	}
 
 	// Retrieve the associated logic
-	logicInterface, ok := c.logicMap.LoadOrStore(key, newCustomLogic(true, c.kubernetesClientset))
+	logic, ok, err := c.logicFor(key, sla)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return fmt.Errorf("the key %s has no previous logic associated with it, initializing it", key)
 	}
-	logic, ok := logicInterface.(Logic)
-	if !ok {
-		return fmt.Errorf("error: %s, failed to cast logic for deployment with name %s and namespace %s", err, deploymentName, namespace)
+
+	// Predict the load of the service
+	growth := 1.0
+	if sla.Spec.Forecast != nil {
+		growth, err = c.forecaster.ObserveService(service, sla.Spec.Forecast, c.MetricClient)
+		if err != nil {
+			klog.Errorf("failed to retrieve the load of service with name %s and namespace %s, error: %s", service.Name, service.Namespace, err)
+		}
+		klog.Info("SLA key: ", key, " predicted throughput growth: ", growth)
 	}
 
 	// Compute the new amount of replicas
-	nReplicas := logic.computeReplica(sla, matchedPods, matchedPodScales, service, c.MetricClient, *deployment.Spec.Replicas)
+	nReplicas := logic.computeReplica(sla, matchedPods, matchedPodScales, service, c.MetricClient, *deployment.Spec.Replicas, growth)
 	klog.Info("SLA key: ", key, " new amount of replicas: ", nReplicas)
 
+	// in shadow mode the replicas are only recommended and recorded in the sla status
+	if c.shadow || sla.Spec.Shadow {
+		klog.Info("SLA key: ", key, " shadow mode, deployment ", namespace, "/", deploymentName, " not scaled from ", *deployment.Spec.Replicas, " replicas")
+		return c.updateRecommendedReplicas(sla, &nReplicas)
+	}
+
 	// Set the new amount of replicas
 	deployment.Spec.Replicas = &nReplicas
 	_, err = c.kubernetesClientset.AppsV1().Deployments(namespace).Update(context.TODO(), deployment, v1.UpdateOptions{})
@@ -231,5 +267,23 @@ func (c *Controller) handleSLA(key string) error {
 		return fmt.Errorf("failed to update the deployment with name %s and namespace %s, error: %s", deploymentName, namespace, err)
 	}
 
+	// the recommendation recorded in shadow mode is stale once the deployment is scaled
+	return c.updateRecommendedReplicas(sla, nil)
+}
+
+// updateRecommendedReplicas records the replicas recommended in shadow mode in the sla status.
+// A nil value clears the recommendation.
+func (c *Controller) updateRecommendedReplicas(sla *v1beta1.ServiceLevelAgreement, replicas *int32) error {
+	if equality.Semantic.DeepEqual(sla.Status.RecommendedReplicas, replicas) {
+		return nil
+	}
+
+	newSLA := sla.DeepCopy()
+	newSLA.Status.RecommendedReplicas = replicas
+	_, err := c.saClientSet.SystemautoscalerV1beta1().ServiceLevelAgreements(sla.Namespace).UpdateStatus(context.TODO(), newSLA, v1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to record the recommended replicas of the sla with name %s and namespace %s, error: %s", sla.Name, sla.Namespace, err)
+	}
+
 	return nil
 }
@@ -0,0 +1,132 @@
+package replicaupdater
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	safake "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned/fake"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
+	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestHandleSLAShadow(t *testing.T) {
+	int32Ptr := func(i int32) *int32 { return &i }
+
+	testcases := []struct {
+		description         string
+		shadow              bool
+		slaShadow           bool
+		recommended         *int32
+		expectedReplicas    int32
+		expectedRecommended *int32
+	}{
+		{
+			description:         "should scale the deployment of an active agreement",
+			recommended:         int32Ptr(3),
+			expectedReplicas:    2,
+			expectedRecommended: nil,
+		},
+		{
+			description:         "should record the replicas recommended for a shadow agreement",
+			slaShadow:           true,
+			expectedReplicas:    4,
+			expectedRecommended: int32Ptr(2),
+		},
+		{
+			description:         "should record the replicas recommended for all the agreements in shadow mode",
+			shadow:              true,
+			expectedReplicas:    4,
+			expectedRecommended: int32Ptr(2),
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			// the response time is half of the required one, so the replicas are halved
+			sla := newSLA("sla", "uid")
+			sla.Spec.Metric.ResponseTime = *resource.NewQuantity(2, resource.DecimalSI)
+			sla.Spec.MinReplicas = 1
+			sla.Spec.MaxReplicas = 10
+			sla.Spec.Shadow = tt.slaShadow
+			sla.Status.RecommendedReplicas = tt.recommended
+
+			slas := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.Nil(t, slas.Add(sla))
+			podScales := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.Nil(t, podScales.Add(&v1beta1.PodScale{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod-foo", Namespace: "default"},
+				Spec:       v1beta1.PodScaleSpec{Namespace: "default", SLA: "sla", Pod: "foo", Service: "foo"},
+			}))
+			pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.Nil(t, pods.Add(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:            "foo",
+					Namespace:       "default",
+					OwnerReferences: []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "foo-rs"}},
+				},
+			}))
+			services := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.Nil(t, services.Add(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}))
+
+			kubernetesClientset := fake.NewSimpleClientset(
+				&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+					Name:            "foo-rs",
+					Namespace:       "default",
+					OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: "foo"}},
+				}},
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+					Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(4)},
+				},
+			)
+			saClientSet := safake.NewSimpleClientset(sla)
+
+			c := &Controller{
+				saClientSet:         saClientSet,
+				kubernetesClientset: kubernetesClientset,
+				listers: informers.Listers{
+					PodLister:                   corelisters.NewPodLister(pods),
+					NodeLister:                  corelisters.NewNodeLister(cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})),
+					ServiceLister:               corelisters.NewServiceLister(services),
+					PodScaleLister:              salisters.NewPodScaleLister(podScales),
+					ServiceLevelAgreementLister: salisters.NewServiceLevelAgreementLister(slas),
+				},
+				MetricClient: &metricsgetter.FakeGetter{ResponseTime: 1},
+				shadow:       tt.shadow,
+			}
+
+			// the logic has been scaling down long enough to actuate the new replicas
+			c.logicMap.Store("default/sla", &logicState{
+				uid:    sla.UID,
+				metric: sla.Spec.Metric,
+				logic: &CustomLogic{
+					startScaleDownTime: time.Now().Add(-time.Hour),
+					stabilizeTime:      time.Now().Add(-time.Hour),
+					state:              ScalingDownState,
+					listers:            c.listers,
+				},
+			})
+
+			require.Nil(t, c.handleSLA("default/sla"))
+
+			deployment, err := kubernetesClientset.AppsV1().Deployments("default").Get(context.TODO(), "foo", metav1.GetOptions{})
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedReplicas, *deployment.Spec.Replicas)
+
+			updatedSLA, err := saClientSet.SystemautoscalerV1beta1().ServiceLevelAgreements("default").Get(context.TODO(), "sla", metav1.GetOptions{})
+			require.Nil(t, err)
+			require.Equal(t, tt.expectedRecommended, updatedSLA.Status.RecommendedReplicas)
+		})
+	}
+}
@@ -1,11 +1,10 @@
 package replicaupdater
 
 import (
-	"context"
+	"fmt"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
 	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
 	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog/v2"
 	"math"
 	"time"
@@ -16,8 +15,9 @@ import (
 
 // Logic is the logic the controller uses to suggest new replica values for an application
 type Logic interface {
-	//computeReplica computes the number of replicas for an application
-	computeReplica(sla *v1beta1.ServiceLevelAgreement, pods []*corev1.Pod, podscales []*v1beta1.PodScale, service *corev1.Service, metricClient metricsgetter.MetricGetter, curReplica int32) int32
+	//computeReplica computes the number of replicas for an application.
+	//growth is the ratio between the load predicted ahead and the current one, 1 when the forecasting is disabled.
+	computeReplica(sla *v1beta1.ServiceLevelAgreement, pods []*corev1.Pod, podscales []*v1beta1.PodScale, service *corev1.Service, metricClient metricsgetter.MetricGetter, curReplica int32, growth float64) int32
 }
 
 type LogicState string
@@ -55,7 +55,7 @@ const (
 )
 
 //computeReplica computes the number of replicas for a service, given the serviceLevelAgreement
-func (logic *HPALogic) computeReplica(sla *v1beta1.ServiceLevelAgreement, pods []*corev1.Pod, podscales []*v1beta1.PodScale, service *corev1.Service, metricClient metricsgetter.MetricGetter, curReplica int32) int32 {
+func (logic *HPALogic) computeReplica(sla *v1beta1.ServiceLevelAgreement, pods []*corev1.Pod, podscales []*v1beta1.PodScale, service *corev1.Service, metricClient metricsgetter.MetricGetter, curReplica int32, growth float64) int32 {
 
 	minReplicas := sla.Spec.MinReplicas
 	maxReplicas := sla.Spec.MaxReplicas
@@ -71,17 +71,18 @@ func (logic *HPALogic) computeReplica(sla *v1beta1.ServiceLevelAgreement, pods [
 
 	// Compute the desired amount of replica
 	desiredTarget := float64(sla.Spec.Metric.ResponseTime.MilliValue())
-	responseTime, err := metricClient.ServiceMetrics(service, metrics.ResponseTime)
+	ratio, err := serviceMetricRatio(sla, service, metricClient)
 
 	if err != nil {
 		klog.Errorf("failed to retrieve metrics for service with name %s and namespace %s, error: %s", service.Name, service.Namespace, err)
 		return curReplica
 	}
 
-	actualTarget := float64(responseTime.Value.MilliValue())
+	// The replicas are sized for the predicted load
+	ratio *= growth
 
 	// Apply constraints
-	nReplicas := int32(math.Min(float64(maxReplicas), math.Max(float64(minReplicas), math.Round(actualTarget/desiredTarget*float64(curReplica)))))
+	nReplicas := int32(math.Min(float64(maxReplicas), math.Max(float64(minReplicas), math.Round(ratio*float64(curReplica)))))
 
 	// Check tolerance
 	// If the new amount of replicas is between the upper bound and the lower bound
@@ -134,23 +135,23 @@ type CustomLogic struct {
 	stabilizeTime      time.Time
 	state              LogicState
 	earlyStop          bool
-	kubeClient         kubernetes.Interface
+	listers            informers.Listers
 }
 
 // newCustomLogic returns a new HPA logic
-func newCustomLogic(earlyStop bool, kubeClient kubernetes.Interface) *CustomLogic {
+func newCustomLogic(earlyStop bool, listers informers.Listers) *CustomLogic {
 	return &CustomLogic{
 		startScaleUpTime:   time.Now(),
 		startScaleDownTime: time.Now(),
 		stabilizeTime:      time.Now(),
 		state:              SteadyState,
 		earlyStop:          earlyStop,
-		kubeClient:         kubeClient,
+		listers:            listers,
 	}
 }
 
 //computeReplica computes the number of replicas for a service, given the serviceLevelAgreement
-func (logic *CustomLogic) computeReplica(sla *v1beta1.ServiceLevelAgreement, pods []*corev1.Pod, podscales []*v1beta1.PodScale, service *corev1.Service, metricClient metricsgetter.MetricGetter, curReplica int32) int32 {
+func (logic *CustomLogic) computeReplica(sla *v1beta1.ServiceLevelAgreement, pods []*corev1.Pod, podscales []*v1beta1.PodScale, service *corev1.Service, metricClient metricsgetter.MetricGetter, curReplica int32, growth float64) int32 {
 
 	minReplicas := sla.Spec.MinReplicas
 	maxReplicas := sla.Spec.MaxReplicas
@@ -178,17 +179,24 @@ func (logic *CustomLogic) computeReplica(sla *v1beta1.ServiceLevelAgreement, pod
 			}
 		}
 	}
+	// Scale up ahead of the predicted load
+	if growth > 1 {
+		predictedReplicas := int32(math.Round(growth * float64(curReplica)))
+		if predictedReplicas > nReplicas {
+			nReplicas = predictedReplicas
+		}
+	}
 	// Check for downscaling
 	if curReplica == nReplicas {
-		desiredTarget := float64(sla.Spec.Metric.ResponseTime.MilliValue())
-		responseTime, err := metricClient.ServiceMetrics(service, metrics.ResponseTime)
+		ratio, err := serviceMetricRatio(sla, service, metricClient)
 		if err != nil {
 			klog.Errorf("failed to retrieve metrics for service with name %s and namespace %s, error: %s", service.Name, service.Namespace, err)
 			return curReplica
 		}
-		actualTarget := float64(responseTime.Value.MilliValue())
+		// The replicas are sized for the predicted load
+		ratio *= growth
 		// Apply constraints
-		downscaledReplicas := int32(math.Min(float64(maxReplicas), math.Max(float64(minReplicas), math.Round(actualTarget/desiredTarget*float64(curReplica)))))
+		downscaledReplicas := int32(math.Min(float64(maxReplicas), math.Max(float64(minReplicas), math.Round(ratio*float64(curReplica)))))
 
 		if downscaledReplicas < nReplicas {
 			nReplicas = downscaledReplicas
@@ -232,8 +240,40 @@ func (logic *CustomLogic) computeReplica(sla *v1beta1.ServiceLevelAgreement, pod
 
 }
 
+// serviceMetricRatio returns how many times the replicas of the service should be scaled
+// to honor the requirements set in the service level agreement. When more requirements are
+// set, the most violated one is followed. The requirements that cannot be observed, like the
+// throughput of a service without traffic, are skipped and the replicas are kept when none is left.
+func serviceMetricRatio(sla *v1beta1.ServiceLevelAgreement, service *corev1.Service, metricClient metricsgetter.MetricGetter) (float64, error) {
+	requirements := metrics.Requirements(sla.Spec.Metric)
+
+	if len(requirements) == 0 {
+		return 0, fmt.Errorf("the service level agreement %s does not set any metric requirement", sla.Name)
+	}
+
+	ratio := 0.0
+	observed := false
+	for _, requirement := range requirements {
+		metric, err := metricClient.ServiceMetrics(service, requirement.Metric)
+		if err != nil {
+			return 0, err
+		}
+		if !requirement.Observed(metric.Value) {
+			continue
+		}
+		observed = true
+		ratio = math.Max(ratio, requirement.Ratio(metric.Value))
+	}
+
+	if !observed {
+		return 1, nil
+	}
+
+	return ratio, nil
+}
+
 func (logic *CustomLogic) getNodeSaturationLevel(nodeName string) (float64, error) {
-	node, err := logic.kubeClient.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	node, err := logic.listers.NodeLister.Get(nodeName)
 	if err != nil {
 		return 0, err
 	}
@@ -241,12 +281,12 @@ func (logic *CustomLogic) getNodeSaturationLevel(nodeName string) (float64, erro
 	allocatableCPU := float64(node.Status.Allocatable.Cpu().MilliValue())
 
 	requestedCPU := float64(0)
-	pods, err := logic.getNodePods(nodeName)
+	pods, err := logic.listers.PodsOnNode(nodeName)
 	if err != nil {
 		return 0, err
 	}
 
-	for _, pod := range pods.Items {
+	for _, pod := range pods {
 		for _, container := range pod.Spec.Containers {
 			requestedCPU = requestedCPU + float64(container.Resources.Requests.Cpu().MilliValue())
 		}
@@ -262,13 +302,3 @@ func (logic *CustomLogic) getNodeSaturationLevel(nodeName string) (float64, erro
 	return requestedCPU / allocatableCPU, nil
 
 }
-
-func (logic *CustomLogic) getNodePods(nodeName string) (*corev1.PodList, error) {
-	pods, err := logic.kubeClient.CoreV1().Pods("").List(context.TODO(), metav1.ListOptions{
-		FieldSelector: "spec.nodeName=" + nodeName,
-	})
-	if err != nil {
-		return nil, err
-	}
-	return pods, nil
-}
@@ -0,0 +1,59 @@
+package replicaupdater
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestServiceMetricRatio(t *testing.T) {
+	testcases := []struct {
+		description string
+		requirement v1beta1.MetricRequirement
+		value       int64
+		expected    float64
+	}{
+		{
+			description: "should scale by the violation of the throughput",
+			requirement: v1beta1.MetricRequirement{Throughput: resource.NewQuantity(10, resource.DecimalSI)},
+			value:       5,
+			expected:    2,
+		},
+		{
+			description: "should keep the replicas of a service without traffic",
+			requirement: v1beta1.MetricRequirement{Throughput: resource.NewQuantity(10, resource.DecimalSI)},
+			value:       0,
+			expected:    1,
+		},
+		{
+			description: "should keep the replicas of a service without traffic and errors",
+			requirement: v1beta1.MetricRequirement{
+				Throughput: resource.NewQuantity(10, resource.DecimalSI),
+				ErrorRate:  resource.NewQuantity(1, resource.DecimalSI),
+			},
+			value:    0,
+			expected: 1,
+		},
+		{
+			description: "should scale by the violation of the error rate",
+			requirement: v1beta1.MetricRequirement{ErrorRate: resource.NewQuantity(1, resource.DecimalSI)},
+			value:       3,
+			expected:    3,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			sla := &v1beta1.ServiceLevelAgreement{Spec: v1beta1.ServiceLevelAgreementSpec{Metric: tt.requirement}}
+			metricClient := &metricsgetter.FakeGetter{ResponseTime: tt.value}
+
+			ratio, err := serviceMetricRatio(sla, &corev1.Service{}, metricClient)
+			require.Nil(t, err)
+			require.Equal(t, tt.expected, ratio)
+		})
+	}
+}
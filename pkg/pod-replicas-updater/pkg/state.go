@@ -0,0 +1,74 @@
+package replicaupdater
+
+import (
+	"fmt"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+)
+
+// logicState is the logic assigned to a ServiceLevelAgreement along with the targets it has been created with.
+type logicState struct {
+	// uid is the UID of the agreement owning the logic. It prevents a new agreement
+	// with the same name of a deleted one from inheriting its state.
+	uid    types.UID
+	metric v1beta1.MetricRequirement
+	logic  Logic
+}
+
+// outdated returns true if the logic belongs to another agreement or the agreement targets changed
+func (s *logicState) outdated(sla *v1beta1.ServiceLevelAgreement) bool {
+	return s.uid != sla.UID || !equality.Semantic.DeepEqual(s.metric, sla.Spec.Metric)
+}
+
+// logicFor returns the logic assigned to the agreement. The returned flag is false when
+// the logic has just been created, either because it was missing or outdated.
+func (c *Controller) logicFor(key string, sla *v1beta1.ServiceLevelAgreement) (Logic, bool, error) {
+	stateInterface, ok := c.logicMap.Load(key)
+	if ok {
+		state, ok := stateInterface.(*logicState)
+		if !ok {
+			return nil, false, fmt.Errorf("failed to cast logic of service level agreement %s", key)
+		}
+		if !state.outdated(sla) {
+			return state.logic, true, nil
+		}
+		klog.Info("Resetting the logic of service level agreement ", key)
+	}
+
+	state := &logicState{
+		uid:    sla.UID,
+		metric: *sla.Spec.Metric.DeepCopy(),
+		logic:  newCustomLogic(true, c.listers),
+	}
+	c.logicMap.Store(key, state)
+	return state.logic, false, nil
+}
+
+// sweepLogics evicts the logics of the ServiceLevelAgreements that no longer exist and the load histories of the deleted Services.
+func (c *Controller) sweepLogics() {
+	c.logicMap.Range(func(k, v interface{}) bool {
+		key, _ := k.(string)
+		state, ok := v.(*logicState)
+		namespace, name, err := cache.SplitMetaNamespaceKey(key)
+		if !ok || err != nil {
+			c.logicMap.Delete(k)
+			return true
+		}
+
+		sla, err := c.listers.ServiceLevelAgreements(namespace).Get(name)
+		if errors.IsNotFound(err) || (err == nil && sla.UID != state.uid) {
+			klog.V(4).Info("Evicting the logic of service level agreement ", key)
+			c.logicMap.Delete(key)
+		} else if err != nil {
+			klog.Error(err)
+		}
+		return true
+	})
+
+	c.forecaster.Sweep(c.listers.ServiceLister)
+}
@@ -0,0 +1,98 @@
+package replicaupdater
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/forecaster"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+)
+
+func newSLA(name string, uid types.UID) *v1beta1.ServiceLevelAgreement {
+	return &v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       uid,
+		},
+		Spec: v1beta1.ServiceLevelAgreementSpec{
+			Metric: v1beta1.MetricRequirement{
+				ResponseTime: *resource.NewMilliQuantity(100, resource.DecimalSI),
+			},
+		},
+	}
+}
+
+func TestLogicFor(t *testing.T) {
+	sla := newSLA("sla", "uid")
+
+	testcases := []struct {
+		description string
+		sla         func() *v1beta1.ServiceLevelAgreement
+		reset       bool
+	}{
+		{
+			description: "should keep the logic when nothing changed",
+			sla:         func() *v1beta1.ServiceLevelAgreement { return sla },
+			reset:       false,
+		},
+		{
+			description: "should reset the logic of a recreated agreement",
+			sla:         func() *v1beta1.ServiceLevelAgreement { return newSLA("sla", "new-uid") },
+			reset:       true,
+		},
+		{
+			description: "should reset the logic when the target changes",
+			sla: func() *v1beta1.ServiceLevelAgreement {
+				newSLA := sla.DeepCopy()
+				newSLA.Spec.Metric.ErrorRate = resource.NewMilliQuantity(10, resource.DecimalSI)
+				return newSLA
+			},
+			reset: true,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Controller{}
+
+			logic, ok, err := c.logicFor("default/sla", sla)
+			require.Nil(t, err)
+			require.False(t, ok)
+
+			newLogic, ok, err := c.logicFor("default/sla", tt.sla())
+			require.Nil(t, err)
+			require.Equal(t, tt.reset, !ok)
+			require.Equal(t, tt.reset, logic != newLogic)
+		})
+	}
+}
+
+func TestSweepLogics(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	require.Nil(t, indexer.Add(newSLA("alive", "alive")))
+	require.Nil(t, indexer.Add(newSLA("recreated", "new")))
+
+	c := &Controller{
+		listers:    informers.Listers{ServiceLevelAgreementLister: salisters.NewServiceLevelAgreementLister(indexer)},
+		forecaster: forecaster.NewForecaster(),
+	}
+	c.logicMap.Store("default/alive", &logicState{uid: "alive"})
+	c.logicMap.Store("default/recreated", &logicState{uid: "old"})
+	c.logicMap.Store("default/deleted", &logicState{uid: "deleted"})
+
+	c.sweepLogics()
+
+	keys := make([]string, 0)
+	c.logicMap.Range(func(k, v interface{}) bool {
+		keys = append(keys, k.(string))
+		return true
+	})
+	require.Equal(t, []string{"default/alive"}, keys)
+}
@@ -4,6 +4,10 @@ import (
 	"flag"
 	"time"
 
+	"github.com/kubernetes-sigs/custom-metrics-apiserver/pkg/dynamicmapper"
+	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
+	metricsclient "k8s.io/metrics/pkg/client/custom_metrics"
+
 	informers2 "github.com/lterrac/system-autoscaler/pkg/informers"
 
 	sainformers "github.com/lterrac/system-autoscaler/pkg/generated/informers/externalversions"
@@ -57,6 +61,13 @@ func main() {
 		klog.Fatalf("Error building example clientset: %s", err.Error())
 	}
 
+	mapper, err := dynamicmapper.NewRESTMapper(kubeClient, time.Second)
+	if err != nil {
+		klog.Fatalf("Error building REST Mapper: %s", err.Error())
+	}
+
+	metricsGetter := metricsgetter.NewDefaultGetter(cfg, mapper, metricsclient.NewAvailableAPIsGetter(kubeClient))
+
 	coreInformerFactory := coreinformers.NewSharedInformerFactory(kubeClient, time.Second*30)
 	saInformerFactory := sainformers.NewSharedInformerFactory(systemAutoscalerClient, time.Second*30)
 
@@ -72,6 +83,7 @@ func main() {
 		kubeClient,
 		systemAutoscalerClient,
 		informers,
+		metricsGetter,
 	)
 
 	// notice that there is no need to run Start methods in a separate goroutine. (i.e. go coreInformerFactory.Start(stopCh)
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/lterrac/system-autoscaler/pkg/informers"
+	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
 	"github.com/lterrac/system-autoscaler/pkg/queue"
 	corev1 "k8s.io/api/core/v1"
 	typev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -33,7 +34,7 @@ const (
 	SubjectToLabel = "app.kubernetes.io/subject-to"
 
 	// QOSNotSupported is used as part of the event 'reason' fired when the controller
-	// process a pod with a QOS other than Guaranteed
+	// process a pod with a QOS not supported by its ServiceLevelAgreement
 	QOSNotSupported = "Unsupported QOS"
 
 	// ContainerNotFound is used as part of the event 'reason' fired when the controller
@@ -46,12 +47,34 @@ const (
 	// MessageResourceSynced is the message used for an Event fired when a podScale
 	// is synced successfully
 	MessageResourceSynced = "podScale synced successfully"
+
+	// ServicesMatched is the reason of the Ready condition when the agreement tracks at least one Service
+	ServicesMatched = "ServicesMatched"
+
+	// NoServiceMatched is the reason of the Ready condition when the selector does not match any Service
+	NoServiceMatched = "NoServiceMatched"
+
+	// PodsSkipped is the reason of the Degraded condition when some Pods cannot be tracked
+	PodsSkipped = "PodsSkipped"
+
+	// AllPodsTracked is the reason of the Degraded condition when all the Pods are tracked
+	AllPodsTracked = "AllPodsTracked"
+
+	// RequirementViolated is the reason of the Violated condition when a requirement is not honored
+	RequirementViolated = "RequirementViolated"
+
+	// RequirementsHonored is the reason of the Violated condition when all the requirements are honored
+	RequirementsHonored = "RequirementsHonored"
+
+	// MetricsUnavailable is the reason of the Violated condition when the Service metrics cannot be retrieved
+	MetricsUnavailable = "MetricsUnavailable"
 )
 
 // Controller is the controller implementation for podScale resources
 type Controller struct {
 	kubeClientset      kubernetes.Interface
 	podScalesClientset clientset.Interface
+	metricClient       metricsgetter.MetricGetter
 
 	listers informers.Listers
 
@@ -69,7 +92,8 @@ type Controller struct {
 func NewController(
 	kubeClient kubernetes.Interface,
 	podScalesClient clientset.Interface,
-	informers informers.Informers) *Controller {
+	informers informers.Informers,
+	metricClient metricsgetter.MetricGetter) *Controller {
 
 	// Create event broadcaster
 	// Add System Autoscaler types to the default Kubernetes Scheme so Events can be
@@ -87,6 +111,7 @@ func NewController(
 	controller := &Controller{
 		kubeClientset:      kubeClient,
 		podScalesClientset: podScalesClient,
+		metricClient:       metricClient,
 
 		listers: informers.GetListers(),
 
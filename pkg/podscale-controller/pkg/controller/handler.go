@@ -1,5 +1,10 @@
 package controller
 
+import (
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
 func (c *Controller) handleServiceLevelAgreementAdd(new interface{}) {
 	c.slasworkqueue.Enqueue(new)
 }
@@ -9,5 +14,32 @@ func (c *Controller) handleServiceLevelAgreementDeletion(old interface{}) {
 }
 
 func (c *Controller) handleServiceLevelAgreementUpdate(old, new interface{}) {
+	oldSLA, ok := old.(*v1beta1.ServiceLevelAgreement)
+	if !ok {
+		c.slasworkqueue.Enqueue(new)
+		return
+	}
+	newSLA, ok := new.(*v1beta1.ServiceLevelAgreement)
+	if !ok {
+		c.slasworkqueue.Enqueue(new)
+		return
+	}
+
+	// skip the updates made by the controller to the status subresource, otherwise
+	// each status update would trigger a new sync. Periodic resyncs keep the same
+	// resource version so they are still processed.
+	if oldSLA.ResourceVersion != newSLA.ResourceVersion && statusOnlyUpdate(oldSLA, newSLA) {
+		return
+	}
+
 	c.slasworkqueue.Enqueue(new)
 }
+
+// statusOnlyUpdate tells whether two versions of a ServiceLevelAgreement differ only in their status.
+// Changes to the labels and the annotations are not tracked by the generation, but they
+// are still processed since they can change the Services matched by the agreement.
+func statusOnlyUpdate(old, new *v1beta1.ServiceLevelAgreement) bool {
+	return old.Generation == new.Generation &&
+		equality.Semantic.DeepEqual(old.Labels, new.Labels) &&
+		equality.Semantic.DeepEqual(old.Annotations, new.Annotations)
+}
@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestStatusOnlyUpdate(t *testing.T) {
+	old := &v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "sla",
+			Namespace:       "default",
+			ResourceVersion: "1",
+			Generation:      1,
+			Labels:          map[string]string{"app": "foo"},
+		},
+	}
+
+	testcases := []struct {
+		description string
+		update      func(sla *v1beta1.ServiceLevelAgreement)
+		statusOnly  bool
+	}{
+		{
+			description: "should detect the status updates",
+			update: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.ResourceVersion = "2"
+				sla.Status.TrackedPodScales = 1
+			},
+			statusOnly: true,
+		},
+		{
+			description: "should detect the spec updates",
+			update: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.ResourceVersion = "2"
+				sla.Generation = 2
+			},
+			statusOnly: false,
+		},
+		{
+			description: "should detect the label updates",
+			update: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.ResourceVersion = "2"
+				sla.Labels = map[string]string{"app": "bar"}
+			},
+			statusOnly: false,
+		},
+		{
+			description: "should detect the annotation updates",
+			update: func(sla *v1beta1.ServiceLevelAgreement) {
+				sla.ResourceVersion = "2"
+				sla.Annotations = map[string]string{"owner": "team"}
+			},
+			statusOnly: false,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			new := old.DeepCopy()
+			tt.update(new)
+			require.Equal(t, tt.statusOnly, statusOnlyUpdate(old, new))
+		})
+	}
+}
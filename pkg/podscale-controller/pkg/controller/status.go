@@ -0,0 +1,119 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+)
+
+// updateServiceLevelAgreementStatus computes the status of the ServiceLevelAgreement
+// after a sync and writes it through the status subresource if it changed.
+func (c *Controller) updateServiceLevelAgreementStatus(sla *v1beta1.ServiceLevelAgreement, services []*corev1.Service, tracked int32, skipped []v1beta1.SkippedPod) error {
+	slaCopy := sla.DeepCopy()
+	status := &slaCopy.Status
+
+	status.ObservedGeneration = sla.Generation
+	status.TrackedPodScales = tracked
+	status.MatchedServices = nil
+	for _, service := range services {
+		status.MatchedServices = append(status.MatchedServices, service.GetName())
+	}
+
+	status.SkippedPods = nil
+	if len(skipped) > 0 {
+		status.SkippedPods = skipped
+	}
+
+	if len(services) > 0 {
+		c.setCondition(slaCopy, v1beta1.SLAReady, metav1.ConditionTrue, ServicesMatched,
+			fmt.Sprintf("the agreement tracks %d services", len(services)))
+	} else {
+		c.setCondition(slaCopy, v1beta1.SLAReady, metav1.ConditionFalse, NoServiceMatched,
+			"the selector does not match any service")
+	}
+
+	if len(skipped) > 0 {
+		c.setCondition(slaCopy, v1beta1.SLADegraded, metav1.ConditionTrue, PodsSkipped,
+			fmt.Sprintf("%d pods cannot be tracked", len(skipped)))
+	} else {
+		c.setCondition(slaCopy, v1beta1.SLADegraded, metav1.ConditionFalse, AllPodsTracked,
+			"all the pods are tracked")
+	}
+
+	c.updateCompliance(slaCopy, services)
+
+	if equality.Semantic.DeepEqual(sla.Status, slaCopy.Status) {
+		return nil
+	}
+
+	_, err := c.podScalesClientset.SystemautoscalerV1beta1().ServiceLevelAgreements(sla.Namespace).UpdateStatus(context.TODO(), slaCopy, metav1.UpdateOptions{})
+	return err
+}
+
+// updateCompliance sets the last observed response time and the Violated condition
+// comparing the metrics of the matched Services with the agreement requirements.
+func (c *Controller) updateCompliance(sla *v1beta1.ServiceLevelAgreement, services []*corev1.Service) {
+	if len(services) == 0 {
+		sla.Status.LastResponseTime = nil
+		c.setCondition(sla, v1beta1.SLAViolated, metav1.ConditionUnknown, NoServiceMatched, "the selector does not match any service")
+		return
+	}
+
+	responseTimeMetric := metrics.ResponseTimeMetric(sla.Spec.Metric.ResponseTimePercentile)
+	requirements := metrics.Requirements(sla.Spec.Metric)
+	violations := make([]string, 0)
+	var responseTime *resource.Quantity
+
+	for _, service := range services {
+		metric, err := c.metricClient.ServiceMetrics(service, responseTimeMetric)
+		if err == nil && (responseTime == nil || metric.Value.Cmp(*responseTime) > 0) {
+			value := metric.Value.DeepCopy()
+			responseTime = &value
+		}
+
+		for _, requirement := range requirements {
+			metric, err := c.metricClient.ServiceMetrics(service, requirement.Metric)
+			if err != nil {
+				klog.Errorf("failed to get %s metric from service with name %s and namespace %s: %s", requirement.Metric, service.GetName(), service.GetNamespace(), err)
+				c.setCondition(sla, v1beta1.SLAViolated, metav1.ConditionUnknown, MetricsUnavailable,
+					fmt.Sprintf("cannot retrieve %s of service %s", requirement.Metric, service.GetName()))
+				sla.Status.LastResponseTime = responseTime
+				return
+			}
+
+			if requirement.Ratio(metric.Value) > 1 {
+				violations = append(violations, fmt.Sprintf("%s of service %s is %s, target is %s",
+					requirement.Metric, service.GetName(), metric.Value.String(), requirement.Target.String()))
+			}
+		}
+	}
+
+	sla.Status.LastResponseTime = responseTime
+
+	if len(violations) > 0 {
+		c.setCondition(sla, v1beta1.SLAViolated, metav1.ConditionTrue, RequirementViolated, strings.Join(violations, "; "))
+	} else {
+		c.setCondition(sla, v1beta1.SLAViolated, metav1.ConditionFalse, RequirementsHonored, "all the requirements are honored")
+	}
+}
+
+// setCondition updates a condition of the ServiceLevelAgreement, keeping the
+// transition time unchanged if the status did not change.
+func (c *Controller) setCondition(sla *v1beta1.ServiceLevelAgreement, conditionType string, status metav1.ConditionStatus, reason string, message string) {
+	meta.SetStatusCondition(&sla.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: sla.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
@@ -0,0 +1,189 @@
+package controller
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/metrics-exposer/pkg/metrics"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta2 "k8s.io/metrics/pkg/apis/custom_metrics/v1beta2"
+)
+
+// serviceMetricGetter returns the metrics of the services in milli units. Missing metrics are not available.
+type serviceMetricGetter map[string]map[metrics.MetricType]int64
+
+func (g serviceMetricGetter) PodMetrics(p *corev1.Pod, metricType metrics.MetricType) (*metricsv1beta2.MetricValue, error) {
+	return nil, fmt.Errorf("pod metrics are not available")
+}
+
+func (g serviceMetricGetter) ServiceMetrics(s *corev1.Service, metricType metrics.MetricType) (*metricsv1beta2.MetricValue, error) {
+	value, ok := g[s.Name][metricType]
+	if !ok {
+		return nil, fmt.Errorf("%s metric of service %s is not available", metricType, s.Name)
+	}
+	return &metricsv1beta2.MetricValue{Value: *resource.NewMilliQuantity(value, resource.DecimalSI)}, nil
+}
+
+func (g serviceMetricGetter) ContainerWorkingSet(p *corev1.Pod, container string) (*resource.Quantity, error) {
+	return nil, fmt.Errorf("resource metrics are not available")
+}
+
+func TestUpdateCompliance(t *testing.T) {
+	requirement := v1beta1.MetricRequirement{
+		ResponseTime: *resource.NewMilliQuantity(100, resource.DecimalSI),
+		Throughput:   resource.NewQuantity(10, resource.DecimalSI),
+	}
+
+	testcases := []struct {
+		description  string
+		services     []string
+		metrics      serviceMetricGetter
+		status       metav1.ConditionStatus
+		reason       string
+		responseTime *resource.Quantity
+	}{
+		{
+			description: "should be unknown without services",
+			services:    []string{},
+			metrics:     serviceMetricGetter{},
+			status:      metav1.ConditionUnknown,
+			reason:      NoServiceMatched,
+		},
+		{
+			description: "should honor the requirements",
+			services:    []string{"foo"},
+			metrics: serviceMetricGetter{
+				"foo": {metrics.ResponseTime: 50, metrics.Throughput: 20000},
+			},
+			status:       metav1.ConditionFalse,
+			reason:       RequirementsHonored,
+			responseTime: resource.NewMilliQuantity(50, resource.DecimalSI),
+		},
+		{
+			description: "should be violated when any service violates a requirement",
+			services:    []string{"foo", "bar"},
+			metrics: serviceMetricGetter{
+				"foo": {metrics.ResponseTime: 50, metrics.Throughput: 20000},
+				"bar": {metrics.ResponseTime: 200, metrics.Throughput: 20000},
+			},
+			status:       metav1.ConditionTrue,
+			reason:       RequirementViolated,
+			responseTime: resource.NewMilliQuantity(200, resource.DecimalSI),
+		},
+		{
+			description: "should not violate the throughput of a service without traffic",
+			services:    []string{"foo"},
+			metrics: serviceMetricGetter{
+				"foo": {metrics.ResponseTime: 0, metrics.Throughput: 0},
+			},
+			status:       metav1.ConditionFalse,
+			reason:       RequirementsHonored,
+			responseTime: resource.NewMilliQuantity(0, resource.DecimalSI),
+		},
+		{
+			description: "should be unknown when the metrics are not available",
+			services:    []string{"foo"},
+			metrics: serviceMetricGetter{
+				"foo": {metrics.ResponseTime: 50},
+			},
+			status:       metav1.ConditionUnknown,
+			reason:       MetricsUnavailable,
+			responseTime: resource.NewMilliQuantity(50, resource.DecimalSI),
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Controller{metricClient: tt.metrics}
+			sla := &v1beta1.ServiceLevelAgreement{
+				ObjectMeta: metav1.ObjectMeta{Name: "sla", Generation: 2},
+				Spec:       v1beta1.ServiceLevelAgreementSpec{Metric: requirement},
+			}
+			services := make([]*corev1.Service, 0)
+			for _, name := range tt.services {
+				services = append(services, &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name}})
+			}
+
+			c.updateCompliance(sla, services)
+
+			condition := meta.FindStatusCondition(sla.Status.Conditions, v1beta1.SLAViolated)
+			require.NotNil(t, condition)
+			require.Equal(t, tt.status, condition.Status)
+			require.Equal(t, tt.reason, condition.Reason)
+			require.Equal(t, int64(2), condition.ObservedGeneration)
+
+			if tt.responseTime == nil {
+				require.Nil(t, sla.Status.LastResponseTime)
+			} else {
+				require.NotNil(t, sla.Status.LastResponseTime)
+				require.Equal(t, tt.responseTime.MilliValue(), sla.Status.LastResponseTime.MilliValue())
+			}
+		})
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	transition := metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	testcases := []struct {
+		description     string
+		status          metav1.ConditionStatus
+		reason          string
+		keepsTransition bool
+	}{
+		{
+			description:     "should keep the transition time when the status does not change",
+			status:          metav1.ConditionTrue,
+			reason:          ServicesMatched,
+			keepsTransition: true,
+		},
+		{
+			description:     "should update the transition time when the status changes",
+			status:          metav1.ConditionFalse,
+			reason:          NoServiceMatched,
+			keepsTransition: false,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			c := &Controller{}
+			sla := &v1beta1.ServiceLevelAgreement{
+				ObjectMeta: metav1.ObjectMeta{Generation: 3},
+				Status: v1beta1.ServiceLevelAgreementStatus{
+					Conditions: []metav1.Condition{{
+						Type:               v1beta1.SLAReady,
+						Status:             metav1.ConditionTrue,
+						ObservedGeneration: 1,
+						LastTransitionTime: transition,
+						Reason:             ServicesMatched,
+						Message:            "old message",
+					}},
+				},
+			}
+
+			c.setCondition(sla, v1beta1.SLAReady, tt.status, tt.reason, "new message")
+
+			require.Len(t, sla.Status.Conditions, 1)
+			condition := sla.Status.Conditions[0]
+			require.Equal(t, tt.status, condition.Status)
+			require.Equal(t, tt.reason, condition.Reason)
+			require.Equal(t, "new message", condition.Message)
+			require.Equal(t, int64(3), condition.ObservedGeneration)
+			require.Equal(t, tt.keepsTransition, condition.LastTransitionTime.Equal(&transition))
+		})
+	}
+
+	// conditions of a different type are added
+	c := &Controller{}
+	sla := &v1beta1.ServiceLevelAgreement{}
+	c.setCondition(sla, v1beta1.SLAReady, metav1.ConditionTrue, ServicesMatched, "")
+	c.setCondition(sla, v1beta1.SLADegraded, metav1.ConditionFalse, AllPodsTracked, "")
+	require.Len(t, sla.Status.Conditions, 2)
+}
@@ -59,6 +59,9 @@ func (c *Controller) syncServiceLevelAgreement(key string) error {
 		return nil
 	}
 
+	var tracked int32
+	skipped := make([]v1beta1.SkippedPod, 0)
+
 	for _, service := range desired {
 
 		// TODO: Decide what happens if service matches a SLA but already have one
@@ -71,13 +74,15 @@ func (c *Controller) syncServiceLevelAgreement(key string) error {
 		//}
 
 		// adjust Service's PodScale according to its Pods
-		err = c.syncService(namespace, service, sla)
+		serviceTracked, serviceSkipped, err := c.syncService(namespace, service, sla)
 		if err != nil {
 			utilruntime.HandleError(fmt.Errorf("error while syncing PodScales for Service '%s'", service.GetName()))
-			utilruntime.HandleError(err)
-			return nil
+			return err
 		}
 
+		tracked += serviceTracked
+		skipped = append(skipped, serviceSkipped...)
+
 		// keep track of the SLA applied to the Service
 		service.Labels[SubjectToLabel] = sla.GetName()
 
@@ -97,6 +102,12 @@ func (c *Controller) syncServiceLevelAgreement(key string) error {
 		return nil
 	}
 
+	err = c.updateServiceLevelAgreementStatus(sla, desired, tracked, skipped)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("error while updating the status of ServiceLevelAgreement '%s'", key))
+		return err
+	}
+
 	c.recorder.Event(sla, corev1.EventTypeNormal, SuccessSynced, MessageResourceSynced)
 	return nil
 }
@@ -131,46 +142,82 @@ func (c *Controller) handleServiceSelectorChange(actual []*corev1.Service, desir
 // by creating and deleting the corresponding `PodScale` resources. It uses the `Selector`
 // to retrive the corresponding `Pod` and `PodScale`. The `Pod` resources are used as
 // a desired state so `PodScale` are changed accordingly.
-func (c *Controller) syncService(namespace string, service *corev1.Service, sla *v1beta1.ServiceLevelAgreement) error {
+// It returns the number of `PodScale` tracking the Service and the Pods that have been skipped.
+func (c *Controller) syncService(namespace string, service *corev1.Service, sla *v1beta1.ServiceLevelAgreement) (int32, []v1beta1.SkippedPod, error) {
+	skipped := make([]v1beta1.SkippedPod, 0)
 	label := labels.Set(service.Spec.Selector)
 	pods, err := c.listers.PodLister.List(label.AsSelector())
 
 	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("error while getting Pods for Service '%s'", service.GetName()))
-		return nil
+		return 0, skipped, nil
 	}
 
 	podscales, err := c.listers.PodScaleLister.List(label.AsSelector())
 
 	if err != nil {
 		utilruntime.HandleError(fmt.Errorf("error while getting PodScales for Service '%s'", service.GetName()))
-		return nil
+		return 0, skipped, nil
 	}
 
 	stateDiff := utils.DiffPods(pods, podscales)
+	tracked := int32(len(podscales))
 
 	for _, pod := range stateDiff.AddList {
-		//TODO: change when a policy to handle other QOS class will be discussed
-		if pod.Status.QOSClass != corev1.PodQOSGuaranteed {
+		// Guaranteed pods are always supported, while Burstable ones only when the agreement allows them
+		if !supportsQOS(pod, sla) {
 			c.recorder.Eventf(pod, corev1.EventTypeWarning, QOSNotSupported, "Unsupported QOS for Pod %s/%s: ", pod.Namespace, pod.Name, pod.Status.QOSClass)
+			skipped = append(skipped, v1beta1.SkippedPod{Name: pod.Name, Reason: QOSNotSupported})
 			continue
 		}
 
 		// do not create the podscale if the specified container does not exists within the Pod
 		if !utils.HasContainer(pod.Spec.Containers, sla.Spec.Service.Container) {
 			c.recorder.Eventf(pod, corev1.EventTypeWarning, ContainerNotFound, "Pod %s/%s does not have container %s", pod.Namespace, pod.Name, sla.Spec.Service.Container)
+			skipped = append(skipped, v1beta1.SkippedPod{Name: pod.Name, Reason: ContainerNotFound})
 			continue
 		}
 
 		podscale := NewPodScale(pod, sla, service, label)
 
-		_, err := c.podScalesClientset.SystemautoscalerV1beta1().PodScales(namespace).Create(context.TODO(), podscale, metav1.CreateOptions{})
+		created, err := c.podScalesClientset.SystemautoscalerV1beta1().PodScales(namespace).Create(context.TODO(), podscale, metav1.CreateOptions{})
 		if err != nil && !errors.IsAlreadyExists(err) {
 			utilruntime.HandleError(fmt.Errorf("error while creating PodScale for Pod '%s'", podscale.GetName()))
 			utilruntime.HandleError(err)
-			return nil
+			return tracked, skipped, nil
 		}
 
+		if err == nil {
+			tracked++
+
+			err = c.initializeStatus(created, podscale.Status)
+			if err != nil {
+				return tracked, skipped, err
+			}
+		}
+	}
+
+	// initialize the PodScales whose status could not be set after the creation
+	desired := make(map[string]*corev1.Pod)
+	for _, pod := range pods {
+		desired[pod.Name] = pod
+	}
+
+	for _, podscale := range podscales {
+		pod, ok := desired[podscale.Spec.Pod]
+		if !ok || len(podscale.Status.ActualResources) > 0 {
+			continue
+		}
+
+		status := NewPodScale(pod, sla, service, label).Status
+		if len(status.ActualResources) == 0 {
+			continue
+		}
+
+		err = c.initializeStatus(podscale.DeepCopy(), status)
+		if err != nil {
+			return tracked, skipped, err
+		}
 	}
 
 	for _, podscale := range stateDiff.DeleteList {
@@ -179,13 +226,36 @@ func (c *Controller) syncService(namespace string, service *corev1.Service, sla
 		if err != nil {
 			utilruntime.HandleError(fmt.Errorf("error while deleting PodScale for Pod '%s'", podscale.Name))
 			utilruntime.HandleError(err)
-			return nil
+			return tracked, skipped, nil
 		}
+		tracked--
 	}
 
+	return tracked, skipped, nil
+}
+
+// initializeStatus sets the initial status of a PodScale, which is ignored on creation because of the status subresource
+func (c *Controller) initializeStatus(podscale *v1beta1.PodScale, status v1beta1.PodScaleStatus) error {
+	podscale.Status = status
+	_, err := c.podScalesClientset.SystemautoscalerV1beta1().PodScales(podscale.Namespace).UpdateStatus(context.TODO(), podscale, metav1.UpdateOptions{})
+	if err != nil {
+		return fmt.Errorf("error while initializing the status of PodScale '%s': %s", podscale.GetName(), err)
+	}
 	return nil
 }
 
+// supportsQOS returns true if the QOS class of the pod is supported by the agreement
+func supportsQOS(pod *corev1.Pod, sla *v1beta1.ServiceLevelAgreement) bool {
+	switch pod.Status.QOSClass {
+	case corev1.PodQOSGuaranteed:
+		return true
+	case corev1.PodQOSBurstable:
+		return sla.Spec.IsBurstable()
+	default:
+		return false
+	}
+}
+
 // NewPodScale creates a new PodScale resource using the corresponding Pod and ServiceLevelAgreement infos.
 // The SLA is the resource Owner in order to enable garbage collection on its deletion.
 func NewPodScale(pod *corev1.Pod, sla *v1beta1.ServiceLevelAgreement, service *corev1.Service, selectorLabels labels.Set) *v1beta1.PodScale {
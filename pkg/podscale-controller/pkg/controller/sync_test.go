@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
+	safake "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned/fake"
+	salisters "github.com/lterrac/system-autoscaler/pkg/generated/listers/systemautoscaler/v1beta1"
+	"github.com/lterrac/system-autoscaler/pkg/informers"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestSyncServiceStatusInitialization(t *testing.T) {
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("500m"),
+		corev1.ResourceMemory: resource.MustParse("256Mi"),
+	}
+	selector := map[string]string{"app": "foo"}
+
+	sla := &v1beta1.ServiceLevelAgreement{
+		ObjectMeta: metav1.ObjectMeta{Name: "sla", Namespace: "default"},
+		Spec: v1beta1.ServiceLevelAgreementSpec{
+			DefaultResources: resources,
+			Service:          &v1beta1.Service{Container: "app"},
+		},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Selector: selector},
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default", Labels: selector},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}},
+		Status:     corev1.PodStatus{QOSClass: corev1.PodQOSGuaranteed},
+	}
+	uninitialized := NewPodScale(pod, sla, service, selector)
+	uninitialized.Status = v1beta1.PodScaleStatus{}
+
+	testcases := []struct {
+		description     string
+		podScales       []*v1beta1.PodScale
+		failStatus      bool
+		err             bool
+		statusUpdates   int
+		initialStatuses bool
+	}{
+		{
+			description:     "should initialize the status of a new PodScale",
+			podScales:       []*v1beta1.PodScale{},
+			statusUpdates:   1,
+			initialStatuses: true,
+		},
+		{
+			description:   "should return the error when the status cannot be initialized",
+			podScales:     []*v1beta1.PodScale{},
+			failStatus:    true,
+			err:           true,
+			statusUpdates: 1,
+		},
+		{
+			description:     "should initialize the status of an existing PodScale found empty",
+			podScales:       []*v1beta1.PodScale{uninitialized},
+			statusUpdates:   1,
+			initialStatuses: true,
+		},
+		{
+			description:   "should not update an initialized PodScale",
+			podScales:     []*v1beta1.PodScale{NewPodScale(pod, sla, service, selector)},
+			statusUpdates: 0,
+		},
+	}
+
+	for _, tt := range testcases {
+		t.Run(tt.description, func(t *testing.T) {
+			pods := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			require.Nil(t, pods.Add(pod))
+
+			podScales := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+			objects := make([]runtime.Object, 0)
+			for _, podScale := range tt.podScales {
+				require.Nil(t, podScales.Add(podScale))
+				objects = append(objects, podScale.DeepCopy())
+			}
+
+			statuses := make([]v1beta1.PodScaleStatus, 0)
+			podScalesClientset := safake.NewSimpleClientset(objects...)
+			podScalesClientset.PrependReactor("update", "podscales", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				if action.GetSubresource() != "status" {
+					return false, nil, nil
+				}
+				statuses = append(statuses, action.(k8stesting.UpdateAction).GetObject().(*v1beta1.PodScale).Status)
+				if tt.failStatus {
+					return true, nil, fmt.Errorf("status update failed")
+				}
+				return false, nil, nil
+			})
+
+			c := &Controller{
+				podScalesClientset: podScalesClientset,
+				listers: informers.Listers{
+					PodLister:      corelisters.NewPodLister(pods),
+					PodScaleLister: salisters.NewPodScaleLister(podScales),
+				},
+				recorder: record.NewFakeRecorder(10),
+			}
+
+			tracked, skipped, err := c.syncService("default", service, sla)
+			if tt.err {
+				require.NotNil(t, err)
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, int32(1), tracked)
+			require.Empty(t, skipped)
+			require.Len(t, statuses, tt.statusUpdates)
+
+			if tt.initialStatuses {
+				require.True(t, equality.Semantic.DeepEqual(resources, statuses[0].ActualResources))
+				require.True(t, equality.Semantic.DeepEqual(resources, statuses[0].CappedResources))
+
+				podScale, err := podScalesClientset.SystemautoscalerV1beta1().PodScales("default").Get(context.TODO(), "pod-foo", metav1.GetOptions{})
+				require.Nil(t, err)
+				require.True(t, equality.Semantic.DeepEqual(resources, podScale.Status.ActualResources))
+			}
+		})
+	}
+}
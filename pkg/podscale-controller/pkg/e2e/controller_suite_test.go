@@ -16,6 +16,7 @@ import (
 
 	systemautoscalerv1beta1 "github.com/lterrac/system-autoscaler/pkg/apis/systemautoscaler/v1beta1"
 	systemautoscaler "github.com/lterrac/system-autoscaler/pkg/generated/clientset/versioned"
+	metricsgetter "github.com/lterrac/system-autoscaler/pkg/pod-autoscaler/pkg/metrics"
 	podscale "github.com/lterrac/system-autoscaler/pkg/podscale-controller/pkg/controller"
 	. "github.com/onsi/ginkgo"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -72,10 +73,15 @@ var _ = BeforeSuite(func(done Done) {
 
 	By("bootstrapping controller")
 
+	metricClient := &metricsgetter.FakeGetter{
+		ResponseTime: 50,
+	}
+
 	controller := podscale.NewController(
 		kubeClient,
 		saClient,
 		informers,
+		metricClient,
 	)
 
 	By("starting informers")
@@ -13,11 +13,20 @@ type syncFunc func(key string) error
 
 type Queue struct {
 	queue workqueue.RateLimitingInterface
+	// maxRetries is the number of times a failed item is requeued before being dropped, zero means forever
+	maxRetries int
 }
 
 func NewQueue(name string) Queue {
+	return NewRetryingQueue(name, 0)
+}
+
+// NewRetryingQueue returns a queue that drops the items failing more than maxRetries times.
+// The failed items are requeued with an exponential backoff.
+func NewRetryingQueue(name string, maxRetries int) Queue {
 	return Queue{
-		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name),
+		queue:      workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), name),
+		maxRetries: maxRetries,
 	}
 }
 
@@ -46,6 +55,10 @@ func (q *Queue) ProcessNextItem(sync syncFunc) bool {
 		// Run the syncHandler, passing it the namespace/name string of the
 		// resource to be synced.
 		if err := sync(key); err != nil {
+			if q.Dropping(key) {
+				q.queue.Forget(obj)
+				return fmt.Errorf("error syncing '%s': %s, dropping after %d retries", key, err.Error(), q.maxRetries)
+			}
 			// Put the item back on the workqueue to handle any transient errors.
 			q.queue.AddRateLimited(key)
 			return fmt.Errorf("error syncing '%s': %s, requeuing", key, err.Error())
@@ -65,6 +78,11 @@ func (q *Queue) ProcessNextItem(sync syncFunc) bool {
 	return true
 }
 
+// Dropping tells whether the key is dropped if its current sync fails, since it already failed maxRetries times
+func (q *Queue) Dropping(key string) bool {
+	return q.maxRetries > 0 && q.queue.NumRequeues(key) >= q.maxRetries
+}
+
 func (q *Queue) Enqueue(obj interface{}) {
 	var key string
 	var err error
@@ -75,6 +93,16 @@ func (q *Queue) Enqueue(obj interface{}) {
 	q.queue.AddRateLimited(key)
 }
 
+// Add enqueues a key without waiting for the backoff
+func (q *Queue) Add(key string) {
+	q.queue.Add(key)
+}
+
+// Len returns the number of keys waiting to be processed
+func (q *Queue) Len() int {
+	return q.queue.Len()
+}
+
 func (q *Queue) ShutDown() {
 	q.queue.ShutDown()
 }
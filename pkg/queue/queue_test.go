@@ -0,0 +1,33 @@
+package queue
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryingQueue(t *testing.T) {
+	q := NewRetryingQueue("test", 2)
+	defer q.ShutDown()
+
+	attempts, dropping := 0, 0
+	fail := func(key string) error {
+		attempts++
+		if q.Dropping(key) {
+			dropping++
+		}
+		return fmt.Errorf("sync of %s failed", key)
+	}
+
+	q.Add("default/item")
+	for i := 0; i < 3; i++ {
+		require.True(t, q.ProcessNextItem(fail))
+	}
+
+	// the item is dropped after the first attempt and two retries
+	require.Equal(t, 3, attempts)
+	require.Equal(t, 1, dropping)
+	require.Equal(t, 0, q.Len())
+	require.Equal(t, 0, q.queue.NumRequeues("default/item"))
+}